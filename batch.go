@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+// lineError is an error on a line of a batch. Col is 1-based and 0 when
+// the error does not point at a position; the message leaves out the
+// position it quotes, so only the column is printed.
+type lineError struct {
+	Line int
+	Col  int
+	Err  error
+}
+
+func (e *lineError) Error() string {
+	if e.Col > 0 {
+		return fmt.Sprintf("line %d:%d: %s", e.Line, e.Col, evaluation.ErrorMessage(e.Err))
+	}
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *lineError) Unwrap() error {
+	return e.Err
+}
+
+// processFile evaluates every line of the named file, or of stdin for "-".
+func processFile(name string) error {
+	if name == "-" {
+		return processBatch(os.Stdin, os.Stdout, os.Stderr)
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return processBatch(f, os.Stdout, os.Stderr)
+}
+
+// processBatch evaluates each line of r as an expression in one scope, so
+// a variable assigned on one line can be used on the next. Errors are
+// reported per line and do not stop the batch; the error returned is that
+// of the first line that failed. Results are written to stdout and errors
+// and warnings to stderr.
+func processBatch(r io.Reader, stdout, stderr io.Writer) error {
+	scope := newScope()
+	scanner := bufio.NewScanner(r)
+	var first error
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		input := strings.TrimSpace(line)
+		if input == "" {
+			continue
+		}
+		// Positions are reported in columns of the line as read.
+		offset := strings.Index(line, input)
+
+		res, err := evaluateInput(input, scope)
+		if err != nil && first == nil {
+			first = err
+		}
+
+		if output == "json" {
+			if werr := writeJSON(stdout, n, input, res, err); werr != nil {
+				return werr
+			}
+			continue
+		}
+		if err != nil {
+			lerr := &lineError{Line: n, Err: err}
+			if pos, ok := evaluation.ErrorPos(err); ok {
+				lerr.Col = offset + pos + 1
+			}
+			fmt.Fprintf(stderr, "Error: %v\n", lerr)
+			continue
+		}
+		if evaluation.IsDefinition(res.rpn) {
+			continue
+		}
+		if res.warning != "" {
+			fmt.Fprintf(stderr, "Warning: line %d: %s\n", n, res.warning)
+		}
+		fmt.Fprintln(stdout, res.text)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if first != nil {
+		return reportedError{first}
+	}
+	return nil
+}
+
+// stdinPiped reports whether stdin is a pipe or file rather than a
+// terminal.
+func stdinPiped() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice == 0
+}
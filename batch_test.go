@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBatchLineErrors(t *testing.T) {
+	input := strings.Join([]string{
+		"x = 2",
+		"  x + foo",
+		"# a comment",
+		"sqrt(-x)  # not real",
+		"x / 0",
+		"x^2",
+		"2 +",
+	}, "\n")
+
+	var stdout, stderr strings.Builder
+	err := processBatch(strings.NewReader(input), &stdout, &stderr)
+	if !errors.As(err, new(reportedError)) {
+		t.Errorf("processBatch() error = %v, want the first line error reported", err)
+	}
+
+	if got, want := stdout.String(), "2.000000000000000\n4.000000000000000\n"; got != want {
+		t.Errorf("stdout = %q, want %q", got, want)
+	}
+	want := []string{
+		"Error: line 2:7: Unknown symbol",
+		"Error: line 4:1: sqrt: argument -2 out of domain",
+		"Error: line 5:3: /: division by zero",
+		"Error: line 7:3: Invalid RPN syntax",
+	}
+	got := strings.Split(strings.TrimSuffix(stderr.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("stderr = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("stderr line %d = %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestBatchUnpositionedError(t *testing.T) {
+	var stdout, stderr strings.Builder
+	processBatch(strings.NewReader("1e308 * 10\n"), &stdout, &stderr)
+	if got, want := stderr.String(), "Error: line 1: calculation error: arithmetic overflow\n"; got != want {
+		t.Errorf("stderr = %q, want %q", got, want)
+	}
+}
+
+func TestBatchJSONErrors(t *testing.T) {
+	defer func(o string) { output = o }(output)
+	output = "json"
+
+	var stdout, stderr strings.Builder
+	processBatch(strings.NewReader("1 + 2\n\n  1 / 0\n"), &stdout, &stderr)
+	lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("stdout = %q, want 2 lines", lines)
+	}
+	var outs [2]jsonOutput
+	for i, line := range lines {
+		if err := json.Unmarshal([]byte(line), &outs[i]); err != nil {
+			t.Fatalf("decoding %s: %v", line, err)
+		}
+	}
+
+	if out := outs[0]; out.Line != 1 || out.Error != nil || out.Result != 3.0 {
+		t.Errorf("line 1 = %+v, want result 3", out)
+	}
+	// The position is in the expression, which is read without the
+	// leading spaces of the line.
+	out := outs[1]
+	if e := out.Error; out.Line != 3 || e == nil || e.Code != "division_by_zero" || e.Pos == nil || *e.Pos != 2 {
+		t.Errorf("line 3 = %+v, error %+v, want division_by_zero at position 2", out, e)
+	}
+}
@@ -1,36 +1,136 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/format"
 	"github.com/a1sarpi/gocalc/src/tokenizer"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
-var useRadians bool
+var (
+	useRadians  bool
+	precision   uint
+	exact       bool
+	complexMode bool
+	intType     string
+	outputBase  int
+	overflow    string
+	formatMode  string
+	digits      int
+	grouping    bool
+	output      string
+	batchFile   string
+	statsMode   bool
+	maxDepth    int
+)
+
+// formatter prints float results, configured by setFormatter from the
+// --format, --digits and --group flags.
+var formatter = format.Default
 
 var rootCmd = &cobra.Command{
 	Use:   "calc",
 	Short: "Advanced calculator with RPN",
+	Long: `Advanced calculator with RPN.
+
+A statement of the form diff(expr, x) prints the derivative of expr with
+respect to x, such as 2 * x for diff(x^2, x). integrate(expr, x, a, b)
+computes the definite integral of expr over x from a to b; with -o json the
+output lists the estimated error of every integral. solve(lhs = rhs, x)
+prints every real root of the equation between -100 and 100, or between a
+and b with solve(lhs = rhs, x, a, b), and root(expr, x, guess) finds a root
+of expr near guess.
+
+Vectors such as [1, 2, 3] and matrices such as [[1, 2], [3, 4]] are
+evaluated in matrix mode. +, -, /, ^, .* and the built-in functions apply
+elementwise, * is the matrix product, and transpose, det, inv, dot, cross
+and norm are available.
+
+sum, mean, median, mode, range, var, stddev, pvar, pstddev and
+percentile(p, ...) take any number of arguments; var and stddev are those
+of a sample and pvar and pstddev those of a population. With --stats the
+numbers on stdin, separated by spaces, commas or newlines, are read and all
+of their statistics printed.
+
+n! and n!! are the factorial and double factorial, binding tighter than ^,
+so -3! is -6 and 2^3! is 64. A factorial of a fraction is gamma(n + 1).
+gamma, lgamma, beta(a, b), nCr(n, r) and nPr(n, r) are also available.
+
+if(c, a, b) is a when c is not zero and b otherwise, evaluating only that
+branch, so a function may call itself: with f(n) = if(n, n * f(n - 1), 1),
+f(5) is 120. --max-depth limits how deeply such calls may nest.
+
+a // b divides and rounds down, and a % b is the remainder that goes with
+it, taking the sign of b as mod(a, b) does. rem(a, b) takes the sign of a
+instead, so -7 % 3 is 2 and rem(-7, 3) is -1.
+
+With -f, or when no expression is given and stdin is not a terminal, every
+line is evaluated as an expression and its result printed on a line of its
+own. Blank lines and lines starting with # are skipped, and a line that
+fails is reported with its line and column without stopping the batch.
+
+calc exits with status 2 when the expression is malformed, 3 when it cannot
+be evaluated, such as 1/0 or sqrt(-1), and 1 on any other error. In a batch
+the status is that of the first line that failed.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		flagArgs, args := splitArgs(cmd.Flags(), args)
+		if err := cmd.Flags().Parse(flagArgs); err != nil {
+			return err
+		}
+		if help, _ := cmd.Flags().GetBool("help"); help {
+			return cmd.Help()
+		}
+		if err := setFormatter(); err != nil {
+			return err
+		}
+		if output != "text" && output != "json" {
+			return fmt.Errorf("output must be text or json, not %s", output)
+		}
+
+		if statsMode {
+			return processStats(os.Stdin)
+		}
+		if batchFile != "" {
+			return processFile(batchFile)
+		}
 		if len(args) == 0 {
+			if stdinPiped() {
+				return processBatch(os.Stdin, os.Stdout, os.Stderr)
+			}
 			return fmt.Errorf("expression is required")
 		}
-		// Если первый аргумент --, берем следующий
-		if args[0] == "--" && len(args) > 1 {
-			return processExpression(args[1])
-		}
-		// Иначе берем первый аргумент
 		return processExpression(args[0])
 	},
+	Args: cobra.ArbitraryArgs,
+	// Errors are printed by main, which also picks the exit code.
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	// Expressions such as "-5+3" look like flags, so flags are split off by
+	// splitArgs instead.
 	DisableFlagParsing: true,
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&useRadians, "radians", "r", false, "Use radians for trigonometric functions")
+	rootCmd.PersistentFlags().StringVar(&formatMode, "format", "fixed", "Result format: shortest, fixed, sci, eng or si")
+	rootCmd.PersistentFlags().IntVar(&digits, "digits", -1, "Decimals to print (default 15 for fixed, otherwise as many as needed)")
+	rootCmd.PersistentFlags().BoolVar(&grouping, "group", false, "Separate thousands with commas")
+	rootCmd.PersistentFlags().IntVar(&maxDepth, "max-depth", evaluation.DefaultMaxDepth, "Maximum depth of nested calls of user-defined functions")
+	rootCmd.Flags().UintVarP(&precision, "precision", "p", 0, "Evaluate with arbitrary precision using N bits of mantissa")
+	rootCmd.Flags().BoolVarP(&exact, "exact", "x", false, "Evaluate with exact rational arithmetic")
+	rootCmd.Flags().BoolVarP(&complexMode, "complex", "c", false, "Evaluate over the complex numbers")
+	rootCmd.Flags().StringVar(&intType, "int", "", "Evaluate with integers of type int8, int16, int32, int64 or uint8 to uint64")
+	rootCmd.Flags().IntVarP(&outputBase, "base", "b", 10, "Print integer results in base N (2-36), implies --int int64")
+	rootCmd.Flags().StringVar(&overflow, "overflow", "checked", "Integer overflow handling: checked or wrap")
+	rootCmd.Flags().StringVarP(&output, "output", "o", "text", "Output format: text or json")
+	rootCmd.Flags().StringVarP(&batchFile, "file", "f", "", "Evaluate each line of a file, or of stdin for -")
+	rootCmd.Flags().BoolVar(&statsMode, "stats", false, "Print statistics of the numbers read from stdin")
 }
 
 func main() {
@@ -43,8 +143,10 @@ func main() {
 
 	rootCmd.SetOut(os.Stdout)
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		if !errors.As(err, new(reportedError)) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -58,21 +160,100 @@ func processExpression(input string) error {
 	input = strings.TrimPrefix(input, "calc")
 	input = strings.TrimSpace(input)
 
-	tokens, err := tokenizer.Tokenize(input)
+	res, err := evaluateInput(input, newScope())
+	if output == "json" {
+		if werr := writeJSON(os.Stdout, 0, input, res, err); werr != nil {
+			return werr
+		}
+		if err != nil {
+			return reportedError{err}
+		}
+		return nil
+	}
+
 	if err != nil {
-		return fmt.Errorf("tokenization error: %v", err)
+		return err
+	}
+	if res.warning != "" {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", res.warning)
+	}
+	fmt.Fprintln(os.Stdout, res.text)
+	return nil
+}
+
+// newScope returns an empty scope limited to --max-depth nested calls.
+func newScope() *evaluation.Scope {
+	scope := evaluation.NewScope()
+	scope.MaxDepth = maxDepth
+	return scope
+}
+
+// evaluate runs a single statement through tokenization, RPN conversion and
+// calculation. Assignments and definitions are stored in scope. offset is
+// the position of statement in the input, by which error positions are
+// moved.
+func evaluate(statement string, offset int, scope *evaluation.Scope, radians bool) (float64, []tokenizer.Token, error) {
+	tokens, err := tokenizer.TokenizeWithScope(statement, scope)
+	if err != nil {
+		return 0, nil, fmt.Errorf("tokenization error: %w", evaluation.ShiftPos(err, offset))
 	}
 
 	rpn, err := evaluation.ToRPN(tokens)
 	if err != nil {
-		return fmt.Errorf("RPN conversion error: %v", err)
+		return 0, nil, fmt.Errorf("RPN conversion error: %w", evaluation.ShiftPos(err, offset))
 	}
 
-	result, err := evaluation.Calculate(rpn, useRadians)
+	result, err := evaluation.CalculateInScope(rpn, radians, scope)
 	if err != nil {
-		return fmt.Errorf("calculation error: %v", err)
+		return 0, rpn, fmt.Errorf("calculation error: %w", evaluation.ShiftPos(err, offset))
 	}
+	return result, rpn, nil
+}
 
-	fmt.Fprintf(os.Stdout, "%.15f\n", result)
+func formatResult(x float64) string {
+	return formatter.Format(x)
+}
+
+func setFormatter() error {
+	mode, err := format.ParseMode(formatMode)
+	if err != nil {
+		return err
+	}
+	formatter = format.Formatter{Mode: mode, Precision: digits, Grouping: grouping}
+	if digits < 0 && mode == format.Fixed {
+		formatter.Precision = format.Default.Precision
+	}
 	return nil
 }
+
+// splitArgs separates the leading flags of the root command from the
+// expression. Only arguments naming a defined flag are treated as flags, so
+// "-5+3" is left alone; "--" ends the flags explicitly.
+func splitArgs(flags *pflag.FlagSet, args []string) ([]string, []string) {
+	var flagArgs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return flagArgs, args[i+1:]
+		}
+
+		var f *pflag.Flag
+		switch {
+		case strings.HasPrefix(arg, "--"):
+			name, _, _ := strings.Cut(arg[2:], "=")
+			f = flags.Lookup(name)
+		case len(arg) == 2 && arg[0] == '-':
+			f = flags.ShorthandLookup(arg[1:])
+		}
+		if f == nil {
+			return flagArgs, args[i:]
+		}
+
+		flagArgs = append(flagArgs, arg)
+		if f.NoOptDefVal == "" && !strings.Contains(arg, "=") && i+1 < len(args) {
+			i++
+			flagArgs = append(flagArgs, args[i])
+		}
+	}
+	return flagArgs, nil
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/symbolic"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// result is the outcome of evaluating an expression in one of the modes.
+type result struct {
+	// value is the result as it appears in JSON output: a number, a
+	// string for fractions and complex numbers, or nested arrays for
+	// vectors and matrices.
+	value   any
+	text    string
+	rpn     []tokenizer.Token
+	warning string
+	// integrals are those evaluated by integrate, with error estimates.
+	integrals []evaluation.Integral
+	// roots are all roots found when the expression is a call of solve.
+	roots []float64
+}
+
+// evaluateInput evaluates input in the mode selected by the flags. Variables
+// and functions defined in float64 mode are stored in scope.
+func evaluateInput(input string, scope *evaluation.Scope) (result, error) {
+	switch {
+	case intType != "" || outputBase != 10:
+		return evalInteger(input)
+	case exact:
+		return evalExact(input)
+	case hasMatrix(input, scope):
+		return evalMatrix(input, scope)
+	case complexMode || hasImaginary(input, scope):
+		return evalComplex(input, scope)
+	case precision > 0:
+		return evalPrecise(input)
+	}
+	return evalStatements(input, scope)
+}
+
+// evalStatements evaluates statements separated by ';' in one scope, so
+// "x = 3*4; x^2" works. The result is that of the last statement. Error
+// positions are in input rather than in the statement that failed.
+func evalStatements(input string, scope *evaluation.Scope) (result, error) {
+	var res result
+	start := 0
+	for _, statement := range strings.Split(input, ";") {
+		// Positions count runes, as the tokenizer does.
+		offset := utf8.RuneCountInString(input[:start])
+		start += len(statement) + 1
+		if strings.TrimSpace(statement) == "" {
+			continue
+		}
+
+		if symbolic.IsDiff(statement) {
+			d, err := evalDiff(statement, offset, useRadians)
+			res = d
+			if err != nil {
+				return res, err
+			}
+			continue
+		}
+
+		scope.TakeIntegrals()
+		scope.TakeSolutions()
+		x, rpn, err := evaluate(statement, offset, scope, useRadians)
+		res = result{value: x, text: formatResult(x), rpn: rpn, integrals: scope.TakeIntegrals()}
+		if err != nil {
+			return res, err
+		}
+		if solutions := scope.TakeSolutions(); isSolve(rpn) && len(solutions) == 1 {
+			res.roots = solutions[0]
+			res.text = formatRoots(res.roots)
+		}
+	}
+	return res, nil
+}
+
+// isSolve reports whether rpn is a single call of solve, whose result is
+// all the roots found rather than the first.
+func isSolve(rpn []tokenizer.Token) bool {
+	if len(rpn) == 0 {
+		return false
+	}
+	last := rpn[len(rpn)-1]
+	return last.Type == tokenizer.Function && last.Value == "solve"
+}
+
+func formatRoots(roots []float64) string {
+	texts := make([]string, len(roots))
+	for i, r := range roots {
+		texts[i] = formatResult(r)
+	}
+	return strings.Join(texts, ", ")
+}
+
+// evalDiff differentiates symbolically for a statement diff(expr, x),
+// whose result is the derivative in infix form. offset is the position of
+// statement in the input.
+func evalDiff(statement string, offset int, radians bool) (result, error) {
+	n, err := symbolic.Parse(statement)
+	if err != nil {
+		return result{}, fmt.Errorf("parse error: %w", evaluation.ShiftPos(err, offset))
+	}
+
+	d, err := symbolic.Expand(n, radians)
+	if err != nil {
+		return result{}, fmt.Errorf("differentiation error: %w", evaluation.ShiftPos(err, offset))
+	}
+
+	text := d.String()
+	return result{value: text, text: text}, nil
+}
+
+// evalPrecise evaluates a single expression with big.Float arithmetic.
+func evalPrecise(input string) (result, error) {
+	rpn, err := compile(input)
+	if err != nil {
+		return result{}, err
+	}
+
+	x, err := evaluation.CalculateBig(rpn, useRadians, precision)
+	if err != nil {
+		return result{rpn: rpn}, fmt.Errorf("calculation error: %w", err)
+	}
+
+	text := evaluation.FormatBig(x)
+	return result{value: json.Number(text), text: text, rpn: rpn}, nil
+}
+
+// evalExact evaluates a single expression with big.Rat arithmetic, warning
+// when the result had to be approximated.
+func evalExact(input string) (result, error) {
+	rpn, err := compile(input)
+	if err != nil {
+		return result{}, err
+	}
+
+	x, err := evaluation.CalculateRational(rpn, useRadians)
+	if err != nil {
+		return result{rpn: rpn}, fmt.Errorf("calculation error: %w", err)
+	}
+
+	text := evaluation.FormatRational(x)
+	return result{value: text, text: text, rpn: rpn, warning: x.Warning()}, nil
+}
+
+// evalInteger evaluates a single expression in programmer mode.
+func evalInteger(input string) (result, error) {
+	t := evaluation.Int64
+	if intType != "" {
+		var err error
+		if t, err = evaluation.ParseIntType(intType); err != nil {
+			return result{}, err
+		}
+	}
+	switch overflow {
+	case "checked":
+	case "wrap":
+		t.Wrap = true
+	default:
+		return result{}, fmt.Errorf("overflow must be checked or wrap, not %s", overflow)
+	}
+	if outputBase < 2 || outputBase > 36 {
+		return result{}, fmt.Errorf("base must be between 2 and 36, not %d", outputBase)
+	}
+
+	rpn, err := compile(input)
+	if err != nil {
+		return result{}, err
+	}
+
+	x, err := evaluation.CalculateInteger(rpn, t)
+	if err != nil {
+		return result{rpn: rpn}, fmt.Errorf("calculation error: %w", err)
+	}
+
+	return result{
+		value: json.Number(x.String()),
+		text:  evaluation.FormatInteger(x, t, outputBase),
+		rpn:   rpn,
+	}, nil
+}
+
+// evalComplex evaluates a single expression over the complex numbers, with
+// the variables of scope.
+func evalComplex(input string, scope *evaluation.Scope) (result, error) {
+	rpn, err := compileInScope(input, scope)
+	if err != nil {
+		return result{}, err
+	}
+
+	z, err := evaluation.CalculateComplexInScope(rpn, useRadians, scope)
+	if err != nil {
+		return result{rpn: rpn}, fmt.Errorf("calculation error: %w", err)
+	}
+
+	text := evaluation.FormatComplex(z)
+	return result{value: text, text: text, rpn: rpn}, nil
+}
+
+// evalMatrix evaluates a single expression whose values may be vectors and
+// matrices, with the variables of scope.
+func evalMatrix(input string, scope *evaluation.Scope) (result, error) {
+	rpn, err := compileInScope(input, scope)
+	if err != nil {
+		return result{}, err
+	}
+
+	v, err := evaluation.CalculateMatrixInScope(rpn, useRadians, scope)
+	if err != nil {
+		return result{rpn: rpn}, fmt.Errorf("calculation error: %w", err)
+	}
+
+	if v.IsScalar() {
+		return result{value: v.Data[0], text: formatResult(v.Data[0]), rpn: rpn}, nil
+	}
+	return result{value: v.Nested(), text: evaluation.FormatValue(v), rpn: rpn}, nil
+}
+
+// compile tokenizes a single expression and converts it to RPN.
+func compile(input string) ([]tokenizer.Token, error) {
+	return compileInScope(input, nil)
+}
+
+// compileInScope compiles input as compile does, resolving names in scope,
+// which may be nil.
+func compileInScope(input string, scope tokenizer.Scope) ([]tokenizer.Token, error) {
+	tokens, err := tokenizer.TokenizeWithScope(input, scope)
+	if err != nil {
+		return nil, fmt.Errorf("tokenization error: %w", err)
+	}
+
+	rpn, err := evaluation.ToRPN(tokens)
+	if err != nil {
+		return nil, fmt.Errorf("RPN conversion error: %w", err)
+	}
+	return rpn, nil
+}
+
+// hasImaginary reports whether input uses the imaginary unit, which selects
+// complex evaluation without --complex. Names are resolved in scope, so
+// x + 2i is recognized after x = 1.
+func hasImaginary(input string, scope *evaluation.Scope) bool {
+	tokens, err := tokenizer.TokenizeWithScope(input, scope)
+	if err != nil {
+		return false
+	}
+	for _, t := range tokens {
+		if tokenizer.IsImaginary(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMatrix reports whether input builds a vector or matrix or applies a
+// matrix operator or function, which selects matrix evaluation. Names are
+// resolved in scope, so [1, 2] * x is recognized after x = 2.
+func hasMatrix(input string, scope *evaluation.Scope) bool {
+	tokens, err := tokenizer.TokenizeWithScope(input, scope)
+	if err != nil {
+		return false
+	}
+	for _, t := range tokens {
+		if t.Type == tokenizer.LeftBracket || tokenizer.MatrixOperators[t.Value] ||
+			t.Type == tokenizer.Function && tokenizer.MatrixFunctions[t.Value] {
+			return true
+		}
+	}
+	return false
+}
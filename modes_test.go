@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestStatementErrorPos(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{"sqrt(-1)", 0},
+		{"x = 1; sqrt(-x)", 7},
+		{"x = 1;; y = 2; x + z", 19},
+		{"π = 1; 1 + foo", 11},
+		{"x = 1; diff(x +, x)", 14},
+		{"x = 1; y = 2 * diff(x^2, x)", 9},
+	}
+
+	for _, tt := range tests {
+		_, err := evalStatements(tt.input, evaluation.NewScope())
+		if pos, ok := evaluation.ErrorPos(err); !ok || pos != tt.pos {
+			t.Errorf("evalStatements(%q) error = %v at %d, %v, want position %d", tt.input, err, pos, ok, tt.pos)
+		}
+		// The message quotes the position in the input too.
+		if want := fmt.Sprint(tt.pos); !strings.Contains(err.Error(), want) {
+			t.Errorf("evalStatements(%q) error = %q, want it to quote position %s", tt.input, err, want)
+		}
+	}
+}
+
+func TestStatementDiff(t *testing.T) {
+	for input, want := range map[string]string{
+		"diff(x^2, x)":            "2 * x",
+		"x = 1; 2 * diff(x^2, x)": "4 * x",
+		"diff(sin(x), x) + 1":     "cos(x) * pi / 180 + 1",
+	} {
+		res, err := evalStatements(input, evaluation.NewScope())
+		if err != nil || res.text != want {
+			t.Errorf("evalStatements(%q) = %q, %v, want %q", input, res.text, err, want)
+		}
+	}
+}
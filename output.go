@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// Exit codes of the calc command.
+const (
+	exitError  = 1 // usage and other errors
+	exitSyntax = 2 // the expression is malformed
+	exitMath   = 3 // the expression cannot be evaluated, e.g. 1/0
+)
+
+// exitCode returns the exit code for an error returned by the command.
+func exitCode(err error) int {
+	switch {
+	case evaluation.IsSyntaxError(err):
+		return exitSyntax
+	case evaluation.ErrorCode(err) != "error":
+		return exitMath
+	}
+	return exitError
+}
+
+// reportedError is an error that has already been written to the output,
+// so main only sets the exit code.
+type reportedError struct {
+	error
+}
+
+func (e reportedError) Unwrap() error {
+	return e.error
+}
+
+type jsonOutput struct {
+	Line    int        `json:"line,omitempty"`
+	Input   string     `json:"input"`
+	Result  any        `json:"result"`
+	RPN     []string   `json:"rpn"`
+	Error   *jsonError `json:"error"`
+	Warning string     `json:"warning,omitempty"`
+	// Integrals lists the value and estimated absolute error of each
+	// integral in the expression.
+	Integrals []jsonIntegral `json:"integrals,omitempty"`
+	// Roots lists every root found when the expression is a call of solve,
+	// whose result is the first.
+	Roots []float64 `json:"roots,omitempty"`
+}
+
+type jsonIntegral struct {
+	Value float64 `json:"value"`
+	Error float64 `json:"error_estimate"`
+}
+
+type jsonError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Pos     *int   `json:"pos,omitempty"`
+}
+
+// writeJSON writes the result of evaluating input, or err, as one JSON
+// object. line is the line number of input in batch mode, or 0.
+func writeJSON(w io.Writer, line int, input string, res result, err error) error {
+	enc := json.NewEncoder(w)
+	return enc.Encode(newJSONOutput(line, input, res, err))
+}
+
+// newJSONOutput returns the JSON form of the result of evaluating input.
+func newJSONOutput(line int, input string, res result, err error) jsonOutput {
+	out := jsonOutput{
+		Line:    line,
+		Input:   input,
+		Result:  res.value,
+		RPN:     rpnStrings(res.rpn),
+		Warning: res.warning,
+		Roots:   res.roots,
+	}
+	for _, i := range res.integrals {
+		out.Integrals = append(out.Integrals, jsonIntegral{Value: i.Value, Error: i.Error})
+	}
+	if err != nil {
+		out.Result = nil
+		out.Error = newJSONError(err)
+	}
+	return out
+}
+
+// newJSONError returns the JSON form of err with its stable code.
+func newJSONError(err error) *jsonError {
+	e := &jsonError{Code: evaluation.ErrorCode(err), Message: evaluation.ErrorMessage(err)}
+	if pos, ok := evaluation.ErrorPos(err); ok {
+		e.Pos = &pos
+	}
+	return e
+}
+
+// rpnStrings returns the values of the RPN tokens, leaving out the argument
+// counts of variadic calls and the lengths of quoted arguments.
+func rpnStrings(rpn []tokenizer.Token) []string {
+	values := []string{}
+	for _, t := range rpn {
+		if t.Type != tokenizer.Comma && t.Type != tokenizer.Quote {
+			values = append(values, t.Value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/a1sarpi/gocalc/src/plot"
+	"github.com/spf13/cobra"
+)
+
+var (
+	plotFrom    float64
+	plotTo      float64
+	plotVar     string
+	plotSamples int
+	plotWidth   int
+	plotHeight  int
+	plotYMin    float64
+	plotYMax    float64
+	plotSVG     string
+)
+
+var plotCmd = &cobra.Command{
+	Use:   "plot EXPRESSION...",
+	Short: "Plot expressions of one variable",
+	Long: `Plot expressions of one variable, x unless --var is given, from --from to
+--to. Each expression is a series of its own. The chart is drawn in the
+terminal, or written as an SVG image to the file given with --svg.
+
+The y axis is scaled to fit every series unless --ymin and --ymax are
+given. Points where an expression is undefined, such as sqrt(x) for x < 0
+or tan(90), are left as gaps. Use -- before an expression that starts with
+a minus sign.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if plotFrom >= plotTo {
+			return fmt.Errorf("--from must be less than --to")
+		}
+
+		opts := plot.Options{Width: plotWidth, Height: plotHeight, YMin: plotYMin, YMax: plotYMax}
+		samples := plotSamples
+		if plotSVG != "" {
+			opts.Width, opts.Height = orDefault(opts.Width, 640), orDefault(opts.Height, 400)
+			samples = orDefault(samples, 500)
+		} else {
+			opts.Width, opts.Height = orDefault(opts.Width, 72), orDefault(opts.Height, 20)
+			samples = orDefault(samples, opts.Width)
+		}
+		if samples < 2 {
+			if plotSamples == 0 {
+				// One point per column of the chart.
+				return fmt.Errorf("--width must be at least 2")
+			}
+			return fmt.Errorf("--samples must be at least 2")
+		}
+
+		var series []plot.Series
+		for _, expr := range args {
+			f, err := plot.Expression(expr, plotVar, useRadians)
+			if err != nil {
+				return fmt.Errorf("%s: tokenization error: %w", expr, err)
+			}
+			s, err := plot.Sample(expr, f, plotFrom, plotTo, samples)
+			if err != nil {
+				return fmt.Errorf("%s: calculation error: %w", expr, err)
+			}
+			series = append(series, s)
+		}
+
+		if plotSVG == "" {
+			return plot.ASCII(cmd.OutOrStdout(), series, opts)
+		}
+		file, err := os.Create(plotSVG)
+		if err != nil {
+			return err
+		}
+		if err := plot.SVG(file, series, opts); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	},
+}
+
+func init() {
+	plotCmd.Flags().Float64Var(&plotFrom, "from", -10, "Start of the plotted range")
+	plotCmd.Flags().Float64Var(&plotTo, "to", 10, "End of the plotted range")
+	plotCmd.Flags().StringVar(&plotVar, "var", "x", "Variable of the expressions")
+	plotCmd.Flags().IntVar(&plotSamples, "samples", 0, "Points per series (default one per column, or 500 for SVG)")
+	plotCmd.Flags().IntVar(&plotWidth, "width", 0, "Chart width in characters, or pixels for SVG (default 72, or 640)")
+	plotCmd.Flags().IntVar(&plotHeight, "height", 0, "Chart height in lines, or pixels for SVG (default 20, or 400)")
+	plotCmd.Flags().Float64Var(&plotYMin, "ymin", 0, "Bottom of the y axis (with --ymax)")
+	plotCmd.Flags().Float64Var(&plotYMax, "ymax", 0, "Top of the y axis (with --ymin)")
+	plotCmd.Flags().StringVar(&plotSVG, "svg", "", "Write the chart as SVG to this file")
+	rootCmd.AddCommand(plotCmd)
+}
+
+// orDefault returns n, or def when n is 0.
+func orDefault(n, def int) int {
+	if n == 0 {
+		return def
+	}
+	return n
+}
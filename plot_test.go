@@ -0,0 +1,23 @@
+package main
+
+import "testing"
+
+func TestPlotSampleCountFlag(t *testing.T) {
+	defer func(samples, width int) { plotSamples, plotWidth = samples, width }(plotSamples, plotWidth)
+
+	tests := []struct {
+		samples, width int
+		want           string
+	}{
+		{0, 1, "--width must be at least 2"},
+		{1, 72, "--samples must be at least 2"},
+		{-3, 1, "--samples must be at least 2"},
+	}
+	for _, tt := range tests {
+		plotSamples, plotWidth = tt.samples, tt.width
+		err := plotCmd.RunE(plotCmd, []string{"x"})
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("plot --samples %d --width %d: error = %v, want %q", tt.samples, tt.width, err, tt.want)
+		}
+	}
+}
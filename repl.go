@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+	"github.com/peterh/liner"
+	"github.com/spf13/cobra"
+)
+
+const (
+	replPrompt      = "> "
+	historyFileName = ".calc_history"
+)
+
+const replHelp = `Enter an expression to evaluate it, e.g. 2 * sin(30).
+  x = 3*4          assign a variable
+  f(x, y) = x + y  define a function
+  if(c, a, b)      a when c is not zero, else b
+  ans              the previous result
+  diff(x^2, x)     differentiate symbolically
+  solve(x^2=2, x)  find the real roots of an equation
+  [[1,2],[3,4]]    a matrix; * multiplies, .* is elementwise
+  5! nCr(5, 2)     factorials and combinations
+  7 // 2, 7 % 2    floor division and modulo
+
+Commands:
+  :help     show this help
+  :radians  use radians for trigonometric functions
+  :degrees  use degrees for trigonometric functions
+  :quit     leave the REPL (or press Ctrl-D)
+`
+
+var historyPath string
+
+var replCmd = &cobra.Command{
+	Use:   "repl",
+	Short: "Start an interactive session",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setFormatter(); err != nil {
+			return err
+		}
+		return runREPL(cmd.OutOrStdout())
+	},
+}
+
+func init() {
+	replCmd.Flags().StringVar(&historyPath, "history", "", "History file (default ~/"+historyFileName+")")
+	rootCmd.AddCommand(replCmd)
+}
+
+func runREPL(out io.Writer) error {
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+
+	path := historyPath
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, historyFileName)
+		}
+	}
+	if path != "" {
+		if f, err := os.Open(path); err == nil {
+			line.ReadHistory(f)
+			f.Close()
+		}
+		defer func() {
+			if f, err := os.Create(path); err == nil {
+				line.WriteHistory(f)
+				f.Close()
+			}
+		}()
+	}
+
+	scope := newScope()
+	for {
+		input, err := line.Prompt(replPrompt)
+		if err == liner.ErrPromptAborted {
+			continue
+		}
+		if err == io.EOF {
+			fmt.Fprintln(out)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(input) == "" {
+			continue
+		}
+		line.AppendHistory(strings.TrimSpace(input))
+		if replLine(input, scope, out) {
+			return nil
+		}
+	}
+}
+
+// replLine evaluates a line entered in the REPL, a command or statements,
+// and writes the result or error to out. It reports whether the line asked
+// to leave the REPL.
+func replLine(input string, scope *evaluation.Scope, out io.Writer) bool {
+	// Statements keep their leading spaces so that error positions match
+	// the line as typed.
+	statement := strings.TrimRightFunc(input, unicode.IsSpace)
+	input = strings.TrimSpace(input)
+
+	switch input {
+	case "":
+		return false
+	case ":help":
+		fmt.Fprint(out, replHelp)
+		return false
+	case ":radians":
+		useRadians = true
+		fmt.Fprintln(out, "Using radians")
+		return false
+	case ":degrees":
+		useRadians = false
+		fmt.Fprintln(out, "Using degrees")
+		return false
+	case ":quit", ":q":
+		return true
+	}
+	if strings.HasPrefix(input, ":") {
+		fmt.Fprintf(out, "Unknown command %s, see :help\n", input)
+		return false
+	}
+
+	// Input goes through the same mode selection as the command line, so
+	// matrices and complex numbers work as they do there.
+	res, err := evaluateInput(statement, scope)
+	if err != nil {
+		printREPLError(out, err)
+		return false
+	}
+	if evaluation.IsDefinition(res.rpn) {
+		return false
+	}
+	// ans is a real number. After a complex number, a matrix or a
+	// derivative it is undefined rather than the result before.
+	if x, ok := realValue(res.value); ok {
+		scope.Set("ans", x)
+	} else {
+		scope.Delete("ans")
+	}
+	if res.warning != "" {
+		fmt.Fprintf(out, "Warning: %s\n", res.warning)
+	}
+	fmt.Fprintln(out, res.text)
+	return false
+}
+
+// realValue returns the value of a result that is a real number: a
+// float64, a number printed by precision or programmer mode, or a fraction
+// of exact mode.
+func realValue(v any) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case json.Number:
+		x, err := v.Float64()
+		return x, err == nil
+	case string:
+		r, ok := new(big.Rat).SetString(v)
+		if !ok {
+			return 0, false
+		}
+		x, _ := r.Float64()
+		return x, true
+	}
+	return 0, false
+}
+
+// printREPLError reports err, pointing a caret at the offending position of
+// the line just entered when the error carries one.
+func printREPLError(out io.Writer, err error) {
+	var tokErr *tokenizer.Error
+	if errors.As(err, &tokErr) {
+		fmt.Fprintf(out, "%s^\n", strings.Repeat(" ", len(replPrompt)+tokErr.Pos))
+		fmt.Fprintf(out, "Error: %s\n", tokErr.Message)
+		return
+	}
+	if pos, ok := evaluation.ErrorPos(err); ok {
+		fmt.Fprintf(out, "%s^\n", strings.Repeat(" ", len(replPrompt)+pos))
+	}
+	fmt.Fprintf(out, "Error: %v\n", err)
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+// replSession runs lines through replLine in one scope and returns the
+// output of each.
+func replSession(t *testing.T, lines ...string) []string {
+	t.Helper()
+	defer func(radians bool) { useRadians = radians }(useRadians)
+	useRadians = false
+
+	scope := evaluation.NewScope()
+	var outputs []string
+	for _, line := range lines {
+		var out strings.Builder
+		if replLine(line, scope, &out) {
+			t.Fatalf("replLine(%q) quit", line)
+		}
+		outputs = append(outputs, out.String())
+	}
+	return outputs
+}
+
+func TestREPLAns(t *testing.T) {
+	got := replSession(t, "x = 2", "x^2", "ans + 1", "3+4i", "ans", "[1, 2] * 2", "ans")
+	want := []string{
+		"2.000000000000000\n",
+		"4.000000000000000\n",
+		"5.000000000000000\n",
+		"3+4i\n",
+		"^\nError: Unknown symbol\n",
+		"[2, 4]\n",
+		"^\nError: Unknown symbol\n",
+	}
+	for i := range want {
+		if strings.TrimLeft(got[i], " ") != want[i] {
+			t.Errorf("line %d: got %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestREPLAngleUnit(t *testing.T) {
+	got := replSession(t, "sin(90)", ":radians", "sin(pi / 2)", ":degrees", "cos(0)")
+	want := []string{
+		"1.000000000000000\n",
+		"Using radians\n",
+		"1.000000000000000\n",
+		"Using degrees\n",
+		"1.000000000000000\n",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestREPLCaret(t *testing.T) {
+	tests := []struct {
+		line  string
+		caret int
+	}{
+		{"1 + foo", 4},
+		{"   1 + foo", 7},
+		{"x = 1; sqrt(-x)", 7},
+		{"x = 1; x / 0", 9},
+	}
+
+	for _, tt := range tests {
+		out := replSession(t, tt.line)[0]
+		caret, _, _ := strings.Cut(out, "\n")
+		if want := strings.Repeat(" ", len(replPrompt)+tt.caret) + "^"; caret != want {
+			t.Errorf("replLine(%q) caret %q, want %q", tt.line, caret, want)
+		}
+	}
+}
+
+func TestREPLCommands(t *testing.T) {
+	var out strings.Builder
+	scope := evaluation.NewScope()
+	if replLine("  ", scope, &out) || out.Len() != 0 {
+		t.Errorf("blank line: quit or wrote %q", out.String())
+	}
+	if replLine(":nope", scope, &out) || !strings.Contains(out.String(), "Unknown command :nope") {
+		t.Errorf(":nope wrote %q, want an unknown command message", out.String())
+	}
+	if !replLine(":quit", scope, &out) {
+		t.Error(":quit did not quit")
+	}
+}
+
+func TestREPLVariablesInOtherModes(t *testing.T) {
+	got := replSession(t, "x = 2", "[1, 2] * x", "det([[x, 1], [1, x]])", "x + 3i", "sqrt(-x) * i",
+		"f(y) = y^2", "[1, 2] * f(1)", "v = [1, 2]")
+	want := []string{
+		"2.000000000000000\n",
+		"[2, 4]\n",
+		"3.000000000000000\n",
+		"2+3i\n",
+		"-1.4142135623731\n",
+		"",
+		"Error: calculation error: f is only available in float64 mode\n",
+		"Error: calculation error: assignment is only available in float64 mode\n",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d: got %q, want %q", i+1, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+	"github.com/spf13/cobra"
+)
+
+const serveHelp = `Serve evaluation over HTTP. Requests and responses are JSON.
+
+  POST /eval       {"expr": "2 * sin(30)", "radians": false, "timeout_ms": 100}
+  POST /batch      {"exprs": ["1 + 2", "sqrt(16)"], "radians": false, "timeout_ms": 100}
+  GET  /functions  the built-in functions and their argument counts
+
+Results have the form of --output json. /eval answers 400 when the
+expression is malformed and 422 when it cannot be evaluated; /batch reports
+errors per expression. timeout_ms is capped by --timeout and covers the
+whole request, so the expressions of a batch share it.`
+
+var (
+	serveAddr    string
+	serveTimeout time.Duration
+	serveMaxBody int64
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start an HTTP evaluation server",
+	Long:  serveHelp,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srv := &http.Server{
+			Addr:              serveAddr,
+			Handler:           newServeMux(),
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			// Leave time to write the response after a calculation that
+			// used all of --timeout.
+			WriteTimeout: serveTimeout + 10*time.Second,
+			IdleTimeout:  time.Minute,
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "Listening on %s\n", serveAddr)
+		return srv.ListenAndServe()
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().DurationVar(&serveTimeout, "timeout", evaluation.DefaultCalculationTime, "Maximum calculation time per request")
+	serveCmd.Flags().Int64Var(&serveMaxBody, "max-body", 1<<20, "Maximum request body size in bytes")
+	rootCmd.AddCommand(serveCmd)
+}
+
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /eval", handleEval)
+	mux.HandleFunc("POST /batch", handleBatch)
+	mux.HandleFunc("GET /functions", handleFunctions)
+	return mux
+}
+
+type evalRequest struct {
+	Expr      string `json:"expr"`
+	Radians   *bool  `json:"radians"`
+	TimeoutMS int64  `json:"timeout_ms"`
+}
+
+type batchRequest struct {
+	Exprs     []string `json:"exprs"`
+	Radians   *bool    `json:"radians"`
+	TimeoutMS int64    `json:"timeout_ms"`
+}
+
+type batchResponse struct {
+	Results []jsonOutput `json:"results"`
+}
+
+type functionInfo struct {
+	Name    string `json:"name"`
+	MinArgs int    `json:"min_args"`
+	// MaxArgs is null for functions taking any number of arguments.
+	MaxArgs *int `json:"max_args"`
+}
+
+func handleEval(w http.ResponseWriter, r *http.Request) {
+	var req evalRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	res, err := evaluateRequest(req.Expr, requestRadians(req.Radians), requestTimeout(req.TimeoutMS))
+	status := http.StatusOK
+	switch {
+	case evaluation.IsSyntaxError(err):
+		status = http.StatusBadRequest
+	case err != nil:
+		status = http.StatusUnprocessableEntity
+	}
+	writeResponse(w, status, newJSONOutput(0, req.Expr, res, err))
+}
+
+func handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	// The expressions share the time allowed for the request, so a long
+	// batch cannot run for --timeout per expression.
+	deadline := time.Now().Add(requestTimeout(req.TimeoutMS))
+	resp := batchResponse{Results: make([]jsonOutput, len(req.Exprs))}
+	for i, expr := range req.Exprs {
+		res, err := evaluateRequest(expr, requestRadians(req.Radians), time.Until(deadline))
+		resp.Results[i] = newJSONOutput(0, expr, res, err)
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+func handleFunctions(w http.ResponseWriter, r *http.Request) {
+	functions := []functionInfo{}
+	for name := range tokenizer.Functions {
+		a := tokenizer.ArityOf(name)
+		info := functionInfo{Name: name, MinArgs: a.Min}
+		if a.Max != tokenizer.Variadic {
+			info.MaxArgs = &a.Max
+		}
+		functions = append(functions, info)
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+	writeResponse(w, http.StatusOK, functions)
+}
+
+// evaluateRequest evaluates a single expression of a request in float64
+// mode. The scope only collects the integrals evaluated, for their error
+// estimates.
+func evaluateRequest(expr string, radians bool, timeout time.Duration) (result, error) {
+	rpn, err := compile(expr)
+	if err != nil {
+		return result{}, err
+	}
+
+	scope := evaluation.NewScope()
+	scope.Timeout = timeout
+	x, err := evaluation.CalculateInScope(rpn, radians, scope)
+	if err != nil {
+		return result{rpn: rpn}, fmt.Errorf("calculation error: %w", err)
+	}
+	return result{value: x, rpn: rpn, integrals: scope.TakeIntegrals()}, nil
+}
+
+// decodeRequest reads the JSON body of r into v, answering the request with
+// an error when the body is too large or malformed.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxBody))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request_too_large",
+				fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, status int, code, msg string) {
+	writeResponse(w, status, struct {
+		Error jsonError `json:"error"`
+	}{jsonError{Code: code, Message: msg}})
+}
+
+func writeResponse(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// requestRadians returns the angle unit of a request, defaulting to --radians.
+func requestRadians(requested *bool) bool {
+	if requested == nil {
+		return useRadians
+	}
+	return *requested
+}
+
+// requestTimeout returns the calculation time allowed for a request, which may lower but not raise --timeout.
+func requestTimeout(ms int64) time.Duration {
+	if ms <= 0 || ms > serveTimeout.Milliseconds() {
+		return serveTimeout
+	}
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServeEval(t *testing.T) {
+	tests := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"expr": "3 - 2", "radians": true}`, http.StatusOK, ""},
+		{`{"expr": "2 +"}`, http.StatusBadRequest, "invalid_syntax"},
+		{`{"expr": "1 / 0"}`, http.StatusUnprocessableEntity, "division_by_zero"},
+		{`{"expr": "sqrt(-4)"}`, http.StatusUnprocessableEntity, "domain"},
+		{`{"expression": "1"}`, http.StatusBadRequest, "bad_request"},
+		{`{"expr": `, http.StatusBadRequest, "bad_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.body, func(t *testing.T) {
+			rec := serveRequest(t, http.MethodPost, "/eval", tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var out jsonOutput
+			if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+				t.Fatalf("decoding %s: %v", rec.Body, err)
+			}
+			switch {
+			case tt.code == "" && out.Error != nil:
+				t.Errorf("error = %+v, want none", out.Error)
+			case tt.code == "" && out.Result != 1.0:
+				t.Errorf("result = %v, want 1", out.Result)
+			case tt.code != "" && (out.Error == nil || out.Error.Code != tt.code):
+				t.Errorf("error = %+v, want code %s", out.Error, tt.code)
+			}
+		})
+	}
+}
+
+func TestServeIntegralErrorEstimate(t *testing.T) {
+	rec := serveRequest(t, http.MethodPost, "/eval", `{"expr": "integrate(x^2, x, 0, 3)"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var out jsonOutput
+	if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if len(out.Integrals) != 1 || math.Abs(out.Integrals[0].Value-9) > 1e-9 || out.Integrals[0].Error > 1e-9 {
+		t.Errorf("integrals = %+v, want 9 with its error estimate", out.Integrals)
+	}
+
+	rec = serveRequest(t, http.MethodPost, "/eval", `{"expr": "integrate(1 / x, x, 0, 1)"}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "no_convergence") {
+		t.Errorf("integrate(1 / x, x, 0, 1): status = %d, body %s, want no_convergence", rec.Code, rec.Body)
+	}
+}
+
+func TestServeBodyTooLarge(t *testing.T) {
+	defer func(n int64) { serveMaxBody = n }(serveMaxBody)
+	serveMaxBody = 16
+
+	rec := serveRequest(t, http.MethodPost, "/eval", `{"expr": "1 + 2 + 3 + 4"}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusRequestEntityTooLarge, rec.Body)
+	}
+}
+
+func TestServeBatch(t *testing.T) {
+	rec := serveRequest(t, http.MethodPost, "/batch", `{"exprs": ["1 + 2", "1 / 0", "sqrt(16)"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+	var resp batchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if len(resp.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(resp.Results))
+	}
+	if resp.Results[0].Result != 3.0 || resp.Results[2].Result != 4.0 {
+		t.Errorf("results = %v, %v, want 3, 4", resp.Results[0].Result, resp.Results[2].Result)
+	}
+	if e := resp.Results[1].Error; e == nil || e.Code != "division_by_zero" || e.Pos == nil || *e.Pos != 2 || e.Message != "/: division by zero" {
+		t.Errorf("error = %+v, want division_by_zero at position 2", e)
+	}
+}
+
+func TestServeFunctions(t *testing.T) {
+	rec := serveRequest(t, http.MethodGet, "/functions", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var functions []functionInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &functions); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+
+	found := map[string]functionInfo{}
+	for _, f := range functions {
+		found[f.Name] = f
+	}
+	if f := found["sin"]; f.MinArgs != 1 || f.MaxArgs == nil || *f.MaxArgs != 1 {
+		t.Errorf("sin = %+v, want one argument", f)
+	}
+	if f, ok := found["max"]; !ok || f.MaxArgs != nil {
+		t.Errorf("max = %+v, want any number of arguments", f)
+	}
+}
+
+func serveRequest(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	newServeMux().ServeHTTP(rec, req)
+	return rec
+}
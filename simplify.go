@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/a1sarpi/gocalc/src/symbolic"
+	"github.com/spf13/cobra"
+)
+
+var simplifyCmd = &cobra.Command{
+	Use:   "simplify EXPRESSION",
+	Short: "Simplify an expression symbolically",
+	Long: `Simplify an expression symbolically: fold constant subexpressions, drop
+identities such as x * 1 and x + 0, and collect like terms, so
+"3*x + 2 - x + 1" becomes 2 * x + 3. Names other than the built-in
+constants and functions are variables. Use -- before an expression that
+starts with a minus sign.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		s, err := symbolic.SimplifyString(args[0], useRadians)
+		if err != nil {
+			return fmt.Errorf("parse error: %w", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), s)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(simplifyCmd)
+}
@@ -0,0 +1,444 @@
+// Package bigmath implements elementary functions on big.Float values.
+//
+// Every function computes its result with guard bits and rounds it to the
+// requested precision. Callers must reject arguments outside a function's
+// domain, since big.Float has no NaN.
+package bigmath
+
+import (
+	"math"
+	"math/big"
+)
+
+// guardBits is the extra working precision used inside the series.
+const guardBits = 64
+
+func newFloat(prec uint) *big.Float {
+	return new(big.Float).SetPrec(prec)
+}
+
+func round(x *big.Float, prec uint) *big.Float {
+	return newFloat(prec).Set(x)
+}
+
+// Pi returns π rounded to prec bits, using Machin's formula
+// π = 16·atan(1/5) − 4·atan(1/239).
+func Pi(prec uint) *big.Float {
+	wp := prec + guardBits
+	a := atanInv(5, wp)
+	a.Mul(a, big.NewFloat(16))
+	b := atanInv(239, wp)
+	b.Mul(b, big.NewFloat(4))
+	return round(a.Sub(a, b), prec)
+}
+
+// Ln2 returns the natural logarithm of 2 rounded to prec bits.
+func Ln2(prec uint) *big.Float {
+	wp := prec + guardBits
+	third := newFloat(wp).Quo(big.NewFloat(1), big.NewFloat(3))
+	x := atanhSeries(third, wp)
+	return round(x.Mul(x, big.NewFloat(2)), prec)
+}
+
+// Exp returns e**x rounded to prec bits. The result is ±Inf when it does not
+// fit into the exponent range of big.Float.
+func Exp(x *big.Float, prec uint) *big.Float {
+	if x.Sign() == 0 {
+		return newFloat(prec).SetInt64(1)
+	}
+	if x.IsInf() {
+		if x.Sign() > 0 {
+			return newFloat(prec).SetInf(false)
+		}
+		return newFloat(prec)
+	}
+
+	// x = k·ln2 + r with |r| <= ln2/2, so e**x = 2**k · e**r.
+	wp := prec + guardBits + uint(max(x.MantExp(nil), 0))
+	ln2 := Ln2(wp)
+	q := newFloat(wp).Quo(x, ln2)
+	kf, _ := q.Float64()
+	k := math.Round(kf)
+	if math.Abs(k) > math.MaxInt32 {
+		if x.Sign() > 0 {
+			return newFloat(prec).SetInf(false)
+		}
+		return newFloat(prec)
+	}
+	r := newFloat(wp).Mul(ln2, big.NewFloat(k))
+	r.Sub(newFloat(wp).Set(x), r)
+
+	// Halve r a few more times to speed up the series, then square back.
+	const halvings = 16
+	r.SetMantExp(r, -halvings)
+	y := expSeries(r, wp)
+	for i := 0; i < halvings; i++ {
+		y.Mul(y, y)
+	}
+
+	y.SetMantExp(y, int(k))
+	return round(y, prec)
+}
+
+// Log returns the natural logarithm of x rounded to prec bits. x must be
+// positive.
+func Log(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+
+	// x = m·2**e with m in [1/√2, √2), so ln x = ln m + e·ln2.
+	m := newFloat(wp)
+	e := x.MantExp(m)
+	if m.Cmp(big.NewFloat(math.Sqrt2/2)) < 0 {
+		m.SetMantExp(m, 1)
+		e--
+	}
+
+	// ln m = 2·atanh((m−1)/(m+1))
+	num := newFloat(wp).Sub(m, big.NewFloat(1))
+	den := newFloat(wp).Add(m, big.NewFloat(1))
+	y := atanhSeries(num.Quo(num, den), wp)
+	y.Mul(y, big.NewFloat(2))
+
+	if e != 0 {
+		t := Ln2(wp)
+		t.Mul(t, newFloat(wp).SetInt64(int64(e)))
+		y.Add(y, t)
+	}
+	return round(y, prec)
+}
+
+// Log2 returns the binary logarithm of x rounded to prec bits. Powers of two
+// give exact results. x must be positive.
+func Log2(x *big.Float, prec uint) *big.Float {
+	m := new(big.Float)
+	e := x.MantExp(m)
+	if m.Cmp(big.NewFloat(0.5)) == 0 {
+		return newFloat(prec).SetInt64(int64(e - 1))
+	}
+	wp := prec + guardBits
+	y := Log(x, wp)
+	return round(y.Quo(y, Ln2(wp)), prec)
+}
+
+// Log10 returns the decimal logarithm of x rounded to prec bits. x must be
+// positive.
+func Log10(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	y := Log(x, wp)
+	return round(y.Quo(y, Log(big.NewFloat(10), wp)), prec)
+}
+
+// Sqrt returns the square root of x rounded to prec bits. x must not be
+// negative.
+func Sqrt(x *big.Float, prec uint) *big.Float {
+	return newFloat(prec).Sqrt(x)
+}
+
+// Pow returns a**b rounded to prec bits. Integer exponents are computed by
+// repeated squaring; otherwise a must not be negative.
+func Pow(a, b *big.Float, prec uint) *big.Float {
+	if b.IsInt() {
+		if n, acc := b.Int64(); acc == big.Exact && n != math.MinInt64 {
+			return powInt(a, n, prec)
+		}
+	}
+	if a.Sign() == 0 {
+		if b.Sign() < 0 {
+			return newFloat(prec).SetInf(false)
+		}
+		return newFloat(prec)
+	}
+
+	wp := prec + guardBits
+	abs := newFloat(wp).Abs(a)
+	y := Log(abs, wp)
+	y.Mul(y, b)
+	y = Exp(y, wp)
+	if a.Sign() < 0 {
+		// Only reachable for integer exponents too large for int64.
+		if odd := new(big.Int); b.IsInt() {
+			b.Int(odd)
+			if odd.Bit(0) == 1 {
+				y.Neg(y)
+			}
+		}
+	}
+	return round(y, prec)
+}
+
+func powInt(a *big.Float, n int64, prec uint) *big.Float {
+	wp := prec + guardBits + 64
+	neg := n < 0
+	if neg {
+		n = -n
+	}
+
+	result := newFloat(wp).SetInt64(1)
+	base := newFloat(wp).Set(a)
+	for n > 0 {
+		if n&1 == 1 {
+			result.Mul(result, base)
+		}
+		n >>= 1
+		if n > 0 {
+			base.Mul(base, base)
+		}
+	}
+
+	if neg {
+		if result.Sign() == 0 {
+			return newFloat(prec).SetInf(false)
+		}
+		result.Quo(newFloat(wp).SetInt64(1), result)
+	}
+	return round(result, prec)
+}
+
+// Sin returns the sine of x (in radians) rounded to prec bits.
+func Sin(x *big.Float, prec uint) *big.Float {
+	s, _ := sinCos(x, prec)
+	return s
+}
+
+// Cos returns the cosine of x (in radians) rounded to prec bits.
+func Cos(x *big.Float, prec uint) *big.Float {
+	_, c := sinCos(x, prec)
+	return c
+}
+
+// Tan returns the tangent of x (in radians) rounded to prec bits.
+func Tan(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	s, c := sinCos(x, wp)
+	return round(s.Quo(s, c), prec)
+}
+
+// Atan returns the arctangent of x, in radians, rounded to prec bits.
+func Atan(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	if x.Sign() == 0 {
+		return newFloat(prec)
+	}
+
+	t := newFloat(wp).Abs(x)
+	invert := t.Cmp(big.NewFloat(1)) > 0
+	if invert {
+		t.Quo(newFloat(wp).SetInt64(1), t)
+	}
+
+	// atan t = 2·atan(t / (1 + √(1+t²))), applied twice.
+	for i := 0; i < 2; i++ {
+		d := newFloat(wp).Mul(t, t)
+		d.Add(d, big.NewFloat(1))
+		d.Sqrt(d)
+		d.Add(d, big.NewFloat(1))
+		t.Quo(t, d)
+	}
+	y := atanSeries(t, wp)
+	y.SetMantExp(y, 2)
+
+	if invert {
+		half := Pi(wp)
+		half.SetMantExp(half, -1)
+		y.Sub(half, y)
+	}
+	if x.Sign() < 0 {
+		y.Neg(y)
+	}
+	return round(y, prec)
+}
+
+// Asin returns the arcsine of x, in radians, rounded to prec bits. x must
+// lie in [-1, 1].
+func Asin(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	one := big.NewFloat(1)
+	if newFloat(wp).Abs(x).Cmp(one) == 0 {
+		half := Pi(wp)
+		half.SetMantExp(half, -1)
+		if x.Sign() < 0 {
+			half.Neg(half)
+		}
+		return round(half, prec)
+	}
+
+	// asin x = atan(x / √(1−x²))
+	d := newFloat(wp).Mul(x, x)
+	d.Sub(one, d)
+	d.Sqrt(d)
+	return Atan(d.Quo(x, d), prec)
+}
+
+// Acos returns the arccosine of x, in radians, rounded to prec bits. x must
+// lie in [-1, 1].
+func Acos(x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	half := Pi(wp)
+	half.SetMantExp(half, -1)
+	return round(half.Sub(half, Asin(x, wp)), prec)
+}
+
+// Atan2 returns the arctangent of y/x, in radians, using the signs of both
+// to determine the quadrant.
+func Atan2(y, x *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	if x.Sign() == 0 {
+		if y.Sign() == 0 {
+			return newFloat(prec)
+		}
+		half := Pi(wp)
+		half.SetMantExp(half, -1)
+		if y.Sign() < 0 {
+			half.Neg(half)
+		}
+		return round(half, prec)
+	}
+
+	a := Atan(newFloat(wp).Quo(y, x), wp)
+	if x.Sign() < 0 {
+		if y.Sign() < 0 {
+			a.Sub(a, Pi(wp))
+		} else {
+			a.Add(a, Pi(wp))
+		}
+	}
+	return round(a, prec)
+}
+
+// Hypot returns √(x²+y²) rounded to prec bits.
+func Hypot(x, y *big.Float, prec uint) *big.Float {
+	wp := prec + guardBits
+	a := newFloat(wp).Mul(x, x)
+	a.Add(a, newFloat(wp).Mul(y, y))
+	return Sqrt(a, prec)
+}
+
+func sinCos(x *big.Float, prec uint) (*big.Float, *big.Float) {
+	// Reducing a huge argument needs π to as many bits as x has integer bits.
+	wp := prec + guardBits + uint(max(x.MantExp(nil), 0))
+
+	// x = n·π/2 + r with |r| <= π/4.
+	half := Pi(wp)
+	half.SetMantExp(half, -1)
+	q := newFloat(wp).Quo(x, half)
+	n, _ := roundToInt(q).Int(nil)
+	r := newFloat(wp).SetInt(n)
+	r.Mul(r, half)
+	r.Sub(newFloat(wp).Set(x), r)
+
+	s, c := sinSeries(r, wp), cosSeries(r, wp)
+	switch new(big.Int).Mod(n, big.NewInt(4)).Int64() {
+	case 1:
+		s, c = c, s.Neg(s)
+	case 2:
+		s, c = s.Neg(s), c.Neg(c)
+	case 3:
+		s, c = c.Neg(c), s
+	}
+	return round(s, prec), round(c, prec)
+}
+
+func roundToInt(x *big.Float) *big.Float {
+	half := big.NewFloat(0.5)
+	y := new(big.Float).SetPrec(x.Prec()).Abs(x)
+	y.Add(y, half)
+	i, _ := y.Int(nil)
+	y.SetInt(i)
+	if x.Sign() < 0 {
+		y.Neg(y)
+	}
+	return y
+}
+
+// expSeries sums x**n/n! for small x.
+func expSeries(x *big.Float, prec uint) *big.Float {
+	sum := newFloat(prec).SetInt64(1)
+	term := newFloat(prec).SetInt64(1)
+	for n := int64(1); ; n++ {
+		term.Mul(term, x)
+		term.Quo(term, newFloat(prec).SetInt64(n))
+		if negligible(term, sum, prec) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// sinSeries sums (−1)**n·x**(2n+1)/(2n+1)! for |x| <= π/4.
+func sinSeries(x *big.Float, prec uint) *big.Float {
+	sum := newFloat(prec).Set(x)
+	term := newFloat(prec).Set(x)
+	x2 := newFloat(prec).Mul(x, x)
+	for n := int64(1); ; n++ {
+		term.Mul(term, x2)
+		term.Quo(term, newFloat(prec).SetInt64(-(2*n)*(2*n+1)))
+		if negligible(term, sum, prec) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// cosSeries sums (−1)**n·x**(2n)/(2n)! for |x| <= π/4.
+func cosSeries(x *big.Float, prec uint) *big.Float {
+	sum := newFloat(prec).SetInt64(1)
+	term := newFloat(prec).SetInt64(1)
+	x2 := newFloat(prec).Mul(x, x)
+	for n := int64(1); ; n++ {
+		term.Mul(term, x2)
+		term.Quo(term, newFloat(prec).SetInt64(-(2*n-1)*(2*n)))
+		if negligible(term, sum, prec) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// atanSeries sums (−1)**n·x**(2n+1)/(2n+1) for small |x|.
+func atanSeries(x *big.Float, prec uint) *big.Float {
+	return oddSeries(x, prec, true)
+}
+
+// atanhSeries sums x**(2n+1)/(2n+1) for small |x|.
+func atanhSeries(x *big.Float, prec uint) *big.Float {
+	return oddSeries(x, prec, false)
+}
+
+func oddSeries(x *big.Float, prec uint, alternating bool) *big.Float {
+	sum := newFloat(prec).Set(x)
+	if x.Sign() == 0 {
+		return sum
+	}
+	power := newFloat(prec).Set(x)
+	x2 := newFloat(prec).Mul(x, x)
+	if alternating {
+		x2.Neg(x2)
+	}
+	term := newFloat(prec)
+	for n := int64(1); ; n++ {
+		power.Mul(power, x2)
+		term.Quo(power, newFloat(prec).SetInt64(2*n+1))
+		if negligible(term, sum, prec) {
+			return sum
+		}
+		sum.Add(sum, term)
+	}
+}
+
+// atanInv returns atan(1/n) for an integer n > 1.
+func atanInv(n int64, prec uint) *big.Float {
+	x := newFloat(prec).Quo(big.NewFloat(1), newFloat(prec).SetInt64(n))
+	return atanSeries(x, prec)
+}
+
+// negligible reports whether adding term to sum no longer changes it at the
+// given precision.
+func negligible(term, sum *big.Float, prec uint) bool {
+	if term.Sign() == 0 {
+		return true
+	}
+	if sum.Sign() == 0 {
+		return false
+	}
+	return term.MantExp(nil) < sum.MantExp(nil)-int(prec)-1
+}
@@ -0,0 +1,109 @@
+package bigmath_test
+
+import (
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/bigmath"
+)
+
+const piDigits = "3.14159265358979323846264338327950288419716939937510582097494459230781640628620899862803482534211706798"
+
+func TestPi(t *testing.T) {
+	want, _, err := big.ParseFloat(piDigits, 10, 330, big.ToNearestEven)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNear(t, "Pi(300)", bigmath.Pi(300), want, 298)
+}
+
+func TestAgainstFloat64(t *testing.T) {
+	unary := []struct {
+		name string
+		fn   func(*big.Float, uint) *big.Float
+		want func(float64) float64
+		args []float64
+	}{
+		{"Exp", bigmath.Exp, math.Exp, []float64{-20, -1, 0.5, 1, 3.75, 100}},
+		{"Log", bigmath.Log, math.Log, []float64{1e-10, 0.3, 1, 2, math.E, 1e20}},
+		{"Log2", bigmath.Log2, math.Log2, []float64{0.125, 3, 1024}},
+		{"Log10", bigmath.Log10, math.Log10, []float64{0.001, 7, 1e12}},
+		{"Sin", bigmath.Sin, math.Sin, []float64{-7, -1, 0, 0.5, 2, 3, 100}},
+		{"Cos", bigmath.Cos, math.Cos, []float64{-7, -1, 0, 0.5, 2, 3, 100}},
+		{"Tan", bigmath.Tan, math.Tan, []float64{-1, 0.3, 1.2}},
+		{"Atan", bigmath.Atan, math.Atan, []float64{-50, -1, 0, 0.1, 1, 3}},
+		{"Asin", bigmath.Asin, math.Asin, []float64{-1, -0.5, 0, 0.9, 1}},
+		{"Acos", bigmath.Acos, math.Acos, []float64{-1, -0.5, 0, 0.9, 1}},
+		{"Sqrt", bigmath.Sqrt, math.Sqrt, []float64{0, 2, 1e10}},
+	}
+
+	for _, tt := range unary {
+		for _, arg := range tt.args {
+			got, _ := tt.fn(big.NewFloat(arg), 53).Float64()
+			if want := tt.want(arg); !close(got, want) {
+				t.Errorf("%s(%v) = %v, want %v", tt.name, arg, got, want)
+			}
+		}
+	}
+
+	binary := []struct {
+		name string
+		fn   func(*big.Float, *big.Float, uint) *big.Float
+		want func(float64, float64) float64
+		args [][2]float64
+	}{
+		{"Pow", bigmath.Pow, math.Pow, [][2]float64{{2, 10}, {2, -2}, {-3, 3}, {2, 0.5}, {10, -1.5}, {0, 2}}},
+		{"Atan2", bigmath.Atan2, math.Atan2, [][2]float64{{1, 1}, {1, -1}, {-1, -1}, {-1, 0}, {0, -2}}},
+		{"Hypot", bigmath.Hypot, math.Hypot, [][2]float64{{3, 4}, {-5, 12}}},
+	}
+
+	for _, tt := range binary {
+		for _, args := range tt.args {
+			got, _ := tt.fn(big.NewFloat(args[0]), big.NewFloat(args[1]), 53).Float64()
+			if want := tt.want(args[0], args[1]); !close(got, want) {
+				t.Errorf("%s(%v, %v) = %v, want %v", tt.name, args[0], args[1], got, want)
+			}
+		}
+	}
+}
+
+func TestHighPrecision(t *testing.T) {
+	const prec = 400
+
+	// sin(π/6) = 1/2 and e**ln(3) = 3 to nearly all bits.
+	x := bigmath.Pi(prec)
+	x.Quo(x, big.NewFloat(6))
+	assertNear(t, "sin(pi/6)", bigmath.Sin(x, prec), big.NewFloat(0.5), prec-8)
+
+	y := bigmath.Exp(bigmath.Log(big.NewFloat(3), prec), prec)
+	assertNear(t, "exp(log(3))", y, big.NewFloat(3), prec-8)
+
+	z := bigmath.Atan(big.NewFloat(1), prec)
+	z.SetMantExp(z, 2)
+	assertNear(t, "4*atan(1)", z, bigmath.Pi(prec), prec-8)
+}
+
+func TestOverflow(t *testing.T) {
+	if got := bigmath.Exp(big.NewFloat(1e12), 64); !got.IsInf() {
+		t.Errorf("Exp(1e12) = %v, want +Inf", got)
+	}
+	if got := bigmath.Pow(big.NewFloat(0), big.NewFloat(-1), 64); !got.IsInf() {
+		t.Errorf("Pow(0, -1) = %v, want +Inf", got)
+	}
+}
+
+func assertNear(t *testing.T, name string, got, want *big.Float, bits int) {
+	t.Helper()
+	diff := new(big.Float).Sub(got, want)
+	if diff.Sign() != 0 && diff.MantExp(nil) > want.MantExp(nil)-bits {
+		t.Errorf("%s = %s, want %s", name, got.Text('g', 50), want.Text('g', 50))
+	}
+}
+
+func close(a, b float64) bool {
+	if a == b {
+		return true
+	}
+	return math.Abs(a-b) <= 1e-14*math.Max(math.Abs(a), math.Abs(b))
+}
@@ -0,0 +1,349 @@
+package evaluation
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/bigmath"
+	"github.com/a1sarpi/gocalc/src/stack"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// CalculateBig evaluates RPN tokens with big.Float arithmetic at prec bits
+// of mantissa. It supports the same operators and functions as Calculate but
+// not variables.
+func CalculateBig(tokens []tokenizer.Token, useRadians bool, prec uint) (*big.Float, error) {
+	startTime := time.Now()
+	s := stack.New[*big.Float]()
+	argc := 0
+
+	for _, token := range tokens {
+		if time.Since(startTime) > DefaultCalculationTime {
+			return nil, ErrTimeout
+		}
+
+		switch token.Type {
+		case tokenizer.Number:
+			if tokenizer.IsImaginary(token) {
+				return nil, ErrComplexValue
+			}
+			val, _, err := big.ParseFloat(token.Value, 0, prec, big.ToNearestEven)
+			if err != nil {
+				return nil, tokenizer.ErrInvalidNumber(token.Pos)
+			}
+			s.Push(val)
+
+		case tokenizer.Constant:
+			switch token.Value {
+			case "pi":
+				s.Push(bigmath.Pi(prec))
+			case "e":
+				s.Push(bigmath.Exp(big.NewFloat(1), prec))
+			case "i":
+				return nil, ErrComplexValue
+			}
+
+		case tokenizer.Variable:
+			return nil, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+
+		case tokenizer.Quote:
+			return nil, ErrFloatOnly(binderName(tokens))
+
+		case tokenizer.Comma:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 {
+				return nil, ErrInvalidRPNSyntax
+			}
+			argc = n
+
+		case tokenizer.RightBracket:
+			return nil, ErrMatrixValue
+
+		case tokenizer.Function:
+			if tokenizer.MatrixFunctions[token.Value] {
+				return nil, ErrMatrixValue
+			}
+			arity := tokenizer.ArityOf(token.Value)
+			if argc == 0 {
+				argc = arity.Min
+			}
+			if !arity.Accepts(argc) {
+				return nil, tokenizer.ErrArgumentCount(token.Value, token.Pos)
+			}
+			if s.Len() < argc {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			args := make([]*big.Float, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = s.Pop()
+			}
+			argc = 0
+
+			result, err := bigFunction(token.Value, args, useRadians, prec, token.Pos)
+			if err != nil {
+				return nil, err
+			}
+			if result.IsInf() {
+				return nil, ErrArithmeticOverflow
+			}
+			s.Push(result)
+
+		case tokenizer.UnaryOperator:
+			if s.IsEmpty() {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			x := s.Pop()
+			switch token.Value {
+			case "-":
+				s.Push(new(big.Float).Neg(x))
+			case "!", "!!":
+				result, err := bigFunction(token.Value, []*big.Float{x}, useRadians, prec, token.Pos)
+				if err != nil {
+					return nil, err
+				}
+				s.Push(result)
+			default:
+				return nil, unsupportedOperator(token.Value)
+			}
+
+		case tokenizer.Operator:
+			if s.Len() < 2 {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			b := s.Pop()
+			a := s.Pop()
+
+			result := new(big.Float).SetPrec(prec)
+			switch token.Value {
+			case "+":
+				result.Add(a, b)
+			case "-":
+				result.Sub(a, b)
+			case "*":
+				result.Mul(a, b)
+			case "/":
+				if b.Sign() == 0 {
+					return nil, &DivisionError{token.Value, token.Pos}
+				}
+				result.Quo(a, b)
+			case "%", "//":
+				if b.Sign() == 0 {
+					return nil, &DivisionError{token.Value, token.Pos}
+				}
+				result = bigModulo(token.Value, a, b, prec)
+			case "^":
+				if err := checkBigPow(token.Value, a, b, token.Pos); err != nil {
+					return nil, err
+				}
+				result = bigmath.Pow(a, b, prec)
+			default:
+				return nil, unsupportedOperator(token.Value)
+			}
+			if result.IsInf() {
+				return nil, ErrArithmeticOverflow
+			}
+			s.Push(result)
+
+		default:
+			return nil, ErrInvalidRPNSyntax
+		}
+	}
+
+	if s.Len() != 1 {
+		return nil, ErrInvalidRPNSyntax
+	}
+	return s.Pop(), nil
+}
+
+func bigFunction(name string, args []*big.Float, useRadians bool, prec uint, pos int) (*big.Float, error) {
+	if tokenizer.Statistics[name] {
+		return bigStatistic(name, args, prec, pos)
+	}
+	x := args[0]
+	one := big.NewFloat(1)
+
+	switch name {
+	case "sin", "cos", "tan", "tg", "ctg":
+		if !useRadians {
+			x = bigDegreesToRadians(x, prec)
+		}
+		switch name {
+		case "sin":
+			return bigmath.Sin(x, prec), nil
+		case "cos":
+			return bigmath.Cos(x, prec), nil
+		case "ctg":
+			t := bigmath.Tan(x, prec)
+			if t.Sign() == 0 {
+				return nil, bigDomainError(name, x, pos)
+			}
+			return new(big.Float).SetPrec(prec).Quo(one, t), nil
+		}
+		return bigmath.Tan(x, prec), nil
+
+	case "asin", "acos", "atan":
+		var result *big.Float
+		switch name {
+		case "asin", "acos":
+			if new(big.Float).Abs(x).Cmp(one) > 0 {
+				return nil, bigDomainError(name, x, pos)
+			}
+			if name == "asin" {
+				result = bigmath.Asin(x, prec)
+			} else {
+				result = bigmath.Acos(x, prec)
+			}
+		default:
+			result = bigmath.Atan(x, prec)
+		}
+		if !useRadians {
+			result = bigRadiansToDegrees(result, prec)
+		}
+		return result, nil
+
+	case "log", "log2", "log10":
+		for _, arg := range args {
+			if arg.Sign() <= 0 {
+				return nil, bigDomainError(name, arg, pos)
+			}
+		}
+		switch {
+		case name == "log" && len(args) == 2:
+			base := bigmath.Log(args[0], prec+32)
+			if base.Sign() == 0 {
+				return nil, bigDomainError(name, args[0], pos)
+			}
+			y := bigmath.Log(args[1], prec+32)
+			return new(big.Float).SetPrec(prec).Quo(y, base), nil
+		case name == "log":
+			return bigmath.Log(x, prec), nil
+		case name == "log2":
+			return bigmath.Log2(x, prec), nil
+		}
+		return bigmath.Log10(x, prec), nil
+
+	case "sqrt":
+		if x.Sign() < 0 {
+			return nil, bigDomainError(name, x, pos)
+		}
+		return bigmath.Sqrt(x, prec), nil
+
+	case "abs":
+		return new(big.Float).SetPrec(prec).Abs(x), nil
+
+	case "exp":
+		return bigmath.Exp(x, prec), nil
+
+	case "min", "max":
+		result := x
+		for _, a := range args[1:] {
+			if c := a.Cmp(result); name == "min" && c < 0 || name == "max" && c > 0 {
+				result = a
+			}
+		}
+		return result, nil
+
+	case "atan2":
+		result := bigmath.Atan2(args[0], args[1], prec)
+		if !useRadians {
+			result = bigRadiansToDegrees(result, prec)
+		}
+		return result, nil
+
+	case "hypot":
+		return bigmath.Hypot(args[0], args[1], prec), nil
+
+	case "rem", "mod":
+		if args[1].Sign() == 0 {
+			return nil, &DivisionError{name, pos}
+		}
+		return bigModulo(name, args[0], args[1], prec), nil
+
+	case "pow":
+		if err := checkBigPow(name, args[0], args[1], pos); err != nil {
+			return nil, err
+		}
+		return bigmath.Pow(args[0], args[1], prec), nil
+
+	case "re", "conj":
+		return x, nil
+
+	case "im":
+		return new(big.Float).SetPrec(prec), nil
+
+	case "!", "!!", "gamma", "lgamma", "beta", "nCr", "nPr":
+		return bigGamma(name, args, prec, pos)
+
+	case "arg":
+		switch {
+		case x.Sign() >= 0:
+			return new(big.Float).SetPrec(prec), nil
+		case useRadians:
+			return bigmath.Pi(prec), nil
+		}
+		return new(big.Float).SetPrec(prec).SetInt64(180), nil
+	}
+
+	return nil, tokenizer.ErrUnknownFunction(name)
+}
+
+// bigGamma evaluates the factorials and their relatives at integer
+// arguments, where they are rational, and reports ErrFloatOnly elsewhere.
+func bigGamma(name string, args []*big.Float, prec uint, pos int) (*big.Float, error) {
+	fargs := make([]float64, len(args))
+	rargs := make([]*big.Rat, len(args))
+	for i, a := range args {
+		fargs[i], _ = a.Float64()
+		rargs[i], _ = a.Rat(nil)
+	}
+	if err := checkDomain(name, fargs, pos); err != nil {
+		return nil, err
+	}
+	if r, ok := ratFunction(name, rargs); ok {
+		return new(big.Float).SetPrec(prec).SetRat(r), nil
+	}
+	return nil, ErrFloatOnly(name)
+}
+
+// checkBigPow rejects a zero base with a negative exponent and a negative
+// base with a fractional one.
+func checkBigPow(name string, a, b *big.Float, pos int) error {
+	if a.Sign() == 0 && b.Sign() < 0 || a.Sign() < 0 && !b.IsInt() {
+		return bigDomainError(name, a, pos)
+	}
+	return nil
+}
+
+func bigDomainError(name string, x *big.Float, pos int) error {
+	f, _ := x.Float64()
+	return &DomainError{name, f, pos}
+}
+
+func bigDegreesToRadians(x *big.Float, prec uint) *big.Float {
+	wp := prec + 32
+	r := new(big.Float).SetPrec(wp).Mul(x, bigmath.Pi(wp))
+	return r.Quo(r, big.NewFloat(180))
+}
+
+func bigRadiansToDegrees(x *big.Float, prec uint) *big.Float {
+	wp := prec + 32
+	r := new(big.Float).SetPrec(wp).Mul(x, big.NewFloat(180))
+	r.Quo(r, bigmath.Pi(wp))
+	return r.SetPrec(prec)
+}
+
+// FormatBig prints x with as many significant decimal digits as its
+// precision can represent. An integer computed without rounding, such as
+// 2^200 or 10^30 at 100 bits, is printed in full.
+func FormatBig(x *big.Float) string {
+	if x.IsInt() && x.Acc() == big.Exact {
+		return x.Text('f', 0)
+	}
+	digits := int(float64(x.Prec()) * math.Log10(2))
+	return x.Text('g', max(digits, 1))
+}
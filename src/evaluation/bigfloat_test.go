@@ -0,0 +1,86 @@
+package evaluation_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+func TestCalculateBig(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		prec    uint
+		want    string
+		radians bool
+	}{
+		{"exact large sum", "1" + strings.Repeat("0", 40) + " + 1", 200, "1" + strings.Repeat("0", 39) + "1", false},
+		{"long decimals", "0.1 + 0.2", 200, "0.3", false},
+		{"pi to 50 digits", "pi", 170, "3.14159265358979323846264338327950288419716939937511", false},
+		{"sqrt 2", "sqrt(2)", 100, "1.41421356237309504880168872421", false},
+		{"degrees", "sin(30) + cos(60)", 128, "1", false},
+		{"radians", "sin(pi / 6)", 128, "0.5", true},
+		{"log with base", "log(2, 1024)", 128, "10", false},
+		{"integer power", "2 ^ 100", 128, "1267650600228229401496703205376", false},
+		{"exact integer at low precision", "2 ^ 100", 100, "1267650600228229401496703205376", false},
+		{"exact power of two", "2 ^ 200", 64, "1606938044258990275541962092341162602522202993782792835301376", false},
+		{"exact power of ten", "10 ^ 30", 100, "1000000000000000000000000000000", false},
+		{"exact sum", "10 ^ 30 + 1", 100, "1000000000000000000000000000001", false},
+		{"rounded power of ten", "10 ^ 30", 64, "1e+30", false},
+		{"rounded integer", "3 ^ 100", 64, "5.15377520732011331e+47", false},
+		{"negative base", "(-3) ^ 3", 64, "-27", false},
+		{"variadic max", "max(1, 2 ^ 0.5, -4)", 64, "1.414213562373095049", false},
+		{"unary minus", "-exp(0)", 64, "-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpn := toRPN(t, tt.input)
+			got, err := evaluation.CalculateBig(rpn, tt.radians, tt.prec)
+			if err != nil {
+				t.Fatalf("CalculateBig(%q) failed: %v", tt.input, err)
+			}
+			if s := evaluation.FormatBig(got); s != tt.want {
+				t.Errorf("CalculateBig(%q) = %s, want %s", tt.input, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateBigErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"1 / 0", evaluation.ErrDivisionByZero},
+		{"sqrt(-4)", evaluation.ErrDomain},
+		{"log(0)", evaluation.ErrDomain},
+		{"log(1, 8)", evaluation.ErrDomain},
+		{"asin(2)", evaluation.ErrDomain},
+		{"(-8) ^ 0.5", evaluation.ErrDomain},
+		{"0 ^ -1", evaluation.ErrDomain},
+	}
+
+	for _, tt := range tests {
+		rpn := toRPN(t, tt.input)
+		if _, err := evaluation.CalculateBig(rpn, false, 64); !errors.Is(err, tt.want) {
+			t.Errorf("CalculateBig(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
+
+func toRPN(t *testing.T, input string) []tokenizer.Token {
+	t.Helper()
+	tokens, err := tokenizer.Tokenize(input)
+	if err != nil {
+		t.Fatalf("Tokenize(%q) failed: %v", input, err)
+	}
+	rpn, err := evaluation.ToRPN(tokens)
+	if err != nil {
+		t.Fatalf("ToRPN(%q) failed: %v", input, err)
+	}
+	return rpn
+}
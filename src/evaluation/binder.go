@@ -0,0 +1,62 @@
+package evaluation
+
+import (
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// applyBinder evaluates a call of a binder such as integrate(body, x, a, b).
+// body and the bound variable arrive as quoted RPN, args as the values of
+// the remaining arguments.
+func applyBinder(name string, quoted [][]tokenizer.Token, args []float64, pos int, useRadians bool, scope *Scope, timeout time.Duration) (float64, error) {
+	startTime := time.Now()
+	body := quoted[0]
+	x := quoted[1][0].Value
+	local := scope.bind(x)
+	f := func(v float64) (float64, error) {
+		local.vars[x] = v
+		return calculate(body, useRadians, local, timeout-time.Since(startTime))
+	}
+
+	switch name {
+	case "integrate":
+		res, err := Integrate(f, args[0], args[1], timeout)
+		if err != nil {
+			return 0, err
+		}
+		scope.recordIntegral(res)
+		return res.Value, nil
+
+	case "solve":
+		from, to := float64(DefaultSolveFrom), float64(DefaultSolveTo)
+		switch len(args) {
+		case 0:
+		case 2:
+			from, to = args[0], args[1]
+		default:
+			return 0, tokenizer.ErrArgumentCount(name, pos)
+		}
+		roots, err := Roots(f, from, to)
+		if err != nil {
+			return 0, err
+		}
+		scope.recordSolution(roots)
+		return roots[0], nil
+
+	case "root":
+		return Root(f, args[0])
+	}
+	return 0, tokenizer.ErrUnknownFunction(name)
+}
+
+// binderName returns the name of the first binder or conditional called in
+// tokens.
+func binderName(tokens []tokenizer.Token) string {
+	for _, t := range tokens {
+		if t.Type == tokenizer.Function && (tokenizer.Binders[t.Value] || tokenizer.Conditionals[t.Value]) {
+			return t.Value
+		}
+	}
+	return "quote"
+}
@@ -1,22 +1,79 @@
 package evaluation
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"math"
+	"math/big"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/a1sarpi/gocalc/src/parser"
 	"github.com/a1sarpi/gocalc/src/stack"
 	"github.com/a1sarpi/gocalc/src/tokenizer"
 )
 
 var (
-	ErrDivisionByZero     = fmt.Errorf("division by zero")
-	ErrInvalidRPNSyntax   = fmt.Errorf("invalid RPN syntax")
-	ErrArithmeticOverflow = fmt.Errorf("arithmetic overflow")
-	ErrTimeout            = fmt.Errorf("calculation timeout")
+	ErrDivisionByZero     = newError("division_by_zero", "division by zero")
+	ErrInvalidRPNSyntax   = newError("invalid_syntax", "invalid RPN syntax")
+	ErrArithmeticOverflow = newError("overflow", "arithmetic overflow")
+	ErrTimeout            = newError("timeout", "calculation timeout")
+	ErrRecursionDepth     = newError("recursion_depth", "maximum recursion depth exceeded")
+	ErrComplexValue       = newError("complex_value", "complex value requires complex mode")
+	ErrIntegerOverflow    = newError("integer_overflow", "integer overflow")
+	ErrNoConvergence      = newError("no_convergence", "calculation did not converge")
+	ErrNoRoot             = newError("no_root", "no real root found")
+	ErrMatrixValue        = newError("matrix_value", "vectors and matrices are not available in this mode")
+	ErrSingularMatrix     = newError("singular_matrix", "matrix is singular")
+	ErrNoData             = newError("no_data", "no numbers given")
+	ErrRedefinition       = func(name string) error {
+		return newError("redefinition", fmt.Sprintf("cannot redefine %s", name))
+	}
+	ErrDuplicateParameter = func(name string) error {
+		return newError("duplicate_parameter", fmt.Sprintf("duplicate parameter %s", name))
+	}
+	ErrNotReal = func(name string) error {
+		return newError("not_real", fmt.Sprintf("%s requires real arguments", name))
+	}
+	ErrIntegerOperator = func(op string) error {
+		return newError("integer_operator", fmt.Sprintf("operator %s requires integer mode", op))
+	}
+	ErrNotInteger = func(name string) error {
+		return newError("not_integer", fmt.Sprintf("%s is not available in integer mode", name))
+	}
+	ErrIntType = func(name string) error {
+		return fmt.Errorf("unknown integer type %s", name)
+	}
+	ErrFloatOnly = func(name string) error {
+		return newError("float_only", fmt.Sprintf("%s is only available in float64 mode", name))
+	}
+	ErrNotCompilable = func(name string) error {
+		return newError("not_compilable", fmt.Sprintf("%s cannot be compiled", name))
+	}
 )
 
+// DivisionError reports a zero divisor of /, //, %, rem or mod at the
+// position of the operator or function.
+type DivisionError struct {
+	Op  string
+	Pos int
+}
+
+func (e *DivisionError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.message(), e.Pos)
+}
+
+func (e *DivisionError) message() string {
+	return e.Op + ": division by zero"
+}
+
+// Is makes errors.Is(err, ErrDivisionByZero) hold for division errors.
+func (e *DivisionError) Is(target error) bool {
+	return target == ErrDivisionByZero
+}
+
 const (
 	DefaultCalculationTime = 5 * time.Second
 	TestCalculationTime    = 100 * time.Millisecond
@@ -34,77 +91,53 @@ func checkOverflow(x float64) error {
 	return nil
 }
 
+// ToRPN converts infix tokens into reverse Polish notation by way of the
+// syntax tree built by parser.Parse. See parser.RPN for the output format.
 func ToRPN(tokens []tokenizer.Token) ([]tokenizer.Token, error) {
-	output := make([]tokenizer.Token, 0, len(tokens))
-	s := stack.New[tokenizer.Token]()
-
-	for _, token := range tokens {
-		switch token.Type {
-		case tokenizer.Number, tokenizer.Constant:
-			output = append(output, token)
-
-		case tokenizer.Function:
-			s.Push(token)
-
-		case tokenizer.LeftBrace:
-			s.Push(token)
-
-		case tokenizer.RightBrace:
-			for !s.IsEmpty() {
-				top := s.Pop()
-				if top.Type == tokenizer.LeftBrace {
-					if !s.IsEmpty() {
-						next := s.Top()
-						if next.Type == tokenizer.Function {
-							next = s.Pop()
-							output = append(output, next)
-						}
-					}
-					break
-				}
-				output = append(output, top)
-			}
-
-		case tokenizer.Operator:
-			for !s.IsEmpty() {
-				next := s.Top()
-				if next.Type != tokenizer.Operator || !hasHigherPrecedence(next.Value, token.Value) {
-					break
-				}
-				next = s.Pop()
-				output = append(output, next)
-			}
-			s.Push(token)
-		}
-	}
-
-	for !s.IsEmpty() {
-		top := s.Pop()
-		if top.Type == tokenizer.LeftBrace {
-			return nil, tokenizer.ErrMismatchedParentheses(top.Pos)
-		}
-		output = append(output, top)
+	n, err := parser.Parse(tokens)
+	if err != nil {
+		return nil, err
 	}
-
-	return output, nil
+	return parser.RPN(n), nil
 }
 
 func Calculate(tokens []tokenizer.Token, useRadians bool) (float64, error) {
 	return CalculateWithTimeout(tokens, useRadians, DefaultCalculationTime)
 }
 
+// CalculateInScope evaluates tokens with variables resolved from scope. An
+// assignment stores its value in scope and returns it.
+func CalculateInScope(tokens []tokenizer.Token, useRadians bool, scope *Scope) (float64, error) {
+	return calculate(tokens, useRadians, scope, scope.Timeout)
+}
+
 func CalculateWithTimeout(tokens []tokenizer.Token, useRadians bool, timeout time.Duration) (float64, error) {
+	return calculate(tokens, useRadians, nil, timeout)
+}
+
+func calculate(tokens []tokenizer.Token, useRadians bool, scope *Scope, timeout time.Duration) (float64, error) {
+	if n := len(tokens); n > 0 && tokens[n-1].Type == tokenizer.Assign {
+		return assign(tokens, useRadians, scope, timeout)
+	}
+
 	startTime := time.Now()
 	s := stack.New[float64]()
+	argc := 0
+	// quoted holds the unevaluated arguments of binders.
+	var quoted [][]tokenizer.Token
 
-	for _, token := range tokens {
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
 		if time.Since(startTime) > timeout {
 			return 0, ErrTimeout
 		}
 
 		switch token.Type {
 		case tokenizer.Number:
-			val, err := strconv.ParseFloat(token.Value, 64)
+			if tokenizer.IsImaginary(token) {
+				return 0, ErrComplexValue
+			}
+			val, err := parseNumber(token.Value)
 			if err != nil {
 				return 0, err
 			}
@@ -119,65 +152,139 @@ func CalculateWithTimeout(tokens []tokenizer.Token, useRadians bool, timeout tim
 				s.Push(math.Pi)
 			case "e":
 				s.Push(math.E)
+			case "i":
+				return 0, ErrComplexValue
 			}
 
+		case tokenizer.Variable:
+			if scope == nil {
+				return 0, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+			}
+			val, ok := scope.Get(token.Value)
+			if !ok {
+				return 0, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+			}
+			s.Push(val)
+
+		case tokenizer.Comma:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 {
+				return 0, ErrInvalidRPNSyntax
+			}
+			argc = n
+
+		case tokenizer.Quote:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 || i+n >= len(tokens) {
+				return 0, ErrInvalidRPNSyntax
+			}
+			quoted = append(quoted, tokens[i+1:i+1+n])
+			i += n
+			// A placeholder keeps the operands of the call in order.
+			s.Push(0)
+
+		case tokenizer.RightBracket:
+			return 0, ErrMatrixValue
+
 		case tokenizer.Function:
-			if s.IsEmpty() {
+			if tokenizer.MatrixFunctions[token.Value] {
+				return 0, ErrMatrixValue
+			}
+			arity := tokenizer.ArityOf(token.Value)
+			if argc == 0 {
+				argc = arity.Min
+			}
+			if !arity.Accepts(argc) {
+				return 0, tokenizer.ErrArgumentCount(token.Value, token.Pos)
+			}
+			if s.Len() < argc {
 				return 0, ErrInvalidRPNSyntax
 			}
 
-			x := s.Pop()
+			args := make([]float64, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = s.Pop()
+			}
+			argc = 0
 
-			var result float64
-			switch token.Value {
-			case "sin":
-				if !useRadians {
-					x = x * math.Pi / 180
+			if tokenizer.Binders[token.Value] {
+				n := len(quoted)
+				if n < 2 || len(quoted[n-1]) != 1 || quoted[n-1][0].Type != tokenizer.Variable {
+					return 0, ErrInvalidRPNSyntax
 				}
-				result = math.Sin(x)
-			case "cos":
-				if !useRadians {
-					x = x * math.Pi / 180
+				result, err := applyBinder(token.Value, quoted[n-2:], args[2:], token.Pos, useRadians, scope, timeout-time.Since(startTime))
+				if err != nil {
+					return 0, err
 				}
-				result = math.Cos(x)
-			case "tan":
-				if !useRadians {
-					x = x * math.Pi / 180
+				quoted = quoted[:n-2]
+				s.Push(result)
+				continue
+			}
+
+			if tokenizer.Conditionals[token.Value] {
+				n := len(quoted)
+				if n < 2 {
+					return 0, ErrInvalidRPNSyntax
 				}
-				result = math.Tan(x)
-			case "asin":
-				result = math.Asin(x)
-				if !useRadians {
-					result = result * 180 / math.Pi
+				branch := quoted[n-1]
+				if args[0] != 0 {
+					branch = quoted[n-2]
 				}
-			case "acos":
-				result = math.Acos(x)
-				if !useRadians {
-					result = result * 180 / math.Pi
+				result, err := calculate(branch, useRadians, scope, timeout-time.Since(startTime))
+				if err != nil {
+					return 0, err
 				}
-			case "atan":
-				result = math.Atan(x)
-				if !useRadians {
-					result = result * 180 / math.Pi
+				quoted = quoted[:n-2]
+				s.Push(result)
+				continue
+			}
+
+			if fn, ok := scope.Function(token.Value); ok {
+				result, err := scope.call(fn, args, token.Pos, useRadians, timeout-time.Since(startTime))
+				if err != nil {
+					return 0, err
 				}
-			case "log":
-				result = math.Log(x)
-			case "log2":
-				result = math.Log2(x)
-			case "log10":
-				result = math.Log10(x)
-			case "sqrt":
-				result = math.Sqrt(x)
-			case "abs":
-				result = math.Abs(x)
-			case "exp":
-				result = math.Exp(x)
+				s.Push(result)
+				continue
+			}
+
+			if err := checkDomain(token.Value, args, token.Pos); err != nil {
+				return 0, err
+			}
+			result, err := applyFunction(token.Value, args, useRadians)
+			if err != nil {
+				return 0, err
 			}
 			if err := checkOverflow(result); err != nil {
 				return 0, err
 			}
 			s.Push(result)
 
+		case tokenizer.UnaryOperator:
+			if s.IsEmpty() {
+				return 0, ErrInvalidRPNSyntax
+			}
+
+			x := s.Pop()
+			switch token.Value {
+			case "-":
+				s.Push(-x)
+			case "!", "!!":
+				if err := checkDomain(token.Value, []float64{x}, token.Pos); err != nil {
+					return 0, err
+				}
+				result, err := applyFunction(token.Value, []float64{x}, useRadians)
+				if err != nil {
+					return 0, err
+				}
+				if err := checkOverflow(result); err != nil {
+					return 0, err
+				}
+				s.Push(result)
+			default:
+				return 0, unsupportedOperator(token.Value)
+			}
+
 		case tokenizer.Operator:
 			if s.Len() < 2 {
 				return 0, ErrInvalidRPNSyntax
@@ -186,21 +293,12 @@ func CalculateWithTimeout(tokens []tokenizer.Token, useRadians bool, timeout tim
 			b := s.Pop()
 			a := s.Pop()
 
-			var result float64
-			switch token.Value {
-			case "+":
-				result = a + b
-			case "-":
-				result = a - b
-			case "*":
-				result = a * b
-			case "/":
-				if b == 0 {
-					return 0, ErrDivisionByZero
-				}
-				result = a / b
-			case "^":
-				result = math.Pow(a, b)
+			if err := checkDomain(token.Value, []float64{a, b}, token.Pos); err != nil {
+				return 0, err
+			}
+			result, err := applyOperator(token.Value, a, b, token.Pos)
+			if err != nil {
+				return 0, err
 			}
 			if err := checkOverflow(result); err != nil {
 				return 0, err
@@ -220,13 +318,212 @@ func CalculateWithTimeout(tokens []tokenizer.Token, useRadians bool, timeout tim
 	return result, nil
 }
 
-func hasHigherPrecedence(op1, op2 string) bool {
-	precedence := map[string]int{
-		"+": 1,
-		"-": 1,
-		"*": 2,
-		"/": 2,
-		"^": 3,
+// parseNumber parses a decimal number or an integer literal with a 0x, 0b
+// or 0o prefix.
+func parseNumber(s string) (float64, error) {
+	x, err := strconv.ParseFloat(s, 64)
+	if errors.Is(err, strconv.ErrSyntax) {
+		if f, ok := new(big.Float).SetString(s); ok {
+			x, _ = f.Float64()
+			return x, nil
+		}
+	}
+	return x, err
+}
+
+// applyFunction evaluates a built-in function on float64 arguments.
+func applyFunction(name string, args []float64, useRadians bool) (float64, error) {
+	fn, ok := floatFunctions[name]
+	if !ok {
+		return 0, tokenizer.ErrUnknownFunction(name)
+	}
+	return fn(args, useRadians), nil
+}
+
+// floatFunction is the float64 implementation of a built-in function. Its
+// arguments have passed checkDomain.
+type floatFunction func(args []float64, useRadians bool) float64
+
+var floatFunctions = map[string]floatFunction{
+	"sin": func(args []float64, useRadians bool) float64 {
+		return math.Sin(toRadians(args[0], useRadians))
+	},
+	"cos": func(args []float64, useRadians bool) float64 {
+		return math.Cos(toRadians(args[0], useRadians))
+	},
+	"tan": func(args []float64, useRadians bool) float64 {
+		return math.Tan(toRadians(args[0], useRadians))
+	},
+	"tg": func(args []float64, useRadians bool) float64 {
+		return math.Tan(toRadians(args[0], useRadians))
+	},
+	"ctg": func(args []float64, useRadians bool) float64 {
+		return 1 / math.Tan(toRadians(args[0], useRadians))
+	},
+	"asin": func(args []float64, useRadians bool) float64 {
+		return fromRadians(math.Asin(args[0]), useRadians)
+	},
+	"acos": func(args []float64, useRadians bool) float64 {
+		return fromRadians(math.Acos(args[0]), useRadians)
+	},
+	"atan": func(args []float64, useRadians bool) float64 {
+		return fromRadians(math.Atan(args[0]), useRadians)
+	},
+	"log": func(args []float64, useRadians bool) float64 {
+		if len(args) == 2 {
+			return math.Log(args[1]) / math.Log(args[0])
+		}
+		return math.Log(args[0])
+	},
+	"log2":  unary(math.Log2),
+	"log10": unary(math.Log10),
+	"sqrt":  unary(math.Sqrt),
+	"abs":   unary(math.Abs),
+	"exp":   unary(math.Exp),
+	"min": func(args []float64, useRadians bool) float64 {
+		result := args[0]
+		for _, a := range args[1:] {
+			result = math.Min(result, a)
+		}
+		return result
+	},
+	"max": func(args []float64, useRadians bool) float64 {
+		result := args[0]
+		for _, a := range args[1:] {
+			result = math.Max(result, a)
+		}
+		return result
+	},
+	"atan2": func(args []float64, useRadians bool) float64 {
+		return fromRadians(math.Atan2(args[0], args[1]), useRadians)
+	},
+	"hypot": func(args []float64, useRadians bool) float64 {
+		return math.Hypot(args[0], args[1])
+	},
+	"pow": func(args []float64, useRadians bool) float64 {
+		return math.Pow(args[0], args[1])
+	},
+	"re":   unary(func(x float64) float64 { return x }),
+	"conj": unary(func(x float64) float64 { return x }),
+	"im":   unary(func(x float64) float64 { return 0 }),
+	"arg": func(args []float64, useRadians bool) float64 {
+		return fromRadians(math.Atan2(0, args[0]), useRadians)
+	},
+	"sum":  statistic(sum),
+	"mean": statistic(mean),
+	"median": statistic(func(xs []float64) float64 {
+		return percentile(sortedFloats(xs), 50)
+	}),
+	"mode": statistic(func(xs []float64) float64 {
+		return mode(xs, cmp.Compare[float64])
+	}),
+	"range": statistic(func(xs []float64) float64 {
+		return slices.Max(xs) - slices.Min(xs)
+	}),
+	"var": statistic(func(xs []float64) float64 {
+		return variance(xs, 1)
+	}),
+	"pvar": statistic(func(xs []float64) float64 {
+		return variance(xs, 0)
+	}),
+	"stddev": statistic(func(xs []float64) float64 {
+		return math.Sqrt(variance(xs, 1))
+	}),
+	"pstddev": statistic(func(xs []float64) float64 {
+		return math.Sqrt(variance(xs, 0))
+	}),
+	"percentile": func(args []float64, useRadians bool) float64 {
+		return percentile(sortedFloats(args[1:]), args[0])
+	},
+	"rem": func(args []float64, useRadians bool) float64 {
+		return math.Mod(args[0], args[1])
+	},
+	"mod": func(args []float64, useRadians bool) float64 {
+		return floorMod(args[0], args[1])
+	},
+	"gamma": unary(math.Gamma),
+	"lgamma": unary(func(x float64) float64 {
+		y, _ := math.Lgamma(x)
+		return y
+	}),
+	"beta": func(args []float64, useRadians bool) float64 {
+		return beta(args[0], args[1])
+	},
+	"nCr": func(args []float64, useRadians bool) float64 {
+		return combinations("nCr", args[0], args[1])
+	},
+	"nPr": func(args []float64, useRadians bool) float64 {
+		return combinations("nPr", args[0], args[1])
+	},
+	// The postfix operators are applied like functions of one argument.
+	"!":  unary(factorial),
+	"!!": unary(doubleFactorial),
+}
+
+// unary adapts a function of one argument that does not depend on the angle
+// unit.
+func unary(f func(float64) float64) floatFunction {
+	return func(args []float64, useRadians bool) float64 {
+		return f(args[0])
+	}
+}
+
+// toRadians converts an angle argument given in degrees unless useRadians.
+func toRadians(x float64, useRadians bool) float64 {
+	if useRadians {
+		return x
+	}
+	return x * math.Pi / 180
+}
+
+// fromRadians converts an angle result to degrees unless useRadians.
+func fromRadians(x float64, useRadians bool) float64 {
+	if useRadians {
+		return x
+	}
+	return x * 180 / math.Pi
+}
+
+// applyOperator evaluates a binary operator on float64 operands. pos is
+// the position of the operator.
+func applyOperator(op string, a, b float64, pos int) (float64, error) {
+	var result float64
+	switch op {
+	case "+":
+		result = a + b
+	case "-":
+		result = a - b
+	case "*":
+		result = a * b
+	case "/":
+		if b == 0 {
+			return 0, &DivisionError{op, pos}
+		}
+		result = a / b
+	case "%", "//":
+		if b == 0 {
+			return 0, &DivisionError{op, pos}
+		}
+		if op == "%" {
+			result = floorMod(a, b)
+		} else {
+			result = floorDiv(a, b)
+		}
+	case "^":
+		result = math.Pow(a, b)
+	default:
+		return 0, unsupportedOperator(op)
+	}
+	return result, nil
+}
+
+// unsupportedOperator reports an operator the current mode cannot apply.
+func unsupportedOperator(op string) error {
+	if tokenizer.IntegerOperators[op] {
+		return ErrIntegerOperator(op)
+	}
+	if tokenizer.MatrixOperators[op] {
+		return ErrMatrixValue
 	}
-	return precedence[op1] >= precedence[op2]
+	return tokenizer.ErrUnknownOperator(op)
 }
@@ -1,8 +1,10 @@
 package evaluation_test
 
 import (
+	"errors"
 	"math"
 	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/a1sarpi/gocalc/src/evaluation"
@@ -201,8 +203,9 @@ func TestAllFunctions(t *testing.T) {
 		{"log2(8)", "log2", 8, 3, true},
 		{"log10(100)", "log10", 100, 2, true},
 		{"ln(1)", "log", 1, 0, true},
-		{"ln(0)", "log", 0, math.Inf(-1), true},
-		{"ln(-1)", "log", -1, math.NaN(), true},
+		{"tg(45°)", "tg", 45, 1, false},
+		{"ctg(45°)", "ctg", 45, 1, false},
+		{"ctg(π/4)", "ctg", math.Pi / 4, 1, true},
 	}
 
 	for _, tt := range tests {
@@ -222,6 +225,83 @@ func TestAllFunctions(t *testing.T) {
 			}
 		})
 	}
+
+	// ln(0) and ln(-1) have no real value.
+	for _, arg := range []float64{0, -1} {
+		rpn := []tokenizer.Token{
+			{tokenizer.Number, floatToString(arg), 0},
+			{tokenizer.Function, "log", 0},
+		}
+		if _, err := evaluation.Calculate(rpn, true); !errors.Is(err, evaluation.ErrDomain) {
+			t.Errorf("ln(%v) error = %v, want %v", arg, err, evaluation.ErrDomain)
+		}
+	}
+}
+
+// TestEveryFunction calls each built-in function in every mode. Modes may
+// reject a function, but only with one of the coded errors.
+func TestEveryFunction(t *testing.T) {
+	calls := map[string]string{
+		"transpose": "transpose([1, 2])",
+		"det":       "det([[1, 2], [3, 4]])",
+		"inv":       "inv([[1, 2], [3, 4]])",
+		"dot":       "dot([1, 2], [3, 4])",
+		"cross":     "cross([1, 2, 3], [4, 5, 6])",
+		"norm":      "norm([3, 4])",
+		"integrate": "integrate(x, x, 0, 1)",
+		"solve":     "solve(x - 1, x)",
+		"root":      "root(x - 1, x, 0)",
+	}
+	modes := map[string]func([]tokenizer.Token) error{
+		"float": func(rpn []tokenizer.Token) error {
+			_, err := evaluation.Calculate(rpn, false)
+			return err
+		},
+		"rational": func(rpn []tokenizer.Token) error {
+			_, err := evaluation.CalculateRational(rpn, false)
+			return err
+		},
+		"big": func(rpn []tokenizer.Token) error {
+			_, err := evaluation.CalculateBig(rpn, false, 64)
+			return err
+		},
+		"complex": func(rpn []tokenizer.Token) error {
+			_, err := evaluation.CalculateComplex(rpn, false)
+			return err
+		},
+		"integer": func(rpn []tokenizer.Token) error {
+			_, err := evaluation.CalculateInteger(rpn, evaluation.Int64)
+			return err
+		},
+		"matrix": func(rpn []tokenizer.Token) error {
+			_, err := evaluation.CalculateMatrix(rpn, false)
+			return err
+		},
+		"compiled": func(rpn []tokenizer.Token) error {
+			p, err := evaluation.CompileRPN(rpn, false)
+			if err != nil {
+				return err
+			}
+			_, err = p.Eval(nil)
+			return err
+		},
+	}
+
+	for name := range tokenizer.Functions {
+		input, ok := calls[name]
+		if !ok {
+			args := strings.TrimSuffix(strings.Repeat("1, ", tokenizer.ArityOf(name).Min), ", ")
+			input = name + "(" + args + ")"
+		}
+		rpn := toRPN(t, input)
+		for mode, calc := range modes {
+			t.Run(name+"/"+mode, func(t *testing.T) {
+				if err := calc(rpn); err != nil && evaluation.ErrorCode(err) == "error" {
+					t.Errorf("%s: %q failed without a code: %v", mode, input, err)
+				}
+			})
+		}
+	}
 }
 
 func TestCalculator(t *testing.T) {
@@ -285,6 +365,71 @@ func TestCalculator(t *testing.T) {
 			input:    "(2 + 3) * 4 ^ 2 - 10 / 2",
 			expected: 75,
 		},
+		{
+			name:     "unary minus before function",
+			input:    "-sin(30) * 4",
+			expected: -2,
+		},
+		{
+			name:     "unary minus before parentheses",
+			input:    "2 * -(1 + 2)",
+			expected: -6,
+		},
+		{
+			name:     "unary minus before power",
+			input:    "-2^2",
+			expected: -4,
+		},
+		{
+			name:     "unary minus before power in exponent",
+			input:    "2 ^ -2^2 * -3e1^0",
+			expected: -0.0625,
+		},
+		{
+			name:     "negative scientific notation",
+			input:    "-1e16 + 2e16",
+			expected: 1e16,
+		},
+		{
+			name:     "variadic min",
+			input:    "min(4, 2 + 1, 7)",
+			expected: 3,
+		},
+		{
+			name:     "variadic max with nested call",
+			input:    "max(1, min(5, 9), -2) * 2",
+			expected: 10,
+		},
+		{
+			name:     "single argument max",
+			input:    "max(3)",
+			expected: 3,
+		},
+		{
+			name:     "atan2 in degrees",
+			input:    "atan2(1, 1)",
+			expected: 45,
+		},
+		{
+			name:     "hypot",
+			input:    "hypot(3, 4)",
+			expected: 5,
+		},
+		{
+			name:     "pow",
+			input:    "pow(2, 10)",
+			expected: 1024,
+		},
+		{
+			name:     "log with base",
+			input:    "log(2, 8)",
+			expected: 3,
+		},
+		{
+			name:     "natural log",
+			input:    "log(e)",
+			expected: 1,
+		},
 	}
 
 	for _, tt := range tests {
@@ -311,6 +456,37 @@ func TestCalculator(t *testing.T) {
 	}
 }
 
+func TestFunctionArity(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+	}{
+		{"too few arguments", "atan2(1)", 0},
+		{"too many arguments", "1 + hypot(1, 2, 3)", 4},
+		{"unary function with two arguments", "sqrt(4, 9)", 0},
+		{"log with three arguments", "2 * log(2, 8, 1)", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := tokenizer.Tokenize(tt.input)
+			if err != nil {
+				t.Fatalf("Tokenize failed: %v", err)
+			}
+
+			_, err = evaluation.ToRPN(tokens)
+			var tokErr *tokenizer.Error
+			if !errors.As(err, &tokErr) {
+				t.Fatalf("ToRPN(%q) error = %v, want *tokenizer.Error", tt.input, err)
+			}
+			if tokErr.Pos != tt.pos {
+				t.Errorf("ToRPN(%q) error position = %d, want %d", tt.input, tokErr.Pos, tt.pos)
+			}
+		})
+	}
+}
+
 func almostEqual(a, b float64) bool {
 	const epsilon = 1e-10
 	return (a-b) < epsilon && (b-a) < epsilon
@@ -0,0 +1,378 @@
+package evaluation
+
+import (
+	"math"
+	"math/cmplx"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/stack"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// maxExactExponent is the largest integer exponent raised by repeated
+// multiplication, which keeps results such as i^2 free of rounding.
+const maxExactExponent = 64
+
+// CalculateComplex evaluates RPN tokens over the complex numbers. The
+// imaginary unit i and literals such as 4i are accepted, and functions
+// like sqrt and log are defined for negative arguments.
+func CalculateComplex(tokens []tokenizer.Token, useRadians bool) (complex128, error) {
+	return CalculateComplexInScope(tokens, useRadians, nil)
+}
+
+// CalculateComplexInScope evaluates tokens as CalculateComplex does, with
+// variables resolved from scope as real numbers. Variables hold real
+// numbers, so assignments are left to Calculate.
+func CalculateComplexInScope(tokens []tokenizer.Token, useRadians bool, scope *Scope) (complex128, error) {
+	if n := len(tokens); n > 0 && tokens[n-1].Type == tokenizer.Assign {
+		return 0, ErrFloatOnly("assignment")
+	}
+	startTime := time.Now()
+	s := stack.New[complex128]()
+	argc := 0
+
+	for _, token := range tokens {
+		if time.Since(startTime) > DefaultCalculationTime {
+			return 0, ErrTimeout
+		}
+
+		switch token.Type {
+		case tokenizer.Number:
+			val, err := parseComplex(token.Value)
+			if err != nil {
+				return 0, tokenizer.ErrInvalidNumber(token.Pos)
+			}
+			s.Push(val)
+
+		case tokenizer.Constant:
+			switch token.Value {
+			case "pi":
+				s.Push(math.Pi)
+			case "e":
+				s.Push(math.E)
+			case "i":
+				s.Push(1i)
+			}
+
+		case tokenizer.Variable:
+			if scope == nil {
+				return 0, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+			}
+			val, ok := scope.Get(token.Value)
+			if !ok {
+				return 0, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+			}
+			s.Push(complex(val, 0))
+
+		case tokenizer.Quote:
+			return 0, ErrFloatOnly(binderName(tokens))
+
+		case tokenizer.Comma:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 {
+				return 0, ErrInvalidRPNSyntax
+			}
+			argc = n
+
+		case tokenizer.RightBracket:
+			return 0, ErrMatrixValue
+
+		case tokenizer.Function:
+			if tokenizer.MatrixFunctions[token.Value] {
+				return 0, ErrMatrixValue
+			}
+			if _, ok := scope.Function(token.Value); ok {
+				return 0, ErrFloatOnly(token.Value)
+			}
+			arity := tokenizer.ArityOf(token.Value)
+			if argc == 0 {
+				argc = arity.Min
+			}
+			if !arity.Accepts(argc) {
+				return 0, tokenizer.ErrArgumentCount(token.Value, token.Pos)
+			}
+			if s.Len() < argc {
+				return 0, ErrInvalidRPNSyntax
+			}
+
+			args := make([]complex128, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = s.Pop()
+			}
+			argc = 0
+
+			result, err := complexFunction(token.Value, args, useRadians, token.Pos)
+			if err != nil {
+				return 0, err
+			}
+			if err := checkComplexOverflow(result); err != nil {
+				return 0, err
+			}
+			s.Push(result)
+
+		case tokenizer.UnaryOperator:
+			if s.IsEmpty() {
+				return 0, ErrInvalidRPNSyntax
+			}
+
+			x := s.Pop()
+			switch token.Value {
+			case "-":
+				// 0 - x rather than -x keeps a zero imaginary part positive, so
+				// sqrt(-(2)) is on the same side of the branch cut as sqrt(-2).
+				s.Push(0 - x)
+			case "!", "!!":
+				result, err := complexFunction(token.Value, []complex128{x}, useRadians, token.Pos)
+				if err != nil {
+					return 0, err
+				}
+				if err := checkComplexOverflow(result); err != nil {
+					return 0, err
+				}
+				s.Push(result)
+			default:
+				return 0, unsupportedOperator(token.Value)
+			}
+
+		case tokenizer.Operator:
+			if s.Len() < 2 {
+				return 0, ErrInvalidRPNSyntax
+			}
+
+			b := s.Pop()
+			a := s.Pop()
+
+			var result complex128
+			switch token.Value {
+			case "+":
+				result = a + b
+			case "-":
+				result = a - b
+			case "*":
+				result = a * b
+			case "/":
+				if b == 0 {
+					return 0, &DivisionError{token.Value, token.Pos}
+				}
+				result = a / b
+			case "%", "//":
+				// Flooring is only defined on the real line.
+				if imag(a) != 0 || imag(b) != 0 {
+					return 0, ErrNotReal(token.Value)
+				}
+				f, err := applyOperator(token.Value, real(a), real(b), token.Pos)
+				if err != nil {
+					return 0, err
+				}
+				result = complex(f, 0)
+			case "^":
+				if a == 0 && real(b) < 0 {
+					return 0, &DomainError{token.Value, 0, token.Pos}
+				}
+				result = complexPow(a, b)
+			default:
+				return 0, unsupportedOperator(token.Value)
+			}
+			if err := checkComplexOverflow(result); err != nil {
+				return 0, err
+			}
+			s.Push(result)
+
+		default:
+			return 0, ErrInvalidRPNSyntax
+		}
+	}
+
+	if s.Len() != 1 {
+		return 0, ErrInvalidRPNSyntax
+	}
+	return s.Pop(), nil
+}
+
+func parseComplex(s string) (complex128, error) {
+	if imag, ok := strings.CutSuffix(s, "i"); ok {
+		y, err := strconv.ParseFloat(imag, 64)
+		return complex(0, y), err
+	}
+	x, err := parseNumber(s)
+	return complex(x, 0), err
+}
+
+func checkComplexOverflow(z complex128) error {
+	if cmplx.IsInf(z) || cmplx.IsNaN(z) {
+		return ErrArithmeticOverflow
+	}
+	return nil
+}
+
+// complexPow computes a**b, multiplying out small integer exponents so
+// that powers of i stay exact.
+func complexPow(a, b complex128) complex128 {
+	n := real(b)
+	if imag(b) != 0 || n != math.Trunc(n) || math.Abs(n) > maxExactExponent {
+		return cmplx.Pow(a, b)
+	}
+
+	result := complex128(1)
+	for k, x := int(math.Abs(n)), a; k > 0; k >>= 1 {
+		if k&1 == 1 {
+			result *= x
+		}
+		x *= x
+	}
+	if n < 0 {
+		result = 1 / result
+	}
+	return result
+}
+
+func complexFunction(name string, args []complex128, useRadians bool, pos int) (complex128, error) {
+	x := args[0]
+	toRadians := func(z complex128) complex128 {
+		if useRadians {
+			return z
+		}
+		return z * math.Pi / 180
+	}
+	fromRadians := func(z complex128) complex128 {
+		if useRadians {
+			return z
+		}
+		return z * 180 / math.Pi
+	}
+
+	switch name {
+	case "log", "log2", "log10":
+		// The logarithm is defined everywhere but at zero, and a base of
+		// one would divide by zero.
+		for _, a := range args {
+			if a == 0 {
+				return 0, &DomainError{name, 0, pos}
+			}
+		}
+		if len(args) == 2 && x == 1 {
+			return 0, &DomainError{name, 1, pos}
+		}
+	case "pow":
+		if args[0] == 0 && real(args[1]) < 0 {
+			return 0, &DomainError{name, 0, pos}
+		}
+	}
+
+	switch name {
+	case "sin":
+		return cmplx.Sin(toRadians(x)), nil
+	case "cos":
+		return cmplx.Cos(toRadians(x)), nil
+	case "tan", "tg":
+		return cmplx.Tan(toRadians(x)), nil
+	case "ctg":
+		return cmplx.Cot(toRadians(x)), nil
+	case "asin":
+		return fromRadians(cmplx.Asin(x)), nil
+	case "acos":
+		return fromRadians(cmplx.Acos(x)), nil
+	case "atan":
+		return fromRadians(cmplx.Atan(x)), nil
+	case "exp":
+		return cmplx.Exp(x), nil
+	case "log":
+		if len(args) == 2 {
+			return cmplx.Log(args[1]) / cmplx.Log(args[0]), nil
+		}
+		return cmplx.Log(x), nil
+	case "log2":
+		return cmplx.Log(x) / math.Ln2, nil
+	case "log10":
+		return cmplx.Log10(x), nil
+	case "sqrt":
+		return cmplx.Sqrt(x), nil
+	case "abs":
+		return complex(cmplx.Abs(x), 0), nil
+	case "pow":
+		return complexPow(args[0], args[1]), nil
+	case "hypot":
+		return cmplx.Sqrt(args[0]*args[0] + args[1]*args[1]), nil
+	case "re":
+		return complex(real(x), 0), nil
+	case "im":
+		return complex(imag(x), 0), nil
+	case "arg":
+		return fromRadians(complex(cmplx.Phase(x), 0)), nil
+	case "conj":
+		return cmplx.Conj(x), nil
+	case "sum", "mean":
+		var sum complex128
+		for _, a := range args {
+			sum += a
+		}
+		if name == "mean" {
+			sum /= complex(float64(len(args)), 0)
+		}
+		return sum, nil
+	}
+
+	// The remaining functions compare their arguments, which is only
+	// meaningful on the real line, or like gamma are only implemented
+	// there.
+	reals := make([]float64, len(args))
+	for i, a := range args {
+		if imag(a) != 0 {
+			return 0, ErrNotReal(name)
+		}
+		reals[i] = real(a)
+	}
+	if !tokenizer.Statistics[name] && !realFunctions[name] {
+		return 0, tokenizer.ErrUnknownFunction(name)
+	}
+	if err := checkDomain(name, reals, pos); err != nil {
+		return 0, err
+	}
+	r, err := applyFunction(name, reals, useRadians)
+	return complex(r, 0), err
+}
+
+// realFunctions are evaluated in complex mode when all their arguments
+// are real.
+var realFunctions = map[string]bool{
+	"min": true, "max": true, "atan2": true, "rem": true, "mod": true,
+	"gamma": true, "lgamma": true, "beta": true, "nCr": true, "nPr": true,
+	"!": true, "!!": true,
+}
+
+// FormatComplex prints z as a+bi. A part is left out only when it is
+// exactly zero, so the rounding error in exp(i*pi) shows as a tiny
+// imaginary part in e-notation.
+func FormatComplex(z complex128) string {
+	re, im := real(z), imag(z)
+	if im == 0 {
+		return formatPart(re)
+	}
+
+	var b strings.Builder
+	if re != 0 {
+		b.WriteString(formatPart(re))
+		if im > 0 {
+			b.WriteByte('+')
+		}
+	}
+	switch im {
+	case 1:
+	case -1:
+		b.WriteByte('-')
+	default:
+		b.WriteString(formatPart(im))
+	}
+	b.WriteByte('i')
+	return b.String()
+}
+
+func formatPart(x float64) string {
+	if x == 0 {
+		// Avoid printing negative zero.
+		x = 0
+	}
+	return strconv.FormatFloat(x, 'g', 15, 64)
+}
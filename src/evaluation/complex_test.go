@@ -0,0 +1,87 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestCalculateComplex(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		radians bool
+	}{
+		{"literal", "3+4i", "3+4i", false},
+		{"unit", "i", "i", false},
+		{"negative unit", "-i", "-i", false},
+		{"square of i", "i^2", "-1", false},
+		{"negative imaginary part", "2 - 3i", "2-3i", false},
+		{"sqrt of negative", "sqrt(-1)", "i", false},
+		{"sqrt of negative literal", "sqrt(-4)", "2i", false},
+		{"sqrt of negated expression", "sqrt(-(2 + 2))", "2i", false},
+		{"log of negative", "log(-1)", "3.14159265358979i", false},
+		{"product", "(1+2i) * (3-i)", "5+5i", false},
+		{"quotient", "(1+i) / (1-i)", "i", false},
+		{"euler", "exp(i * pi)", "-1+1.22464679914735e-16i", true},
+		{"power of imaginary literal", "4i^2", "-4", false},
+		{"negative imaginary literal power", "-4i^2", "4", false},
+		{"tiny imaginary part", "1+1e-20i", "1+1e-20i", false},
+		{"abs", "abs(3+4i)", "5", false},
+		{"re", "re(3-4i)", "3", false},
+		{"im", "im(3-4i)", "-4", false},
+		{"conj", "conj(3-4i)", "3+4i", false},
+		{"arg in degrees", "arg(i)", "90", false},
+		{"arg in radians", "arg(-1)", "3.14159265358979", true},
+		{"real function", "max(1, 2, 3)", "3", false},
+		{"power of i", "i^i", "0.207879576350762", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpn := toRPN(t, tt.input)
+			got, err := evaluation.CalculateComplex(rpn, tt.radians)
+			if err != nil {
+				t.Fatalf("CalculateComplex(%q) failed: %v", tt.input, err)
+			}
+			if s := evaluation.FormatComplex(got); s != tt.want {
+				t.Errorf("CalculateComplex(%q) = %s, want %s", tt.input, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateComplexErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"1 / 0", evaluation.ErrDivisionByZero},
+		{"0 ^ -1", evaluation.ErrDomain},
+		{"log(0)", evaluation.ErrDomain},
+		{"log(1, 2)", evaluation.ErrDomain},
+	}
+
+	for _, tt := range tests {
+		rpn := toRPN(t, tt.input)
+		if _, err := evaluation.CalculateComplex(rpn, false); !errors.Is(err, tt.want) {
+			t.Errorf("CalculateComplex(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+
+	rpn := toRPN(t, "max(1, i)")
+	if _, err := evaluation.CalculateComplex(rpn, false); err == nil {
+		t.Errorf("CalculateComplex(%q): expected error but got none", "max(1, i)")
+	}
+}
+
+func TestComplexValueInRealMode(t *testing.T) {
+	for _, input := range []string{"i", "2 + 3i"} {
+		rpn := toRPN(t, input)
+		if _, err := evaluation.Calculate(rpn, false); !errors.Is(err, evaluation.ErrComplexValue) {
+			t.Errorf("Calculate(%q) error = %v, want %v", input, err, evaluation.ErrComplexValue)
+		}
+	}
+}
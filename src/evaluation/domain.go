@@ -0,0 +1,97 @@
+package evaluation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// ErrDomain matches every *DomainError with errors.Is.
+var ErrDomain = newError("domain", "argument out of domain")
+
+// DomainError reports a function or operator applied to an argument
+// outside its domain, such as sqrt(-4) or log(0).
+type DomainError struct {
+	Func string
+	Arg  float64
+	Pos  int
+}
+
+func (e *DomainError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.message(), e.Pos)
+}
+
+func (e *DomainError) message() string {
+	return fmt.Sprintf("%s: argument %s out of domain", e.Func, strconv.FormatFloat(e.Arg, 'g', -1, 64))
+}
+
+// Is makes errors.Is(err, ErrDomain) hold for domain errors.
+func (e *DomainError) Is(target error) bool {
+	return target == ErrDomain
+}
+
+// checkDomain validates the arguments of a built-in function or operator
+// before it is evaluated in float64, where a domain violation would
+// otherwise surface as a NaN or infinite result. A zero divisor of rem or
+// mod is reported as a DivisionError, as for the / operator.
+func checkDomain(name string, args []float64, pos int) error {
+	x := args[0]
+
+	switch name {
+	case "sqrt":
+		if x < 0 {
+			return &DomainError{name, x, pos}
+		}
+	case "log", "log2", "log10":
+		for _, a := range args {
+			if a <= 0 {
+				return &DomainError{name, a, pos}
+			}
+		}
+		if len(args) == 2 && x == 1 {
+			return &DomainError{name, x, pos}
+		}
+	case "asin", "acos":
+		if math.Abs(x) > 1 {
+			return &DomainError{name, x, pos}
+		}
+	case "!":
+		if isPole(x + 1) {
+			return &DomainError{name, x, pos}
+		}
+	case "gamma", "lgamma":
+		if isPole(x) {
+			return &DomainError{name, x, pos}
+		}
+	case "!!":
+		if x != math.Trunc(x) || x < -1 {
+			return &DomainError{name, x, pos}
+		}
+	case "beta":
+		for _, a := range args {
+			if isPole(a) {
+				return &DomainError{name, a, pos}
+			}
+		}
+	case "nCr", "nPr":
+		for _, a := range args {
+			if a < 0 || a != math.Trunc(a) {
+				return &DomainError{name, a, pos}
+			}
+		}
+	case "rem", "mod":
+		if args[1] == 0 {
+			return &DivisionError{name, pos}
+		}
+	case "percentile":
+		if x < 0 || x > 100 {
+			return &DomainError{name, x, pos}
+		}
+	case "^", "pow":
+		y := args[1]
+		if x == 0 && y < 0 || x < 0 && y != math.Trunc(y) {
+			return &DomainError{name, x, pos}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestDomainErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		fn    string
+		arg   float64
+		pos   int
+		msg   string
+	}{
+		{"sqrt(-4)", "sqrt", -4, 0, "sqrt: argument -4 out of domain at position 0"},
+		{"log(0)", "log", 0, 0, "log: argument 0 out of domain at position 0"},
+		{"1 + log(-1)", "log", -1, 4, "log: argument -1 out of domain at position 4"},
+		{"log(1, 5)", "log", 1, 0, "log: argument 1 out of domain at position 0"},
+		{"log2(-8)", "log2", -8, 0, "log2: argument -8 out of domain at position 0"},
+		{"asin(2)", "asin", 2, 0, "asin: argument 2 out of domain at position 0"},
+		{"acos(-1.5)", "acos", -1.5, 0, "acos: argument -1.5 out of domain at position 0"},
+		{"0^-1", "^", 0, 1, "^: argument 0 out of domain at position 1"},
+		{"(-8) ^ 0.5", "^", -8, 5, "^: argument -8 out of domain at position 5"},
+		{"pow(-2, 1.5)", "pow", -2, 0, "pow: argument -2 out of domain at position 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := evaluation.Calculate(toRPN(t, tt.input), false)
+			if !errors.Is(err, evaluation.ErrDomain) {
+				t.Fatalf("Calculate(%q) error = %v, want %v", tt.input, err, evaluation.ErrDomain)
+			}
+
+			var domainErr *evaluation.DomainError
+			if !errors.As(err, &domainErr) {
+				t.Fatalf("Calculate(%q) error %T is not a *DomainError", tt.input, err)
+			}
+			if domainErr.Func != tt.fn || domainErr.Arg != tt.arg || domainErr.Pos != tt.pos {
+				t.Errorf("Calculate(%q) = %+v, want {%s %v %d}", tt.input, *domainErr, tt.fn, tt.arg, tt.pos)
+			}
+			if err.Error() != tt.msg {
+				t.Errorf("Calculate(%q) error = %q, want %q", tt.input, err.Error(), tt.msg)
+			}
+		})
+	}
+}
+
+func TestDomainBoundaries(t *testing.T) {
+	for _, input := range []string{"sqrt(0)", "asin(1)", "acos(-1)", "log(1)", "0 ^ 0", "(-8) ^ 3"} {
+		if _, err := evaluation.Calculate(toRPN(t, input), false); err != nil {
+			t.Errorf("Calculate(%q) failed: %v", input, err)
+		}
+	}
+}
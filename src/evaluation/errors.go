@@ -0,0 +1,130 @@
+package evaluation
+
+import (
+	"errors"
+
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// Error is an evaluation error. Code identifies the kind of error and does
+// not change between releases, so programs can rely on it where the
+// message is meant for people.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func newError(code, msg string) *Error {
+	return &Error{Code: code, Message: msg}
+}
+
+// ErrorCode returns the stable code of err, such as "division_by_zero" or
+// "unknown_symbol", or "error" when err carries none.
+func ErrorCode(err error) string {
+	var tokErr *tokenizer.Error
+	if errors.As(err, &tokErr) {
+		return tokErr.Code
+	}
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return ErrDomain.Code
+	}
+	var shapeErr *ShapeError
+	if errors.As(err, &shapeErr) {
+		return ErrShape.Code
+	}
+	var divErr *DivisionError
+	if errors.As(err, &divErr) {
+		return ErrDivisionByZero.Code
+	}
+	var evalErr *Error
+	if errors.As(err, &evalErr) {
+		return evalErr.Code
+	}
+	return "error"
+}
+
+// ErrorPos returns the position in the input that err refers to.
+func ErrorPos(err error) (int, bool) {
+	var tokErr *tokenizer.Error
+	if errors.As(err, &tokErr) {
+		return tokErr.Pos, true
+	}
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr.Pos, true
+	}
+	var shapeErr *ShapeError
+	if errors.As(err, &shapeErr) {
+		return shapeErr.Pos, true
+	}
+	var divErr *DivisionError
+	if errors.As(err, &divErr) {
+		return divErr.Pos, true
+	}
+	return 0, false
+}
+
+// ErrorMessage returns the message of the innermost error that has one,
+// without the stage prefixes added by the command or the position, for
+// output that shows the position on its own.
+func ErrorMessage(err error) string {
+	var tokErr *tokenizer.Error
+	if errors.As(err, &tokErr) {
+		return tokErr.Message
+	}
+	var domainErr *DomainError
+	if errors.As(err, &domainErr) {
+		return domainErr.message()
+	}
+	var shapeErr *ShapeError
+	if errors.As(err, &shapeErr) {
+		return shapeErr.message()
+	}
+	var divErr *DivisionError
+	if errors.As(err, &divErr) {
+		return divErr.message()
+	}
+	var evalErr *Error
+	if errors.As(err, &evalErr) {
+		return evalErr.Message
+	}
+	return err.Error()
+}
+
+// ShiftPos returns a copy of err with the position it refers to moved by
+// offset, for an error in a part of the input that starts at offset. Errors
+// without a position are returned as they are; err itself is not changed.
+// Shift an error before wrapping it, as its message quotes the position.
+func ShiftPos(err error, offset int) error {
+	switch e := err.(type) {
+	case *tokenizer.Error:
+		shifted := *e
+		shifted.Pos += offset
+		return &shifted
+	case *DomainError:
+		shifted := *e
+		shifted.Pos += offset
+		return &shifted
+	case *ShapeError:
+		shifted := *e
+		shifted.Pos += offset
+		return &shifted
+	case *DivisionError:
+		shifted := *e
+		shifted.Pos += offset
+		return &shifted
+	}
+	return err
+}
+
+// IsSyntaxError reports whether err is a mistake in the input, as opposed
+// to a well-formed expression that cannot be evaluated.
+func IsSyntaxError(err error) bool {
+	var tokErr *tokenizer.Error
+	return errors.As(err, &tokErr) || errors.Is(err, ErrInvalidRPNSyntax)
+}
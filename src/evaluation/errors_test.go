@@ -0,0 +1,152 @@
+package evaluation_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		input   string
+		code    string
+		pos     int
+		syntax  bool
+		message string
+	}{
+		{"1/0", "division_by_zero", 1, false, "/: division by zero"},
+		{"sqrt(-4)", "domain", 0, false, "sqrt: argument -4 out of domain"},
+		{"2 + foo", "unknown_symbol", 4, true, "Unknown symbol"},
+		{"(2+3", "mismatched_parentheses", 3, true, "Mismatched parentheses"},
+		{"2+*3", "invalid_syntax", 2, true, "Invalid RPN syntax"},
+		{"pow(2)", "argument_count", 0, true, "Wrong number of arguments for pow"},
+		{"1e308*10", "overflow", -1, false, "arithmetic overflow"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			err := calculate(tt.input)
+			if err == nil {
+				t.Fatalf("%q: expected an error", tt.input)
+			}
+			// The command wraps errors with the stage that failed.
+			err = fmt.Errorf("wrapped: %w", err)
+
+			if code := evaluation.ErrorCode(err); code != tt.code {
+				t.Errorf("ErrorCode(%v) = %q, want %q", err, code, tt.code)
+			}
+			pos, ok := evaluation.ErrorPos(err)
+			if tt.pos < 0 && ok || tt.pos >= 0 && pos != tt.pos {
+				t.Errorf("ErrorPos(%v) = %d, %v, want %d", err, pos, ok, tt.pos)
+			}
+			if syntax := evaluation.IsSyntaxError(err); syntax != tt.syntax {
+				t.Errorf("IsSyntaxError(%v) = %v, want %v", err, syntax, tt.syntax)
+			}
+			if msg := evaluation.ErrorMessage(err); msg != tt.message {
+				t.Errorf("ErrorMessage(%v) = %q, want %q", err, msg, tt.message)
+			}
+		})
+	}
+}
+
+func TestErrorCodeUnknown(t *testing.T) {
+	if code := evaluation.ErrorCode(fmt.Errorf("boom")); code != "error" {
+		t.Errorf("ErrorCode = %q, want %q", code, "error")
+	}
+}
+
+func TestDivisionErrorPos(t *testing.T) {
+	rpn := func(input string) []tokenizer.Token { return toRPN(t, input) }
+	tests := []struct {
+		name string
+		eval func(string) error
+	}{
+		{"float", func(s string) error { _, err := evaluation.Calculate(rpn(s), false); return err }},
+		{"big", func(s string) error { _, err := evaluation.CalculateBig(rpn(s), false, 100); return err }},
+		{"rational", func(s string) error { _, err := evaluation.CalculateRational(rpn(s), false); return err }},
+		{"complex", func(s string) error { _, err := evaluation.CalculateComplex(rpn(s), false); return err }},
+		{"integer", func(s string) error { _, err := evaluation.CalculateInteger(rpn(s), evaluation.Int64); return err }},
+		{"matrix", func(s string) error { _, err := evaluation.CalculateMatrix(rpn(s), false); return err }},
+		{"program", func(s string) error {
+			p, err := evaluation.CompileRPN(rpn(s), false)
+			if err != nil {
+				return err
+			}
+			_, err = p.Eval(nil)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, input := range []string{"1 + 2 / 0", "1 + 2 % 0"} {
+				err := tt.eval(input)
+				if !errors.Is(err, evaluation.ErrDivisionByZero) {
+					t.Fatalf("%s: err = %v, want %v", input, err, evaluation.ErrDivisionByZero)
+				}
+				if pos, ok := evaluation.ErrorPos(err); !ok || pos != 6 {
+					t.Errorf("%s: ErrorPos(%v) = %d, %v, want 6", input, err, pos, ok)
+				}
+			}
+			err := tt.eval("1 + rem(2, 0)")
+			if !errors.Is(err, evaluation.ErrDivisionByZero) {
+				t.Fatalf("rem: err = %v, want %v", err, evaluation.ErrDivisionByZero)
+			}
+			if pos, ok := evaluation.ErrorPos(err); !ok || pos != 4 {
+				t.Errorf("rem: ErrorPos(%v) = %d, %v, want 4", err, pos, ok)
+			}
+		})
+	}
+}
+
+func TestShiftPos(t *testing.T) {
+	_, shapeErr := evaluation.CalculateMatrix(toRPN(t, "[1, 2] + [1, 2, 3]"), false)
+	tests := []struct {
+		err  error
+		pos  int
+		want string
+	}{
+		{calculate("sqrt(-4)"), 0, "sqrt: argument -4 out of domain at position 10"},
+		{calculate("2 + foo"), 4, "Error while tokenizing (position: 14): Unknown symbol"},
+		{calculate("1 / 0"), 2, "/: division by zero at position 12"},
+		{shapeErr, 7, "+: cannot apply to vector of 2 and vector of 3 at position 17"},
+	}
+
+	for _, tt := range tests {
+		msg := tt.err.Error()
+		err := evaluation.ShiftPos(tt.err, 10)
+		if pos, ok := evaluation.ErrorPos(err); !ok || pos != tt.pos+10 {
+			t.Errorf("ErrorPos(ShiftPos(%v, 10)) = %d, %v, want %d", tt.err, pos, ok, tt.pos+10)
+		}
+		if err.Error() != tt.want {
+			t.Errorf("ShiftPos(%v, 10) = %q, want %q", tt.err, err, tt.want)
+		}
+		if evaluation.ErrorCode(err) != evaluation.ErrorCode(tt.err) {
+			t.Errorf("ErrorCode(ShiftPos(%v, 10)) = %q, want %q", tt.err, evaluation.ErrorCode(err), evaluation.ErrorCode(tt.err))
+		}
+		// The error shifted is left as it was.
+		if pos, _ := evaluation.ErrorPos(tt.err); pos != tt.pos || tt.err.Error() != msg {
+			t.Errorf("ShiftPos changed %v to position %d", tt.err, pos)
+		}
+	}
+
+	if err := evaluation.ShiftPos(evaluation.ErrTimeout, 10); err != evaluation.ErrTimeout {
+		t.Errorf("ShiftPos(%v) = %v, want it unchanged", evaluation.ErrTimeout, err)
+	}
+}
+
+func calculate(input string) error {
+	tokens, err := tokenizer.Tokenize(input)
+	if err != nil {
+		return err
+	}
+	rpn, err := evaluation.ToRPN(tokens)
+	if err != nil {
+		return err
+	}
+	_, err = evaluation.Calculate(rpn, false)
+	return err
+}
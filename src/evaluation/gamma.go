@@ -0,0 +1,119 @@
+package evaluation
+
+import (
+	"math"
+	"math/big"
+)
+
+// maxExactFactorial bounds the arguments of the factorials and
+// combinations computed exactly in big integers.
+const maxExactFactorial = 10000
+
+// factorial computes x!, multiplying out integers and using gamma for the
+// rest, so 0.5! is sqrt(pi)/2. Negative integers have passed checkDomain.
+func factorial(x float64) float64 {
+	if x != math.Trunc(x) || x > 170 {
+		return math.Gamma(x + 1)
+	}
+	result := 1.0
+	for i := 2.0; i <= x; i++ {
+		result *= i
+	}
+	return result
+}
+
+// doubleFactorial computes x!!, the product of the integers from x down to
+// 1 or 2 in steps of 2. x is an integer of at least -1, and (-1)!! and 0!!
+// are 1.
+func doubleFactorial(x float64) float64 {
+	result := 1.0
+	for i := x; i > 1 && !math.IsInf(result, 0); i -= 2 {
+		result *= i
+	}
+	return result
+}
+
+// beta computes gamma(a) * gamma(b) / gamma(a + b), by way of lgamma when
+// the gammas overflow.
+func beta(a, b float64) float64 {
+	if isPole(a + b) {
+		return 0
+	}
+	if math.Max(math.Abs(a), math.Max(math.Abs(b), math.Abs(a+b))) < 170 {
+		return math.Gamma(a) * math.Gamma(b) / math.Gamma(a+b)
+	}
+	la, sa := math.Lgamma(a)
+	lb, sb := math.Lgamma(b)
+	lab, sab := math.Lgamma(a + b)
+	return float64(sa*sb*sab) * math.Exp(la+lb-lab)
+}
+
+// combinations computes nCr, the number of ways to choose r of n items,
+// and nPr, the number of their orderings. n and r are integers of at
+// least 0.
+func combinations(name string, n, r float64) float64 {
+	if r > n {
+		return 0
+	}
+	if name == "nPr" {
+		result := 1.0
+		for i := n - r + 1; i <= n && !math.IsInf(result, 0); i++ {
+			result *= i
+		}
+		return result
+	}
+	// Every partial product is itself a binomial coefficient, so the
+	// result stays an integer and is exact up to 2^53.
+	k := math.Min(r, n-r)
+	result := 1.0
+	for i := 1.0; i <= k && !math.IsInf(result, 0); i++ {
+		result = result * (n - k + i) / i
+	}
+	return result
+}
+
+// isPole reports whether x is a pole of gamma: zero or a negative integer.
+func isPole(x float64) bool {
+	return x <= 0 && x == math.Trunc(x)
+}
+
+// exactFactorial computes n! for op "!" and n!! for "!!" in big integers,
+// reporting false when n is negative, other than -1 for !!, or larger
+// than maxExactFactorial.
+func exactFactorial(op string, n *big.Int) (*big.Int, bool) {
+	if !n.IsInt64() || n.Int64() > maxExactFactorial || n.Sign() < 0 && (op != "!!" || n.Int64() != -1) {
+		return nil, false
+	}
+	step := int64(1)
+	if op == "!!" {
+		step = 2
+	}
+	result := big.NewInt(1)
+	for i := n.Int64(); i > 1; i -= step {
+		result.Mul(result, big.NewInt(i))
+	}
+	return result, true
+}
+
+// exactCombinations computes nCr and nPr in big integers, reporting false
+// when n or r is negative or the product would run over more than
+// maxExactFactorial factors.
+func exactCombinations(name string, n, r *big.Int) (*big.Int, bool) {
+	if n.Sign() < 0 || r.Sign() < 0 || !n.IsInt64() || !r.IsInt64() {
+		return nil, false
+	}
+	if r.Cmp(n) > 0 {
+		return new(big.Int), true
+	}
+	k := r.Int64()
+	if name == "nPr" {
+		if k > maxExactFactorial {
+			return nil, false
+		}
+		return new(big.Int).MulRange(n.Int64()-k+1, n.Int64()), true
+	}
+	if min(k, n.Int64()-k) > maxExactFactorial {
+		return nil, false
+	}
+	return new(big.Int).Binomial(n.Int64(), k), true
+}
@@ -0,0 +1,137 @@
+package evaluation_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestFactorials(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+		exact string
+	}{
+		{"0!", 1, "1"},
+		{"5!", 120, "120"},
+		{"-3!", -6, "-6"},
+		{"2^3!", 64, "64"},
+		{"3!^2", 36, "36"},
+		{"(3!)!", 720, "720"},
+		{"6!!", 48, "48"},
+		{"7!!", 105, "105"},
+		{"8!!", 384, "384"},
+		{"(-1)!!", 1, "1"},
+		{"0.5!", math.Sqrt(math.Pi) / 2, ""},
+		{"(-0.5)!", math.Sqrt(math.Pi), ""},
+		{"gamma(5)", 24, "24"},
+		{"gamma(0.5)", math.Sqrt(math.Pi), ""},
+		{"gamma(-1.5)", 4 * math.Sqrt(math.Pi) / 3, ""},
+		{"lgamma(10)", math.Log(362880), ""},
+		{"beta(2, 3)", 1.0 / 12, "1/12"},
+		{"beta(0.5, 0.5)", math.Pi, ""},
+		{"nCr(5, 2)", 10, "10"},
+		{"nCr(52, 5)", 2598960, "2598960"},
+		{"nCr(3, 5)", 0, "0"},
+		{"nPr(5, 2)", 20, "20"},
+		{"nPr(5, 0)", 1, "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rpn := toRPN(t, tt.input)
+			got, err := evaluation.Calculate(rpn, false)
+			if err != nil {
+				t.Fatalf("Calculate(%q) failed: %v", tt.input, err)
+			}
+			if !almostEqual(got, tt.want) {
+				t.Errorf("Calculate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+
+			r, err := evaluation.CalculateRational(rpn, false)
+			if err != nil {
+				t.Fatalf("CalculateRational(%q) failed: %v", tt.input, err)
+			}
+			if r.Exact() != (tt.exact != "") {
+				t.Errorf("CalculateRational(%q) exact = %v, want %v", tt.input, r.Exact(), tt.exact != "")
+			}
+			if tt.exact != "" && r.Value.RatString() != tt.exact {
+				t.Errorf("CalculateRational(%q) = %s, want %s", tt.input, r.Value.RatString(), tt.exact)
+			}
+		})
+	}
+}
+
+func TestFactorialErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"(-1)!", evaluation.ErrDomain},
+		{"(-3)!!", evaluation.ErrDomain},
+		{"2.5!!", evaluation.ErrDomain},
+		{"gamma(0)", evaluation.ErrDomain},
+		{"lgamma(-2)", evaluation.ErrDomain},
+		{"beta(-1, 2)", evaluation.ErrDomain},
+		{"nCr(5, -1)", evaluation.ErrDomain},
+		{"nPr(2.5, 1)", evaluation.ErrDomain},
+		{"171!", evaluation.ErrArithmeticOverflow},
+	}
+
+	for _, tt := range tests {
+		rpn := toRPN(t, tt.input)
+		if _, err := evaluation.Calculate(rpn, false); !errors.Is(err, tt.want) {
+			t.Errorf("Calculate(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+
+	// Exact arithmetic has no trouble with large factorials.
+	r, err := evaluation.CalculateRational(toRPN(t, "171! / 170!"), false)
+	if err != nil || r.Value.RatString() != "171" {
+		t.Errorf("CalculateRational(171! / 170!) = %v, %v, want 171", r, err)
+	}
+}
+
+func TestFactorialsOtherModes(t *testing.T) {
+	big, err := evaluation.CalculateBig(toRPN(t, "25! + nCr(60, 30)"), false, 128)
+	if err != nil || big.Text('f', 0) != "15511210161595567548861424" {
+		t.Errorf("CalculateBig(25! + nCr(60, 30)) = %v, %v, want 15511210161595567548861424", big, err)
+	}
+	if _, err := evaluation.CalculateBig(toRPN(t, "0.5!"), false, 128); evaluation.ErrorCode(err) != "float_only" {
+		t.Errorf("CalculateBig(0.5!) error = %v, want float_only", err)
+	}
+
+	z, err := evaluation.CalculateComplex(toRPN(t, "4! + i"), false)
+	if err != nil || z != 24+1i {
+		t.Errorf("CalculateComplex(4! + i) = %v, %v, want (24+1i)", z, err)
+	}
+	if _, err := evaluation.CalculateComplex(toRPN(t, "gamma(i)"), false); evaluation.ErrorCode(err) != "not_real" {
+		t.Errorf("CalculateComplex(gamma(i)) error = %v, want not_real", err)
+	}
+
+	n, err := evaluation.CalculateInteger(toRPN(t, "20! / nPr(20, 18) + nCr(10, 3)"), evaluation.Int64)
+	if err != nil || n.Int64() != 122 {
+		t.Errorf("CalculateInteger = %v, %v, want 122", n, err)
+	}
+	if _, err := evaluation.CalculateInteger(toRPN(t, "21!"), evaluation.Int64); !errors.Is(err, evaluation.ErrIntegerOverflow) {
+		t.Errorf("CalculateInteger(21!) error = %v, want %v", err, evaluation.ErrIntegerOverflow)
+	}
+
+	v, err := evaluation.CalculateMatrix(toRPN(t, "[1, 2, 3, 4]!"), false)
+	if err != nil || evaluation.FormatValue(v) != "[1, 2, 6, 24]" {
+		t.Errorf("CalculateMatrix([1, 2, 3, 4]!) = %v, %v, want [1, 2, 6, 24]", v, err)
+	}
+
+	p, err := evaluation.Compile("x! / nCr(x, 2)")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if got, err := p.Eval(map[string]float64{"x": 4}); err != nil || got != 4 {
+		t.Errorf("Eval(x! / nCr(x, 2)) = %v, %v, want 4", got, err)
+	}
+	if _, err := p.Eval(map[string]float64{"x": -2}); !errors.Is(err, evaluation.ErrDomain) {
+		t.Errorf("Eval(x = -2) error = %v, want %v", err, evaluation.ErrDomain)
+	}
+}
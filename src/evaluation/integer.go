@@ -0,0 +1,380 @@
+package evaluation
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/stack"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// IntType is the integer type of programmer mode.
+type IntType struct {
+	// Bits is the word size: 8, 16, 32 or 64.
+	Bits     int
+	Unsigned bool
+	// Wrap makes results that do not fit wrap around modulo 2^Bits instead
+	// of failing with ErrIntegerOverflow.
+	Wrap bool
+}
+
+// Int64 is the default type of programmer mode.
+var Int64 = IntType{Bits: 64}
+
+// ParseIntType parses a type name such as int32 or uint8.
+func ParseIntType(name string) (IntType, error) {
+	var t IntType
+	bits, ok := strings.CutPrefix(name, "int")
+	if !ok {
+		bits, ok = strings.CutPrefix(name, "uint")
+		t.Unsigned = true
+	}
+	if !ok {
+		return t, ErrIntType(name)
+	}
+
+	switch bits {
+	case "8", "16", "32", "64":
+		t.Bits, _ = strconv.Atoi(bits)
+	default:
+		return t, ErrIntType(name)
+	}
+	return t, nil
+}
+
+func (t IntType) String() string {
+	if t.Unsigned {
+		return "uint" + strconv.Itoa(t.Bits)
+	}
+	return "int" + strconv.Itoa(t.Bits)
+}
+
+func (t IntType) min() *big.Int {
+	if t.Unsigned {
+		return new(big.Int)
+	}
+	return new(big.Int).Neg(new(big.Int).Lsh(big.NewInt(1), uint(t.Bits-1)))
+}
+
+func (t IntType) max() *big.Int {
+	bits := t.Bits
+	if !t.Unsigned {
+		bits--
+	}
+	m := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	return m.Sub(m, big.NewInt(1))
+}
+
+// modulus returns 2^Bits.
+func (t IntType) modulus() *big.Int {
+	return new(big.Int).Lsh(big.NewInt(1), uint(t.Bits))
+}
+
+// fit returns x if it is in range and otherwise wraps it or reports
+// overflow.
+func (t IntType) fit(x *big.Int) (*big.Int, error) {
+	if x.Cmp(t.min()) >= 0 && x.Cmp(t.max()) <= 0 {
+		return x, nil
+	}
+	if !t.Wrap {
+		return nil, ErrIntegerOverflow
+	}
+	return t.wrap(x), nil
+}
+
+// wrap reduces x modulo 2^Bits into the range of t.
+func (t IntType) wrap(x *big.Int) *big.Int {
+	m := t.modulus()
+	r := new(big.Int).Mod(x, m)
+	if !t.Unsigned && r.Cmp(t.max()) > 0 {
+		r.Sub(r, m)
+	}
+	return r
+}
+
+// CalculateInteger evaluates RPN tokens with integers of type t. Besides
+// the arithmetic operators it supports the bitwise operators &, |, xor, ~,
+// << and >>, and % as the remainder of floored division. Division
+// truncates towards zero.
+func CalculateInteger(tokens []tokenizer.Token, t IntType) (*big.Int, error) {
+	startTime := time.Now()
+	s := stack.New[*big.Int]()
+	argc := 0
+
+	for _, token := range tokens {
+		if time.Since(startTime) > DefaultCalculationTime {
+			return nil, ErrTimeout
+		}
+
+		switch token.Type {
+		case tokenizer.Number:
+			r, ok := new(big.Rat).SetString(token.Value)
+			if !ok || !r.IsInt() || tokenizer.IsImaginary(token) {
+				return nil, tokenizer.ErrInvalidNumber(token.Pos)
+			}
+			val, err := t.fit(new(big.Int).Set(r.Num()))
+			if err != nil {
+				return nil, err
+			}
+			s.Push(val)
+
+		case tokenizer.Constant:
+			return nil, ErrNotInteger(token.Value)
+
+		case tokenizer.Variable:
+			return nil, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+
+		case tokenizer.Quote:
+			return nil, ErrFloatOnly(binderName(tokens))
+
+		case tokenizer.Comma:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 {
+				return nil, ErrInvalidRPNSyntax
+			}
+			argc = n
+
+		case tokenizer.RightBracket:
+			return nil, ErrMatrixValue
+
+		case tokenizer.Function:
+			if tokenizer.MatrixFunctions[token.Value] {
+				return nil, ErrMatrixValue
+			}
+			arity := tokenizer.ArityOf(token.Value)
+			if argc == 0 {
+				argc = arity.Min
+			}
+			if !arity.Accepts(argc) {
+				return nil, tokenizer.ErrArgumentCount(token.Value, token.Pos)
+			}
+			if s.Len() < argc {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			args := make([]*big.Int, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = s.Pop()
+			}
+			argc = 0
+
+			result, err := intFunction(token.Value, args, t, token.Pos)
+			if err != nil {
+				return nil, err
+			}
+			s.Push(result)
+
+		case tokenizer.UnaryOperator:
+			if s.IsEmpty() {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			x := s.Pop()
+			switch token.Value {
+			case "-":
+				result, err := t.fit(new(big.Int).Neg(x))
+				if err != nil {
+					return nil, err
+				}
+				s.Push(result)
+			case "~":
+				// Inverting the bits of a value always fits the word.
+				s.Push(t.wrap(new(big.Int).Not(x)))
+			case "!", "!!":
+				result, err := intFunction(token.Value, []*big.Int{x}, t, token.Pos)
+				if err != nil {
+					return nil, err
+				}
+				s.Push(result)
+			default:
+				return nil, tokenizer.ErrUnknownOperator(token.Value)
+			}
+
+		case tokenizer.Operator:
+			if s.Len() < 2 {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			b := s.Pop()
+			a := s.Pop()
+
+			result, err := intOperator(token.Value, a, b, t, token.Pos)
+			if err != nil {
+				return nil, err
+			}
+			s.Push(result)
+
+		default:
+			return nil, ErrInvalidRPNSyntax
+		}
+	}
+
+	if s.Len() != 1 {
+		return nil, ErrInvalidRPNSyntax
+	}
+	return s.Pop(), nil
+}
+
+func intOperator(op string, a, b *big.Int, t IntType, pos int) (*big.Int, error) {
+	result := new(big.Int)
+	switch op {
+	case "+":
+		result.Add(a, b)
+	case "-":
+		result.Sub(a, b)
+	case "*":
+		result.Mul(a, b)
+	case "/":
+		if b.Sign() == 0 {
+			return nil, &DivisionError{op, pos}
+		}
+		result.Quo(a, b)
+	case "//":
+		if b.Sign() == 0 {
+			return nil, &DivisionError{op, pos}
+		}
+		result = intFloorDiv(a, b)
+	case "%":
+		if b.Sign() == 0 {
+			return nil, &DivisionError{op, pos}
+		}
+		result.Rem(a, b)
+		if result.Sign() != 0 && result.Sign() != b.Sign() {
+			result.Add(result, b)
+		}
+	case "^":
+		return intPow(op, a, b, t, pos)
+	case "&":
+		result.And(a, b)
+	case "|":
+		result.Or(a, b)
+	case "xor":
+		result.Xor(a, b)
+	case "<<", ">>":
+		if b.Sign() < 0 {
+			return nil, &DomainError{op, float64(b.Int64()), pos}
+		}
+		// Shifting by the word size or more leaves no bits of a in the
+		// word, so larger counts need not be applied.
+		n := uint(t.Bits + 1)
+		if b.IsUint64() && b.Uint64() < uint64(n) {
+			n = uint(b.Uint64())
+		}
+		if op == "<<" {
+			result.Lsh(a, n)
+		} else {
+			result.Rsh(a, n)
+		}
+	default:
+		return nil, tokenizer.ErrUnknownOperator(op)
+	}
+	return t.fit(result)
+}
+
+// intPow computes a**b for b >= 0, reducing modulo 2^Bits as it goes when
+// the result cannot fit anyway.
+func intPow(name string, a, b *big.Int, t IntType, pos int) (*big.Int, error) {
+	if b.Sign() < 0 {
+		return nil, &DomainError{name, float64(b.Int64()), pos}
+	}
+	if a.CmpAbs(big.NewInt(1)) <= 0 || b.Cmp(big.NewInt(int64(t.Bits))) <= 0 {
+		return t.fit(new(big.Int).Exp(a, b, nil))
+	}
+	// |a| >= 2 and b > Bits, so |a|^b is at least 2^(Bits+1).
+	if !t.Wrap {
+		return nil, ErrIntegerOverflow
+	}
+	m := t.modulus()
+	return t.wrap(new(big.Int).Exp(new(big.Int).Mod(a, m), b, m)), nil
+}
+
+func intFunction(name string, args []*big.Int, t IntType, pos int) (*big.Int, error) {
+	x := args[0]
+
+	switch name {
+	case "abs":
+		return t.fit(new(big.Int).Abs(x))
+	case "min", "max":
+		result := x
+		for _, a := range args[1:] {
+			if c := a.Cmp(result); name == "min" && c < 0 || name == "max" && c > 0 {
+				result = a
+			}
+		}
+		return result, nil
+	case "pow":
+		return intPow(name, args[0], args[1], t, pos)
+	case "rem":
+		if args[1].Sign() == 0 {
+			return nil, &DivisionError{name, pos}
+		}
+		return t.fit(new(big.Int).Rem(args[0], args[1]))
+	case "mod":
+		return intOperator("%", args[0], args[1], t, pos)
+	case "sum":
+		sum := new(big.Int)
+		for _, a := range args {
+			sum.Add(sum, a)
+		}
+		return t.fit(sum)
+	case "range":
+		lo, hi := x, x
+		for _, a := range args[1:] {
+			if a.Cmp(lo) < 0 {
+				lo = a
+			}
+			if a.Cmp(hi) > 0 {
+				hi = a
+			}
+		}
+		return t.fit(new(big.Int).Sub(hi, lo))
+	case "mode":
+		return mode(args, (*big.Int).Cmp), nil
+	case "!", "!!", "nCr", "nPr":
+		fargs := make([]float64, len(args))
+		for i, a := range args {
+			fargs[i], _ = new(big.Float).SetInt(a).Float64()
+		}
+		if err := checkDomain(name, fargs, pos); err != nil {
+			return nil, err
+		}
+		var result *big.Int
+		var ok bool
+		if len(args) == 1 {
+			result, ok = exactFactorial(name, x)
+		} else {
+			result, ok = exactCombinations(name, args[0], args[1])
+		}
+		// Every result too large to compute exactly is far beyond 64 bits.
+		if !ok {
+			return nil, ErrIntegerOverflow
+		}
+		return t.fit(result)
+	}
+	return nil, ErrNotInteger(name)
+}
+
+// FormatInteger prints x in base 2 to 36. Base 10 prints the signed value.
+// Other bases print the bits of the word, so a negative number shows in two's
+// complement, and bases 2, 8 and 16 get a 0b, 0o or 0x prefix.
+func FormatInteger(x *big.Int, t IntType, base int) string {
+	if base == 10 {
+		return x.String()
+	}
+
+	s := t.wrap(x)
+	if s.Sign() < 0 {
+		s.Add(s, t.modulus())
+	}
+	switch base {
+	case 2:
+		return "0b" + s.Text(base)
+	case 8:
+		return "0o" + s.Text(base)
+	case 16:
+		return "0x" + strings.ToUpper(s.Text(base))
+	}
+	return s.Text(base)
+}
@@ -0,0 +1,155 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestCalculateInteger(t *testing.T) {
+	uint8Wrap := evaluation.IntType{Bits: 8, Unsigned: true, Wrap: true}
+	int8Wrap := evaluation.IntType{Bits: 8, Wrap: true}
+
+	tests := []struct {
+		name  string
+		input string
+		typ   evaluation.IntType
+		want  string
+	}{
+		{"arithmetic", "2 + 3 * 4", evaluation.Int64, "14"},
+		{"truncated division", "-7 / 2", evaluation.Int64, "-3"},
+		{"floored remainder", "-7 % 3", evaluation.Int64, "2"},
+		{"remainder with negative divisor", "7 % -3", evaluation.Int64, "-2"},
+		{"floor division", "-7 // 2", evaluation.Int64, "-4"},
+		{"truncated rem", "rem(-7, 3) * 10 + mod(-7, 3)", evaluation.Int64, "-8"},
+		{"hex literal", "0xFF + 1", evaluation.Int64, "256"},
+		{"binary literal", "0b1010 | 0b0101", evaluation.Int64, "15"},
+		{"octal literal", "0o17 & 0xC", evaluation.Int64, "12"},
+		{"negative hex literal", "-0x10", evaluation.Int64, "-16"},
+		{"xor", "6 xor 3", evaluation.Int64, "5"},
+		{"not", "~0", evaluation.Int64, "-1"},
+		{"unsigned not", "~0", uint8Wrap, "255"},
+		{"shift left", "1 << 10", evaluation.Int64, "1024"},
+		{"arithmetic shift right", "-16 >> 2", evaluation.Int64, "-4"},
+		{"shift precedence", "1 + 1 << 2", evaluation.Int64, "8"},
+		{"bitwise precedence", "1 | 2 xor 3 & 5", evaluation.Int64, "3"},
+		{"power", "3 ^ 4", evaluation.Int64, "81"},
+		{"unsigned wrap", "255 + 1", uint8Wrap, "0"},
+		{"unsigned negation wraps", "-1", uint8Wrap, "255"},
+		{"signed wrap", "127 + 1", int8Wrap, "-128"},
+		{"wrapping power", "3 ^ 64", uint8Wrap, "1"},
+		{"wrapping shift", "1 << 100", int8Wrap, "0"},
+		{"functions", "max(abs(-3), 2) + pow(2, 3)", evaluation.Int64, "11"},
+		{"int64 bounds", "-9223372036854775807 - 1", evaluation.Int64, "-9223372036854775808"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := evaluation.CalculateInteger(toRPN(t, tt.input), tt.typ)
+			if err != nil {
+				t.Fatalf("CalculateInteger(%q) failed: %v", tt.input, err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("CalculateInteger(%q) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateIntegerErrors(t *testing.T) {
+	int8 := evaluation.IntType{Bits: 8}
+	uint64 := evaluation.IntType{Bits: 64, Unsigned: true}
+
+	tests := []struct {
+		input string
+		typ   evaluation.IntType
+		want  error
+	}{
+		{"127 + 1", int8, evaluation.ErrIntegerOverflow},
+		{"-(-128)", int8, evaluation.ErrIntegerOverflow},
+		{"0 - 1", uint64, evaluation.ErrIntegerOverflow},
+		{"2 ^ 64", uint64, evaluation.ErrIntegerOverflow},
+		{"1 << 8", int8, evaluation.ErrIntegerOverflow},
+		{"1 / 0", evaluation.Int64, evaluation.ErrDivisionByZero},
+		{"1 % 0", evaluation.Int64, evaluation.ErrDivisionByZero},
+		{"1 // 0", evaluation.Int64, evaluation.ErrDivisionByZero},
+		{"rem(1, 0)", evaluation.Int64, evaluation.ErrDivisionByZero},
+		{"-128 // -1", int8, evaluation.ErrIntegerOverflow},
+		{"2 ^ -1", evaluation.Int64, evaluation.ErrDomain},
+		{"1 << -1", evaluation.Int64, evaluation.ErrDomain},
+	}
+
+	for _, tt := range tests {
+		_, err := evaluation.CalculateInteger(toRPN(t, tt.input), tt.typ)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("CalculateInteger(%q) as %s error = %v, want %v", tt.input, tt.typ, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"1.5 + 1", "pi", "sin(1)"} {
+		if _, err := evaluation.CalculateInteger(toRPN(t, input), evaluation.Int64); err == nil {
+			t.Errorf("CalculateInteger(%q): expected error but got none", input)
+		}
+	}
+}
+
+func TestParseIntType(t *testing.T) {
+	for _, name := range []string{"int8", "int16", "int32", "int64", "uint8", "uint16", "uint32", "uint64"} {
+		typ, err := evaluation.ParseIntType(name)
+		if err != nil {
+			t.Fatalf("ParseIntType(%q) failed: %v", name, err)
+		}
+		if typ.String() != name {
+			t.Errorf("ParseIntType(%q) = %s", name, typ)
+		}
+	}
+
+	for _, name := range []string{"", "int", "int12", "uint128", "float64"} {
+		if _, err := evaluation.ParseIntType(name); err == nil {
+			t.Errorf("ParseIntType(%q): expected error but got none", name)
+		}
+	}
+}
+
+func TestFormatInteger(t *testing.T) {
+	int8 := evaluation.IntType{Bits: 8}
+
+	tests := []struct {
+		input string
+		typ   evaluation.IntType
+		base  int
+		want  string
+	}{
+		{"255", evaluation.Int64, 16, "0xFF"},
+		{"10", evaluation.Int64, 2, "0b1010"},
+		{"8", evaluation.Int64, 8, "0o10"},
+		{"35", evaluation.Int64, 36, "z"},
+		{"-1", int8, 16, "0xFF"},
+		{"-2", int8, 2, "0b11111110"},
+		{"-1", int8, 10, "-1"},
+	}
+
+	for _, tt := range tests {
+		x, err := evaluation.CalculateInteger(toRPN(t, tt.input), tt.typ)
+		if err != nil {
+			t.Fatalf("CalculateInteger(%q) failed: %v", tt.input, err)
+		}
+		if got := evaluation.FormatInteger(x, tt.typ, tt.base); got != tt.want {
+			t.Errorf("FormatInteger(%s, %s, %d) = %s, want %s", x, tt.typ, tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestIntegerOperatorInFloatMode(t *testing.T) {
+	for _, input := range []string{"6 & 3", "~1", "1 << 2"} {
+		if _, err := evaluation.Calculate(toRPN(t, input), false); err == nil {
+			t.Errorf("Calculate(%q): expected error but got none", input)
+		}
+	}
+
+	got, err := evaluation.Calculate(toRPN(t, "0x10 + 0b11"), false)
+	if err != nil || got != 19 {
+		t.Errorf("Calculate(%q) = %v, %v, want 19", "0x10 + 0b11", got, err)
+	}
+}
@@ -0,0 +1,93 @@
+package evaluation_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestIntegrateExpressions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"integrate(x^2, x, 0, 1)", 1.0 / 3},
+		{"integrate(x^2, x, 1, 0)", -1.0 / 3},
+		{"integrate(x, x, 2, 2)", 0},
+		{"integrate(sin(x), x, 0, 180)", 360 / math.Pi},
+		{"integrate(exp(-x^2), x, -10, 10)", math.Sqrt(math.Pi)},
+		{"integrate(log(x), x, 0, 1)", -1},
+		{"integrate(1 / sqrt(x), x, 0, 1)", 2},
+		{"2 * integrate(x, x, 0, 1) + 1", 2},
+		{"integrate(integrate(x * y, y, 0, 1), x, 0, 2)", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := evaluation.Calculate(toRPN(t, tt.input), false)
+			if err != nil {
+				t.Fatalf("Calculate(%q) failed: %v", tt.input, err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Calculate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntegrateErrorEstimate(t *testing.T) {
+	scope := evaluation.NewScope()
+	if _, err := evaluateInScope("a = 3", scope); err != nil {
+		t.Fatalf("assignment failed: %v", err)
+	}
+	got, err := evaluateInScope("integrate(a * x^2, x, 0, 1) + integrate(sqrt(x), x, 0, 1)", scope)
+	if err != nil {
+		t.Fatalf("integrate failed: %v", err)
+	}
+	if math.Abs(got-(1+2.0/3)) > 1e-9 {
+		t.Errorf("integrate = %v, want %v", got, 1+2.0/3)
+	}
+
+	integrals := scope.TakeIntegrals()
+	if len(integrals) != 2 {
+		t.Fatalf("TakeIntegrals() = %v, want 2 integrals", integrals)
+	}
+	for _, i := range integrals {
+		if i.Error < 0 || i.Error > 1e-9 {
+			t.Errorf("error estimate of %v = %v, want at most 1e-9", i.Value, i.Error)
+		}
+	}
+	if integrals := scope.TakeIntegrals(); len(integrals) != 0 {
+		t.Errorf("second TakeIntegrals() = %v, want none", integrals)
+	}
+}
+
+func TestIntegrateErrors(t *testing.T) {
+	_, err := evaluation.Calculate(toRPN(t, "integrate(sqrt(x), x, -1, 1)"), false)
+	var domainErr *evaluation.DomainError
+	if !errors.As(err, &domainErr) || domainErr.Func != "sqrt" || domainErr.Pos != 10 {
+		t.Errorf("integrate of sqrt over [-1, 1]: error = %v, want sqrt domain error at 10", err)
+	}
+
+	slow := func(x float64) (float64, error) {
+		time.Sleep(time.Millisecond)
+		return math.Abs(x - 0.3), nil
+	}
+	if _, err := evaluation.Integrate(slow, 0, 1, 10*time.Millisecond); !errors.Is(err, evaluation.ErrTimeout) {
+		t.Errorf("Integrate with a slow integrand: error = %v, want ErrTimeout", err)
+	}
+
+	if _, err := evaluation.Calculate(toRPN(t, "integrate(1 / x, x, 0, 1)"), false); !errors.Is(err, evaluation.ErrNoConvergence) {
+		t.Errorf("integrate of 1/x over [0, 1]: error = %v, want ErrNoConvergence", err)
+	}
+
+	if _, err := evaluation.CalculateBig(toRPN(t, "integrate(x, x, 0, 1)"), false, 64); evaluation.ErrorCode(err) != "float_only" {
+		t.Errorf("CalculateBig(integrate): error = %v, want float_only", err)
+	}
+	if _, err := evaluation.CompileRPN(toRPN(t, "integrate(x, x, 0, 1)"), false); evaluation.ErrorCode(err) != "not_compilable" {
+		t.Errorf("CompileRPN(integrate): error = %v, want not_compilable", err)
+	}
+}
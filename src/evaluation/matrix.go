@@ -0,0 +1,593 @@
+package evaluation
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/stack"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// Value is a value of matrix mode: a scalar, a vector or a matrix, with its
+// elements in row-major order.
+type Value struct {
+	// Shape is nil for a scalar, {n} for a vector of n elements and
+	// {rows, cols} for a matrix.
+	Shape []int
+	Data  []float64
+}
+
+// Scalar returns x as a Value.
+func Scalar(x float64) Value {
+	return Value{Data: []float64{x}}
+}
+
+// IsScalar reports whether v is a single number.
+func (v Value) IsScalar() bool {
+	return len(v.Shape) == 0
+}
+
+// Nested returns v as a float64, a []float64 or a [][]float64, which is
+// how it appears in JSON.
+func (v Value) Nested() any {
+	switch len(v.Shape) {
+	case 0:
+		return v.Data[0]
+	case 1:
+		return v.Data
+	}
+	rows := make([][]float64, v.Shape[0])
+	for i := range rows {
+		rows[i] = v.Data[i*v.Shape[1] : (i+1)*v.Shape[1]]
+	}
+	return rows
+}
+
+// CalculateMatrix evaluates RPN tokens whose values may be vectors such as
+// [1, 2, 3] and matrices such as [[1, 2], [3, 4]]. The operators +, -, /,
+// ^ and .* and the built-in functions apply elementwise, with a scalar
+// operand used for every element. * multiplies matrices, a matrix and a
+// vector, or two vectors for their dot product, and scales by a scalar.
+// Operands of the wrong shape give a ShapeError at the operator.
+func CalculateMatrix(tokens []tokenizer.Token, useRadians bool) (Value, error) {
+	return CalculateMatrixInScope(tokens, useRadians, nil)
+}
+
+// CalculateMatrixInScope evaluates tokens as CalculateMatrix does, with
+// variables resolved from scope as scalars. Variables hold numbers, so
+// assignments are left to Calculate.
+func CalculateMatrixInScope(tokens []tokenizer.Token, useRadians bool, scope *Scope) (Value, error) {
+	if n := len(tokens); n > 0 && tokens[n-1].Type == tokenizer.Assign {
+		return Value{}, ErrFloatOnly("assignment")
+	}
+	startTime := time.Now()
+	s := stack.New[Value]()
+	argc := 0
+
+	for _, token := range tokens {
+		if time.Since(startTime) > DefaultCalculationTime {
+			return Value{}, ErrTimeout
+		}
+
+		switch token.Type {
+		case tokenizer.Number:
+			if tokenizer.IsImaginary(token) {
+				return Value{}, ErrComplexValue
+			}
+			val, err := parseNumber(token.Value)
+			if err != nil {
+				return Value{}, err
+			}
+			if err := checkOverflow(val); err != nil {
+				return Value{}, err
+			}
+			s.Push(Scalar(val))
+
+		case tokenizer.Constant:
+			switch token.Value {
+			case "pi":
+				s.Push(Scalar(math.Pi))
+			case "e":
+				s.Push(Scalar(math.E))
+			default:
+				return Value{}, ErrComplexValue
+			}
+
+		case tokenizer.Variable:
+			if scope == nil {
+				return Value{}, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+			}
+			val, ok := scope.Get(token.Value)
+			if !ok {
+				return Value{}, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+			}
+			s.Push(Scalar(val))
+
+		case tokenizer.Quote:
+			return Value{}, ErrFloatOnly(binderName(tokens))
+
+		case tokenizer.Comma:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 {
+				return Value{}, ErrInvalidRPNSyntax
+			}
+			argc = n
+
+		case tokenizer.RightBracket:
+			if argc == 0 || s.Len() < argc {
+				return Value{}, ErrInvalidRPNSyntax
+			}
+			elems := make([]Value, argc)
+			for i := argc - 1; i >= 0; i-- {
+				elems[i] = s.Pop()
+			}
+			argc = 0
+
+			v, err := stackValues(elems, token.Pos)
+			if err != nil {
+				return Value{}, err
+			}
+			s.Push(v)
+
+		case tokenizer.Function:
+			if _, ok := scope.Function(token.Value); ok {
+				return Value{}, ErrFloatOnly(token.Value)
+			}
+			arity := tokenizer.ArityOf(token.Value)
+			if argc == 0 {
+				argc = arity.Min
+			}
+			if !arity.Accepts(argc) {
+				return Value{}, tokenizer.ErrArgumentCount(token.Value, token.Pos)
+			}
+			if s.Len() < argc {
+				return Value{}, ErrInvalidRPNSyntax
+			}
+
+			args := make([]Value, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = s.Pop()
+			}
+			argc = 0
+
+			result, err := matrixFunction(token.Value, args, useRadians, token.Pos)
+			if err != nil {
+				return Value{}, err
+			}
+			if err := checkValueOverflow(result); err != nil {
+				return Value{}, err
+			}
+			s.Push(result)
+
+		case tokenizer.UnaryOperator:
+			if s.IsEmpty() {
+				return Value{}, ErrInvalidRPNSyntax
+			}
+			x := s.Pop()
+			switch token.Value {
+			case "-":
+				result := Value{Shape: x.Shape, Data: make([]float64, len(x.Data))}
+				for i, a := range x.Data {
+					result.Data[i] = -a
+				}
+				s.Push(result)
+			case "!", "!!":
+				// The factorials apply to each element, like sqrt.
+				result, err := matrixFunction(token.Value, []Value{x}, useRadians, token.Pos)
+				if err != nil {
+					return Value{}, err
+				}
+				if err := checkValueOverflow(result); err != nil {
+					return Value{}, err
+				}
+				s.Push(result)
+			default:
+				return Value{}, unsupportedOperator(token.Value)
+			}
+
+		case tokenizer.Operator:
+			if s.Len() < 2 {
+				return Value{}, ErrInvalidRPNSyntax
+			}
+
+			b := s.Pop()
+			a := s.Pop()
+
+			result, err := matrixOperator(token.Value, a, b, token.Pos)
+			if err != nil {
+				return Value{}, err
+			}
+			if err := checkValueOverflow(result); err != nil {
+				return Value{}, err
+			}
+			s.Push(result)
+
+		default:
+			return Value{}, ErrInvalidRPNSyntax
+		}
+	}
+
+	if s.Len() != 1 {
+		return Value{}, ErrInvalidRPNSyntax
+	}
+	return s.Pop(), nil
+}
+
+// stackValues builds a vector from scalars or a matrix from vectors of
+// equal length.
+func stackValues(elems []Value, pos int) (Value, error) {
+	first := elems[0]
+	if len(first.Shape) > 1 {
+		return Value{}, &ShapeError{"[]", [][]int{first.Shape}, pos}
+	}
+
+	v := Value{Shape: append([]int{len(elems)}, first.Shape...)}
+	for _, e := range elems {
+		if !sameShape(e, first) {
+			return Value{}, &ShapeError{"[]", [][]int{first.Shape, e.Shape}, pos}
+		}
+		v.Data = append(v.Data, e.Data...)
+	}
+	return v, nil
+}
+
+func matrixOperator(op string, a, b Value, pos int) (Value, error) {
+	switch op {
+	case "+":
+		return elementwise(op, []Value{a, b}, pos, func(x []float64) (float64, error) {
+			return x[0] + x[1], nil
+		})
+	case "-":
+		return elementwise(op, []Value{a, b}, pos, func(x []float64) (float64, error) {
+			return x[0] - x[1], nil
+		})
+	case "*", ".*":
+		if op == "*" && !a.IsScalar() && !b.IsScalar() {
+			return matrixProduct(a, b, pos)
+		}
+		return elementwise(op, []Value{a, b}, pos, func(x []float64) (float64, error) {
+			return x[0] * x[1], nil
+		})
+	case "/":
+		return elementwise(op, []Value{a, b}, pos, func(x []float64) (float64, error) {
+			if x[1] == 0 {
+				return 0, &DivisionError{op, pos}
+			}
+			return x[0] / x[1], nil
+		})
+	case "%", "//":
+		return elementwise(op, []Value{a, b}, pos, func(x []float64) (float64, error) {
+			return applyOperator(op, x[0], x[1], pos)
+		})
+	case "^":
+		return elementwise(op, []Value{a, b}, pos, func(x []float64) (float64, error) {
+			if err := checkDomain(op, x, pos); err != nil {
+				return 0, err
+			}
+			return math.Pow(x[0], x[1]), nil
+		})
+	}
+	return Value{}, unsupportedOperator(op)
+}
+
+// elementwise applies f to the elements of args in turn. Arguments that
+// are not scalars must all have the same shape, which is the shape of the
+// result; scalars are used for every element.
+func elementwise(op string, args []Value, pos int, f func([]float64) (float64, error)) (Value, error) {
+	var shaped *Value
+	for i := range args {
+		if args[i].IsScalar() {
+			continue
+		}
+		if shaped == nil {
+			shaped = &args[i]
+		} else if !sameShape(args[i], *shaped) {
+			return Value{}, &ShapeError{op, shapes(args), pos}
+		}
+	}
+	result := Scalar(0)
+	if shaped != nil {
+		result = Value{Shape: shaped.Shape, Data: make([]float64, len(shaped.Data))}
+	}
+
+	x := make([]float64, len(args))
+	for i := range result.Data {
+		for j, a := range args {
+			if a.IsScalar() {
+				x[j] = a.Data[0]
+			} else {
+				x[j] = a.Data[i]
+			}
+		}
+		y, err := f(x)
+		if err != nil {
+			return Value{}, err
+		}
+		result.Data[i] = y
+	}
+	return result, nil
+}
+
+// matrixProduct multiplies a and b, neither of them a scalar. A vector
+// on the left is a row and on the right a column, and the dimension it
+// stands for is dropped from the result, so two vectors give their dot
+// product.
+func matrixProduct(a, b Value, pos int) (Value, error) {
+	rows, inner := 1, a.Shape[0]
+	if len(a.Shape) == 2 {
+		rows, inner = a.Shape[0], a.Shape[1]
+	}
+	cols := 1
+	if len(b.Shape) == 2 {
+		cols = b.Shape[1]
+	}
+	if b.Shape[0] != inner {
+		return Value{}, &ShapeError{"*", shapes([]Value{a, b}), pos}
+	}
+
+	result := Value{Data: make([]float64, rows*cols)}
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			var sum float64
+			for k := 0; k < inner; k++ {
+				sum += a.Data[i*inner+k] * b.Data[k*cols+j]
+			}
+			result.Data[i*cols+j] = sum
+		}
+	}
+
+	switch {
+	case len(a.Shape) == 2 && len(b.Shape) == 2:
+		result.Shape = []int{rows, cols}
+	case len(a.Shape) == 2:
+		result.Shape = []int{rows}
+	case len(b.Shape) == 2:
+		result.Shape = []int{cols}
+	}
+	return result, nil
+}
+
+func matrixFunction(name string, args []Value, useRadians bool, pos int) (Value, error) {
+	x := args[0]
+
+	switch name {
+	case "transpose":
+		if len(x.Shape) < 2 {
+			return x, nil
+		}
+		rows, cols := x.Shape[0], x.Shape[1]
+		result := Value{Shape: []int{cols, rows}, Data: make([]float64, len(x.Data))}
+		for i := 0; i < rows; i++ {
+			for j := 0; j < cols; j++ {
+				result.Data[j*rows+i] = x.Data[i*cols+j]
+			}
+		}
+		return result, nil
+
+	case "det":
+		if !isSquare(x) {
+			return Value{}, &ShapeError{name, shapes(args), pos}
+		}
+		return Scalar(determinant(x)), nil
+
+	case "inv":
+		if !isSquare(x) {
+			return Value{}, &ShapeError{name, shapes(args), pos}
+		}
+		return inverse(x)
+
+	case "dot":
+		if len(x.Shape) != 1 || !sameShape(x, args[1]) {
+			return Value{}, &ShapeError{name, shapes(args), pos}
+		}
+		return matrixProduct(x, args[1], pos)
+
+	case "cross":
+		a, b := x, args[1]
+		if len(a.Shape) != 1 || a.Shape[0] != 3 || !sameShape(a, b) {
+			return Value{}, &ShapeError{name, shapes(args), pos}
+		}
+		return Value{Shape: []int{3}, Data: []float64{
+			a.Data[1]*b.Data[2] - a.Data[2]*b.Data[1],
+			a.Data[2]*b.Data[0] - a.Data[0]*b.Data[2],
+			a.Data[0]*b.Data[1] - a.Data[1]*b.Data[0],
+		}}, nil
+
+	case "norm":
+		// The Euclidean norm of a vector is the Frobenius norm of a
+		// matrix, and the absolute value of a scalar.
+		var sum float64
+		for _, a := range x.Data {
+			sum = math.Hypot(sum, a)
+		}
+		return Scalar(sum), nil
+
+	case "min", "max":
+		// A single vector or matrix is reduced to its least or greatest
+		// element, as by sum. More arguments apply elementwise, so
+		// min([1, 5], 3) is [1, 3].
+		if len(args) == 1 {
+			x, err := applyFunction(name, x.Data, useRadians)
+			if err != nil {
+				return Value{}, err
+			}
+			return Scalar(x), nil
+		}
+	}
+
+	if tokenizer.Statistics[name] {
+		// A statistic of vectors and matrices is that of their elements,
+		// so mean([1, 2, 3]) is 2.
+		var xs []float64
+		for _, a := range args {
+			xs = append(xs, a.Data...)
+		}
+		if !tokenizer.ArityOf(name).Accepts(len(xs)) {
+			return Value{}, tokenizer.ErrArgumentCount(name, pos)
+		}
+		if err := checkDomain(name, xs, pos); err != nil {
+			return Value{}, err
+		}
+		x, err := applyFunction(name, xs, useRadians)
+		if err != nil {
+			return Value{}, err
+		}
+		return Scalar(x), nil
+	}
+
+	if _, ok := floatFunctions[name]; !ok {
+		return Value{}, tokenizer.ErrUnknownFunction(name)
+	}
+	return elementwise(name, args, pos, func(x []float64) (float64, error) {
+		if err := checkDomain(name, x, pos); err != nil {
+			return 0, err
+		}
+		return applyFunction(name, x, useRadians)
+	})
+}
+
+// determinant computes the determinant of a square matrix by Gaussian
+// elimination with partial pivoting.
+func determinant(x Value) float64 {
+	n := x.Shape[0]
+	m := append([]float64(nil), x.Data...)
+	det := 1.0
+	for k := 0; k < n; k++ {
+		p := pivotRow(m, n, k)
+		if m[p*n+k] == 0 {
+			return 0
+		}
+		if p != k {
+			swapRows(m, n, p, k)
+			det = -det
+		}
+		det *= m[k*n+k]
+		for i := k + 1; i < n; i++ {
+			f := m[i*n+k] / m[k*n+k]
+			for j := k; j < n; j++ {
+				m[i*n+j] -= f * m[k*n+j]
+			}
+		}
+	}
+	return det
+}
+
+// inverse inverts a square matrix by Gauss-Jordan elimination with partial
+// pivoting. A pivot that is negligible next to the largest element makes
+// the matrix singular.
+func inverse(x Value) (Value, error) {
+	n := x.Shape[0]
+	m := append([]float64(nil), x.Data...)
+	inv := Value{Shape: []int{n, n}, Data: make([]float64, n*n)}
+	for i := 0; i < n; i++ {
+		inv.Data[i*n+i] = 1
+	}
+
+	var scale float64
+	for _, a := range m {
+		scale = math.Max(scale, math.Abs(a))
+	}
+
+	for k := 0; k < n; k++ {
+		p := pivotRow(m, n, k)
+		if math.Abs(m[p*n+k]) <= 1e-14*scale {
+			return Value{}, ErrSingularMatrix
+		}
+		swapRows(m, n, p, k)
+		swapRows(inv.Data, n, p, k)
+
+		pivot := m[k*n+k]
+		for j := 0; j < n; j++ {
+			m[k*n+j] /= pivot
+			inv.Data[k*n+j] /= pivot
+		}
+		for i := 0; i < n; i++ {
+			if i == k {
+				continue
+			}
+			f := m[i*n+k]
+			for j := 0; j < n; j++ {
+				m[i*n+j] -= f * m[k*n+j]
+				inv.Data[i*n+j] -= f * inv.Data[k*n+j]
+			}
+		}
+	}
+	return inv, nil
+}
+
+// pivotRow returns the row at or below k with the largest element in
+// column k of the n by n matrix m.
+func pivotRow(m []float64, n, k int) int {
+	p := k
+	for i := k + 1; i < n; i++ {
+		if math.Abs(m[i*n+k]) > math.Abs(m[p*n+k]) {
+			p = i
+		}
+	}
+	return p
+}
+
+func swapRows(m []float64, n, i, j int) {
+	for c := 0; c < n; c++ {
+		m[i*n+c], m[j*n+c] = m[j*n+c], m[i*n+c]
+	}
+}
+
+func isSquare(x Value) bool {
+	return len(x.Shape) == 2 && x.Shape[0] == x.Shape[1]
+}
+
+func sameShape(a, b Value) bool {
+	if len(a.Shape) != len(b.Shape) {
+		return false
+	}
+	for i := range a.Shape {
+		if a.Shape[i] != b.Shape[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func shapes(args []Value) [][]int {
+	result := make([][]int, len(args))
+	for i, a := range args {
+		result[i] = a.Shape
+	}
+	return result
+}
+
+func checkValueOverflow(v Value) error {
+	for _, x := range v.Data {
+		if err := checkOverflow(x); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FormatValue prints v as it is written in an expression, such as 2,
+// [1, 2] or [[1, 2], [3, 4]].
+func FormatValue(v Value) string {
+	var b strings.Builder
+	formatValue(&b, v.Shape, v.Data)
+	return b.String()
+}
+
+func formatValue(b *strings.Builder, shape []int, data []float64) {
+	if len(shape) == 0 {
+		b.WriteString(formatPart(data[0]))
+		return
+	}
+	size := len(data) / shape[0]
+	b.WriteByte('[')
+	for i := 0; i < shape[0]; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		formatValue(b, shape[1:], data[i*size:(i+1)*size])
+	}
+	b.WriteByte(']')
+}
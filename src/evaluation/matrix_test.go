@@ -0,0 +1,161 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+func TestCalculateMatrix(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"vector", "[1, 2, 3]", "[1, 2, 3]"},
+		{"matrix", "[[1, 2], [3, 4]]", "[[1, 2], [3, 4]]"},
+		{"scalar", "2 + 3", "5"},
+		{"sum", "[1, 2] + [3, 4]", "[4, 6]"},
+		{"scalar broadcast", "10 - [1, 2]", "[9, 8]"},
+		{"negation", "-[1, -2]", "[-1, 2]"},
+		{"scaling", "2 * [[1, 2], [3, 4]]", "[[2, 4], [6, 8]]"},
+		{"elementwise product", "[[1, 2], [3, 4]] .* [[5, 6], [7, 8]]", "[[5, 12], [21, 32]]"},
+		{"matrix product", "[[1, 2], [3, 4]] * [[5, 6], [7, 8]]", "[[19, 22], [43, 50]]"},
+		{"matrix times vector", "[[1, 2], [3, 4]] * [1, 1]", "[3, 7]"},
+		{"vector times matrix", "[1, 1] * [[1, 2], [3, 4]]", "[4, 6]"},
+		{"vector product", "[1, 2] * [3, 4]", "11"},
+		{"elementwise power", "[1, 2, 3] ^ 2", "[1, 4, 9]"},
+		{"elementwise function", "sqrt([4, 9])", "[2, 3]"},
+		{"computed elements", "[1 + 1, max(3, 4)]", "[2, 4]"},
+		{"min of a vector", "min([3, 1, 2])", "1"},
+		{"max of a vector", "max([3, 1, 2])", "3"},
+		{"max of a matrix", "max([[1, 5], [3, 2]])", "5"},
+		{"min elementwise", "min([1, 5], 3)", "[1, 3]"},
+		{"max of vectors", "max([1, 5], [4, 2])", "[4, 5]"},
+		{"transpose", "transpose([[1, 2, 3], [4, 5, 6]])", "[[1, 4], [2, 5], [3, 6]]"},
+		{"transpose of vector", "transpose([1, 2])", "[1, 2]"},
+		{"det", "det([[1, 2], [3, 4]])", "-2"},
+		{"det with pivoting", "det([[0, 1], [1, 0]])", "-1"},
+		{"det of singular", "det([[1, 2], [2, 4]])", "0"},
+		{"inv", "inv([[1, 2], [3, 4]])", "[[-2, 1], [1.5, -0.5]]"},
+		{"inv times matrix", "inv([[2, 0], [0, 4]]) * [[2, 0], [0, 4]]", "[[1, 0], [0, 1]]"},
+		{"dot", "dot([1, 2, 3], [4, 5, 6])", "32"},
+		{"cross", "cross([1, 0, 0], [0, 1, 0])", "[0, 0, 1]"},
+		{"norm of vector", "norm([3, 4])", "5"},
+		{"norm of matrix", "norm([[1, 1], [1, 1]])", "2"},
+		{"norm of scalar", "norm(-3)", "3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpn := toRPN(t, tt.input)
+			got, err := evaluation.CalculateMatrix(rpn, false)
+			if err != nil {
+				t.Fatalf("CalculateMatrix(%q) failed: %v", tt.input, err)
+			}
+			if s := evaluation.FormatValue(got); s != tt.want {
+				t.Errorf("CalculateMatrix(%q) = %s, want %s", tt.input, s, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateMatrixErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"[1, 2] / [1, 0]", evaluation.ErrDivisionByZero},
+		{"sqrt([1, -1])", evaluation.ErrDomain},
+		{"inv([[1, 2], [2, 4]])", evaluation.ErrSingularMatrix},
+		{"[1, i]", evaluation.ErrComplexValue},
+		{"[1, 2] + [1, 2, 3]", evaluation.ErrShape},
+		{"[[1, 2], [3]]", evaluation.ErrShape},
+		{"[[1, 2], 3]", evaluation.ErrShape},
+		{"[[[1]]]", evaluation.ErrShape},
+		{"[[1, 2]] * [[1, 2]]", evaluation.ErrShape},
+		{"det([1, 2])", evaluation.ErrShape},
+		{"inv([[1, 2, 3], [4, 5, 6]])", evaluation.ErrShape},
+		{"dot([1, 2], [1, 2, 3])", evaluation.ErrShape},
+		{"cross([1, 2], [3, 4])", evaluation.ErrShape},
+	}
+
+	for _, tt := range tests {
+		rpn := toRPN(t, tt.input)
+		if _, err := evaluation.CalculateMatrix(rpn, false); !errors.Is(err, tt.want) {
+			t.Errorf("CalculateMatrix(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestShapeErrorPos(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{"[1, 2] + [1, 2, 3]", 7},
+		{"[1, 2] * [[1, 2, 3]]", 7},
+		{"1 + det([1, 2])", 4},
+	}
+
+	for _, tt := range tests {
+		rpn := toRPN(t, tt.input)
+		_, err := evaluation.CalculateMatrix(rpn, false)
+		if code := evaluation.ErrorCode(err); code != "shape_mismatch" {
+			t.Errorf("ErrorCode(%q) = %q, want %q", tt.input, code, "shape_mismatch")
+		}
+		if pos, ok := evaluation.ErrorPos(err); !ok || pos != tt.pos {
+			t.Errorf("ErrorPos(%q) = %d, %v, want %d", tt.input, pos, ok, tt.pos)
+		}
+	}
+}
+
+func TestMatrixValueInRealMode(t *testing.T) {
+	for _, input := range []string{"[1, 2]", "det(2)", "2 .* 3"} {
+		rpn := toRPN(t, input)
+		if _, err := evaluation.Calculate(rpn, false); !errors.Is(err, evaluation.ErrMatrixValue) {
+			t.Errorf("Calculate(%q) error = %v, want %v", input, err, evaluation.ErrMatrixValue)
+		}
+		if _, err := evaluation.CalculateComplex(rpn, false); !errors.Is(err, evaluation.ErrMatrixValue) {
+			t.Errorf("CalculateComplex(%q) error = %v, want %v", input, err, evaluation.ErrMatrixValue)
+		}
+		if _, err := evaluation.CompileRPN(rpn, false); !errors.Is(err, evaluation.ErrMatrixValue) {
+			t.Errorf("CompileRPN(%q) error = %v, want %v", input, err, evaluation.ErrMatrixValue)
+		}
+	}
+}
+
+func TestCalculateInScopeOtherModes(t *testing.T) {
+	scope := evaluation.NewScope()
+	scope.Set("x", 2)
+	rpn := func(input string) []tokenizer.Token {
+		tokens, err := tokenizer.TokenizeWithScope(input, scope)
+		if err != nil {
+			t.Fatalf("TokenizeWithScope(%q) failed: %v", input, err)
+		}
+		rpn, err := evaluation.ToRPN(tokens)
+		if err != nil {
+			t.Fatalf("ToRPN(%q) failed: %v", input, err)
+		}
+		return rpn
+	}
+
+	v, err := evaluation.CalculateMatrixInScope(rpn("[1, x] * x"), false, scope)
+	if got := evaluation.FormatValue(v); err != nil || got != "[2, 4]" {
+		t.Errorf("CalculateMatrixInScope([1, x] * x) = %s, %v, want [2, 4]", got, err)
+	}
+	z, err := evaluation.CalculateComplexInScope(rpn("x + 3i"), false, scope)
+	if err != nil || z != 2+3i {
+		t.Errorf("CalculateComplexInScope(x + 3i) = %v, %v, want (2+3i)", z, err)
+	}
+
+	assign := rpn("y = [1, 2]")
+	if _, err := evaluation.CalculateMatrixInScope(assign, false, scope); evaluation.ErrorCode(err) != "float_only" {
+		t.Errorf("CalculateMatrixInScope(y = [1, 2]) error = %v, want float_only", err)
+	}
+	if _, err := evaluation.CalculateMatrix(toRPN(t, "[1, 2] * 2"), false); err != nil {
+		t.Errorf("CalculateMatrix without a scope failed: %v", err)
+	}
+}
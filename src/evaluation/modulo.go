@@ -0,0 +1,74 @@
+package evaluation
+
+import (
+	"math"
+	"math/big"
+)
+
+// floorMod computes a % b for b other than zero. The % operator and mod
+// take the sign of the divisor, flooring the quotient as // does, so -7 % 3
+// is 2 and -7 // 3 is -3. rem truncates the quotient instead and takes the
+// sign of the dividend, so rem(-7, 3) is -1.
+func floorMod(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r != 0 && (r < 0) != (b < 0) {
+		r += b
+	}
+	return r
+}
+
+// floorDiv computes a // b for b other than zero. It is derived from the
+// remainder rather than from a / b, which may round up to the next
+// integer, so that a == b*(a // b) + a % b as closely as float64 allows.
+func floorDiv(a, b float64) float64 {
+	r := math.Mod(a, b)
+	q := (a - r) / b
+	if r != 0 && (r < 0) != (b < 0) {
+		q--
+	}
+	return math.Round(q)
+}
+
+// ratModulo computes a // b, a % b, mod(a, b) or rem(a, b) exactly for b
+// other than zero.
+func ratModulo(op string, a, b *big.Rat) *big.Rat {
+	q := new(big.Rat).Quo(a, b)
+	n := new(big.Int)
+	if op == "rem" {
+		n.Quo(q.Num(), q.Denom())
+	} else {
+		// The denominator is positive, where Euclidean division floors.
+		n.Div(q.Num(), q.Denom())
+	}
+	if op == "//" {
+		return new(big.Rat).SetInt(n)
+	}
+	r := new(big.Rat).Mul(b, new(big.Rat).SetInt(n))
+	return r.Sub(a, r)
+}
+
+// bigModulo computes a // b, a % b, mod(a, b) or rem(a, b) for b other than
+// zero to prec bits.
+func bigModulo(op string, a, b *big.Float, prec uint) *big.Float {
+	wp := prec + 32
+	q := new(big.Float).SetPrec(wp).Quo(a, b)
+	n, _ := q.Int(nil)
+	if op != "rem" && q.Sign() < 0 && !q.IsInt() {
+		n.Sub(n, big.NewInt(1))
+	}
+	nf := new(big.Float).SetPrec(wp).SetInt(n)
+	if op == "//" {
+		return nf.SetPrec(prec)
+	}
+	r := new(big.Float).SetPrec(wp).Mul(b, nf)
+	return r.Sub(a, r).SetPrec(prec)
+}
+
+// intFloorDiv computes a // b for b other than zero.
+func intFloorDiv(a, b *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(a, b, new(big.Int))
+	if r.Sign() != 0 && r.Sign() != b.Sign() {
+		q.Sub(q, big.NewInt(1))
+	}
+	return q
+}
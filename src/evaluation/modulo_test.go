@@ -0,0 +1,116 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestModulo(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+		exact string
+	}{
+		{"7 % 3", 1, "1"},
+		{"-7 % 3", 2, "2"},
+		{"7 % -3", -2, "-2"},
+		{"-7 % -3", -1, "-1"},
+		{"5.5 % 2", 1.5, "3/2"},
+		{"-0.5 % 0.2", 0.1, "1/10"},
+		{"7 // 2", 3, "3"},
+		{"-7 // 2", -4, "-4"},
+		{"7.5 // -2", -4, "-4"},
+		{"-6 // 3", -2, "-2"},
+		{"1 + 7 // 2 * 3", 10, "10"},
+		{"rem(-7, 3)", -1, "-1"},
+		{"rem(7, -3)", 1, "1"},
+		{"mod(-7, 3)", 2, "2"},
+		{"mod(7, -3)", -2, "-2"},
+		{"rem(5.5, -2)", 1.5, "3/2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rpn := toRPN(t, tt.input)
+			got, err := evaluation.Calculate(rpn, false)
+			if err != nil {
+				t.Fatalf("Calculate(%q) failed: %v", tt.input, err)
+			}
+			if !almostEqual(got, tt.want) {
+				t.Errorf("Calculate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+
+			big, err := evaluation.CalculateBig(rpn, false, 128)
+			if err != nil {
+				t.Fatalf("CalculateBig(%q) failed: %v", tt.input, err)
+			}
+			if f, _ := big.Float64(); !almostEqual(f, tt.want) {
+				t.Errorf("CalculateBig(%q) = %v, want %v", tt.input, f, tt.want)
+			}
+
+			r, err := evaluation.CalculateRational(rpn, false)
+			if err != nil {
+				t.Fatalf("CalculateRational(%q) failed: %v", tt.input, err)
+			}
+			if !r.Exact() || r.Value.RatString() != tt.exact {
+				t.Errorf("CalculateRational(%q) = %s, want exactly %s", tt.input, r.Value.RatString(), tt.exact)
+			}
+		})
+	}
+}
+
+func TestModuloDivisionByZero(t *testing.T) {
+	for _, input := range []string{"1 % 0", "1 // 0", "rem(1, 0)", "mod(1, 0)", "1 % (2 - 2)"} {
+		rpn := toRPN(t, input)
+		if _, err := evaluation.Calculate(rpn, false); !errors.Is(err, evaluation.ErrDivisionByZero) {
+			t.Errorf("Calculate(%q) error = %v, want %v", input, err, evaluation.ErrDivisionByZero)
+		}
+		if _, err := evaluation.CalculateBig(rpn, false, 128); !errors.Is(err, evaluation.ErrDivisionByZero) {
+			t.Errorf("CalculateBig(%q) error = %v, want %v", input, err, evaluation.ErrDivisionByZero)
+		}
+		if _, err := evaluation.CalculateRational(rpn, false); !errors.Is(err, evaluation.ErrDivisionByZero) {
+			t.Errorf("CalculateRational(%q) error = %v, want %v", input, err, evaluation.ErrDivisionByZero)
+		}
+		if _, err := evaluation.CalculateComplex(rpn, false); !errors.Is(err, evaluation.ErrDivisionByZero) {
+			t.Errorf("CalculateComplex(%q) error = %v, want %v", input, err, evaluation.ErrDivisionByZero)
+		}
+		if _, err := evaluation.CalculateMatrix(rpn, false); !errors.Is(err, evaluation.ErrDivisionByZero) {
+			t.Errorf("CalculateMatrix(%q) error = %v, want %v", input, err, evaluation.ErrDivisionByZero)
+		}
+	}
+}
+
+func TestModuloOtherModes(t *testing.T) {
+	z, err := evaluation.CalculateComplex(toRPN(t, "-7 % 3 + i"), false)
+	if err != nil || z != 2+1i {
+		t.Errorf("CalculateComplex(-7 %% 3 + i) = %v, %v, want (2+1i)", z, err)
+	}
+	if _, err := evaluation.CalculateComplex(toRPN(t, "i // 2"), false); evaluation.ErrorCode(err) != "not_real" {
+		t.Errorf("CalculateComplex(i // 2) error = %v, want not_real", err)
+	}
+
+	v, err := evaluation.CalculateMatrix(toRPN(t, "[5, -7, 8] % 3 + [7, -7, 1] // 2"), false)
+	if err != nil || evaluation.FormatValue(v) != "[5, -2, 2]" {
+		t.Errorf("CalculateMatrix = %v, %v, want [5, -2, 2]", v, err)
+	}
+
+	p, err := evaluation.Compile("x % 3 + x // 3 + rem(x, 3)")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if got, err := p.Eval(map[string]float64{"x": -7}); err != nil || got != -2 {
+		t.Errorf("Eval(x = -7) = %v, %v, want -2", got, err)
+	}
+	if _, err := p.Eval(map[string]float64{"x": 0}); err != nil {
+		t.Errorf("Eval(x = 0) failed: %v", err)
+	}
+	p, err = evaluation.Compile("mod(1, x)")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if _, err := p.Eval(map[string]float64{"x": 0}); !errors.Is(err, evaluation.ErrDivisionByZero) {
+		t.Errorf("Eval(mod(1, 0)) error = %v, want %v", err, evaluation.ErrDivisionByZero)
+	}
+}
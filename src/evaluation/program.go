@@ -0,0 +1,289 @@
+package evaluation
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// Program is an expression compiled for repeated evaluation. Literals and
+// constants are parsed and functions resolved once by Compile, so Eval
+// neither tokenizes nor allocates. A Program is safe for concurrent use.
+type Program struct {
+	code       []instruction
+	vars       []string
+	depth      int
+	useRadians bool
+	stacks     sync.Pool
+}
+
+type opcode uint8
+
+const (
+	opPush opcode = iota
+	opVar
+	opNeg
+	opAdd
+	opSub
+	opMul
+	opDiv
+	opMod
+	opFloorDiv
+	opPow
+	opCall
+)
+
+var opcodes = map[string]opcode{
+	"+":  opAdd,
+	"-":  opSub,
+	"*":  opMul,
+	"/":  opDiv,
+	"%":  opMod,
+	"//": opFloorDiv,
+	"^":  opPow,
+}
+
+// instruction is a step of a Program. value is the number pushed by opPush;
+// name is the variable of opVar and the function or operator otherwise.
+type instruction struct {
+	op    opcode
+	value float64
+	name  string
+	fn    floatFunction
+	argc  int
+	pos   int
+}
+
+// freeVariables resolves every name that is not built in as a variable.
+type freeVariables struct{}
+
+func (freeVariables) HasVariable(name string) bool { return true }
+func (freeVariables) HasFunction(name string) bool { return false }
+
+// Compile compiles expr for evaluation in degrees. Names other than the
+// built-in constants and functions are variables whose values are passed
+// to Eval.
+func Compile(expr string) (*Program, error) {
+	tokens, err := tokenizer.TokenizeWithScope(expr, freeVariables{})
+	if err != nil {
+		return nil, err
+	}
+	rpn, err := ToRPN(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return CompileRPN(rpn, false)
+}
+
+// CompileRPN compiles an expression already converted by ToRPN. Assignments
+// and user-defined functions cannot be compiled.
+func CompileRPN(tokens []tokenizer.Token, useRadians bool) (*Program, error) {
+	p := &Program{useRadians: useRadians}
+	seen := make(map[string]bool)
+	depth := 0
+	argc := 0
+
+	for _, token := range tokens {
+		in := instruction{name: token.Value, pos: token.Pos}
+
+		switch token.Type {
+		case tokenizer.Number:
+			if tokenizer.IsImaginary(token) {
+				return nil, ErrComplexValue
+			}
+			val, err := parseNumber(token.Value)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkOverflow(val); err != nil {
+				return nil, err
+			}
+			in.op, in.value = opPush, val
+			depth++
+
+		case tokenizer.Constant:
+			switch token.Value {
+			case "pi":
+				in.op, in.value = opPush, math.Pi
+			case "e":
+				in.op, in.value = opPush, math.E
+			default:
+				return nil, ErrComplexValue
+			}
+			depth++
+
+		case tokenizer.Variable:
+			in.op = opVar
+			if !seen[token.Value] {
+				seen[token.Value] = true
+				p.vars = append(p.vars, token.Value)
+			}
+			depth++
+
+		case tokenizer.Comma:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 {
+				return nil, ErrInvalidRPNSyntax
+			}
+			argc = n
+			continue
+
+		case tokenizer.RightBracket:
+			return nil, ErrMatrixValue
+
+		case tokenizer.Function:
+			if tokenizer.MatrixFunctions[token.Value] {
+				return nil, ErrMatrixValue
+			}
+			arity := tokenizer.ArityOf(token.Value)
+			if argc == 0 {
+				argc = arity.Min
+			}
+			if !arity.Accepts(argc) {
+				return nil, tokenizer.ErrArgumentCount(token.Value, token.Pos)
+			}
+			fn, ok := floatFunctions[token.Value]
+			if !ok {
+				return nil, tokenizer.ErrUnknownSymbol(token.Pos)
+			}
+			if depth < argc {
+				return nil, ErrInvalidRPNSyntax
+			}
+			in.op, in.fn, in.argc = opCall, fn, argc
+			depth -= argc - 1
+			argc = 0
+
+		case tokenizer.UnaryOperator:
+			if depth < 1 {
+				return nil, ErrInvalidRPNSyntax
+			}
+			if token.Value == "-" {
+				in.op = opNeg
+				break
+			}
+			fn, ok := floatFunctions[token.Value]
+			if !ok || !tokenizer.PostfixOperators[token.Value] {
+				return nil, unsupportedOperator(token.Value)
+			}
+			in.op, in.fn, in.argc = opCall, fn, 1
+
+		case tokenizer.Operator:
+			op, ok := opcodes[token.Value]
+			if !ok {
+				return nil, unsupportedOperator(token.Value)
+			}
+			if depth < 2 {
+				return nil, ErrInvalidRPNSyntax
+			}
+			in.op = op
+			depth--
+
+		case tokenizer.Assign:
+			return nil, tokenizer.ErrInvalidAssignment(token.Pos)
+
+		case tokenizer.Quote:
+			return nil, ErrNotCompilable(binderName(tokens))
+
+		default:
+			return nil, ErrInvalidRPNSyntax
+		}
+
+		p.code = append(p.code, in)
+		p.depth = max(p.depth, depth)
+	}
+
+	if depth != 1 {
+		return nil, ErrInvalidRPNSyntax
+	}
+	p.stacks.New = func() any {
+		s := make([]float64, 0, p.depth)
+		return &s
+	}
+	return p, nil
+}
+
+// Variables returns the names of the variables the program reads, sorted.
+func (p *Program) Variables() []string {
+	names := append([]string(nil), p.vars...)
+	sort.Strings(names)
+	return names
+}
+
+// Eval evaluates the program with the given variable values. It returns the
+// same results and errors as Calculate on the source expression.
+func (p *Program) Eval(vars map[string]float64) (float64, error) {
+	sp := p.stacks.Get().(*[]float64)
+	defer p.stacks.Put(sp)
+	s := (*sp)[:0]
+
+	for i := range p.code {
+		in := &p.code[i]
+		n := len(s)
+
+		switch in.op {
+		case opPush:
+			s = append(s, in.value)
+
+		case opVar:
+			val, ok := vars[in.name]
+			if !ok {
+				return 0, tokenizer.ErrUndefinedVariable(in.name, in.pos)
+			}
+			s = append(s, val)
+
+		case opNeg:
+			s[n-1] = -s[n-1]
+
+		case opCall:
+			args := s[n-in.argc:]
+			if err := checkDomain(in.name, args, in.pos); err != nil {
+				return 0, err
+			}
+			result := in.fn(args, p.useRadians)
+			if err := checkOverflow(result); err != nil {
+				return 0, err
+			}
+			s = append(s[:n-in.argc], result)
+
+		default:
+			a, b := s[n-2], s[n-1]
+			var result float64
+			switch in.op {
+			case opAdd:
+				result = a + b
+			case opSub:
+				result = a - b
+			case opMul:
+				result = a * b
+			case opDiv:
+				if b == 0 {
+					return 0, &DivisionError{in.name, in.pos}
+				}
+				result = a / b
+			case opMod, opFloorDiv:
+				if b == 0 {
+					return 0, &DivisionError{in.name, in.pos}
+				}
+				if in.op == opMod {
+					result = floorMod(a, b)
+				} else {
+					result = floorDiv(a, b)
+				}
+			case opPow:
+				if err := checkDomain(in.name, s[n-2:], in.pos); err != nil {
+					return 0, err
+				}
+				result = math.Pow(a, b)
+			}
+			if err := checkOverflow(result); err != nil {
+				return 0, err
+			}
+			s = append(s[:n-2], result)
+		}
+	}
+
+	return s[0], nil
+}
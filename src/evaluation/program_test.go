@@ -0,0 +1,159 @@
+package evaluation_test
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+func TestProgramMatchesCalculate(t *testing.T) {
+	for _, input := range []string{
+		"2 + 3 * 4",
+		"-(2 + 3) ^ 2",
+		"2 ^ 3 ^ 2",
+		"sin(30) + cos(60)",
+		"log(8, 2) + log2(8) + log10(1000)",
+		"max(1, 5, 3) - min(4, -2)",
+		"atan2(1, 1) * hypot(3, 4)",
+		"sqrt(16) / abs(-2)",
+		"2 * pi + e",
+		"0x1F + 0b101",
+	} {
+		t.Run(input, func(t *testing.T) {
+			want, err := evaluation.Calculate(toRPN(t, input), false)
+			if err != nil {
+				t.Fatalf("Calculate(%q) failed: %v", input, err)
+			}
+
+			p, err := evaluation.Compile(input)
+			if err != nil {
+				t.Fatalf("Compile(%q) failed: %v", input, err)
+			}
+			got, err := p.Eval(nil)
+			if err != nil {
+				t.Fatalf("Eval(%q) failed: %v", input, err)
+			}
+			if got != want {
+				t.Errorf("Eval(%q) = %v, want %v", input, got, want)
+			}
+		})
+	}
+}
+
+func TestProgramVariables(t *testing.T) {
+	p, err := evaluation.Compile("x^2 + 2*x*y + y - x")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if got := p.Variables(); len(got) != 2 || got[0] != "x" || got[1] != "y" {
+		t.Errorf("Variables() = %v, want [x y]", got)
+	}
+
+	got, err := p.Eval(map[string]float64{"x": 3, "y": 2})
+	if err != nil {
+		t.Fatalf("Eval failed: %v", err)
+	}
+	if got != 20 {
+		t.Errorf("Eval = %v, want 20", got)
+	}
+
+	_, err = p.Eval(map[string]float64{"x": 3})
+	var tokErr *tokenizer.Error
+	if !errors.As(err, &tokErr) || tokErr.Code != "undefined_variable" {
+		t.Errorf("Eval without y: error = %v, want undefined variable", err)
+	}
+}
+
+func TestProgramRadians(t *testing.T) {
+	p, err := evaluation.CompileRPN(toRPN(t, "sin(pi / 2)"), true)
+	if err != nil {
+		t.Fatalf("CompileRPN failed: %v", err)
+	}
+	if got, _ := p.Eval(nil); math.Abs(got-1) > 1e-15 {
+		t.Errorf("Eval = %v, want 1", got)
+	}
+}
+
+func TestProgramErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		vars  map[string]float64
+		want  error
+	}{
+		{"1 / x", map[string]float64{"x": 0}, evaluation.ErrDivisionByZero},
+		{"sqrt(x)", map[string]float64{"x": -1}, evaluation.ErrDomain},
+		{"x ^ 0.5", map[string]float64{"x": -8}, evaluation.ErrDomain},
+		{"10 ^ x", map[string]float64{"x": 400}, evaluation.ErrArithmeticOverflow},
+	}
+
+	for _, tt := range tests {
+		p, err := evaluation.Compile(tt.input)
+		if err != nil {
+			t.Fatalf("Compile(%q) failed: %v", tt.input, err)
+		}
+		if _, err := p.Eval(tt.vars); !errors.Is(err, tt.want) {
+			t.Errorf("Eval(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"x = 3", "2 +", "3i", "5 & 3"} {
+		if _, err := evaluation.Compile(input); err == nil {
+			t.Errorf("Compile(%q): expected an error", input)
+		}
+	}
+}
+
+func TestProgramAllocations(t *testing.T) {
+	p, err := evaluation.Compile("x^2 + sin(x) * max(x, 1, 2) - log(x, 2)")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	vars := map[string]float64{"x": 3}
+	p.Eval(vars)
+
+	if allocs := testing.AllocsPerRun(100, func() { p.Eval(vars) }); allocs != 0 {
+		t.Errorf("Eval allocates %v times per run, want 0", allocs)
+	}
+}
+
+func TestProgramConcurrent(t *testing.T) {
+	p, err := evaluation.Compile("x * (x + 1) / 2")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				x := float64(i)
+				got, err := p.Eval(map[string]float64{"x": x})
+				if err != nil || got != x*(x+1)/2 {
+					t.Errorf("Eval(x=%v) = %v, %v", x, got, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkProgramEval(b *testing.B) {
+	p, err := evaluation.Compile("x^2 + 3*x*sin(x) - 1/x")
+	if err != nil {
+		b.Fatalf("Compile failed: %v", err)
+	}
+	vars := map[string]float64{"x": 2}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		vars["x"] = float64(i%100 + 1)
+		p.Eval(vars)
+	}
+}
@@ -0,0 +1,467 @@
+package evaluation
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/stack"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// maxExactBits bounds the size of an exact power before it falls back to
+// floating point.
+const maxExactBits = 1 << 16
+
+// maxRootDegree is the largest denominator of a fractional exponent for
+// which an exact root is attempted.
+const maxRootDegree = 64
+
+// Rational is the result of an exact evaluation. Value is exact unless
+// Approximate names the function or constant that forced a fallback to
+// float64 arithmetic.
+type Rational struct {
+	Value       *big.Rat
+	Approximate string
+}
+
+// Exact reports whether Value is free of rounding.
+func (r *Rational) Exact() bool {
+	return r.Approximate == ""
+}
+
+// Warning describes why the result is approximate, or is empty.
+func (r *Rational) Warning() string {
+	if r.Exact() {
+		return ""
+	}
+	return fmt.Sprintf("%s has no exact rational value, result is approximate", r.Approximate)
+}
+
+// CalculateRational evaluates RPN tokens with exact big.Rat arithmetic.
+// Addition, subtraction, multiplication, division and powers with integer
+// exponents stay exact. Functions without a rational result, like sin, are
+// evaluated in float64 and mark the result as approximate.
+func CalculateRational(tokens []tokenizer.Token, useRadians bool) (*Rational, error) {
+	startTime := time.Now()
+	s := stack.New[*big.Rat]()
+	argc := 0
+	approximate := ""
+	inexact := func(name string) {
+		if approximate == "" {
+			approximate = name
+		}
+	}
+	// apply evaluates a function or postfix operator exactly when
+	// ratFunction can, and in float64 otherwise.
+	apply := func(token tokenizer.Token, args []*big.Rat) (*big.Rat, error) {
+		if result, ok := ratFunction(token.Value, args); ok {
+			return result, nil
+		}
+		fargs := make([]float64, len(args))
+		for i, a := range args {
+			fargs[i], _ = a.Float64()
+		}
+		if err := checkDomain(token.Value, fargs, token.Pos); err != nil {
+			return nil, err
+		}
+		f, err := applyFunction(token.Value, fargs, useRadians)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkOverflow(f); err != nil {
+			return nil, err
+		}
+		inexact(token.Value)
+		return new(big.Rat).SetFloat64(f), nil
+	}
+
+	for _, token := range tokens {
+		if time.Since(startTime) > DefaultCalculationTime {
+			return nil, ErrTimeout
+		}
+
+		switch token.Type {
+		case tokenizer.Number:
+			if tokenizer.IsImaginary(token) {
+				return nil, ErrComplexValue
+			}
+			val, ok := new(big.Rat).SetString(token.Value)
+			if !ok {
+				return nil, tokenizer.ErrInvalidNumber(token.Pos)
+			}
+			s.Push(val)
+
+		case tokenizer.Constant:
+			var val float64
+			switch token.Value {
+			case "pi":
+				val = math.Pi
+			case "e":
+				val = math.E
+			case "i":
+				return nil, ErrComplexValue
+			}
+			inexact(token.Value)
+			s.Push(new(big.Rat).SetFloat64(val))
+
+		case tokenizer.Variable:
+			return nil, tokenizer.ErrUndefinedVariable(token.Value, token.Pos)
+
+		case tokenizer.Quote:
+			return nil, ErrFloatOnly(binderName(tokens))
+
+		case tokenizer.Comma:
+			n, err := strconv.Atoi(token.Value)
+			if err != nil || n < 1 {
+				return nil, ErrInvalidRPNSyntax
+			}
+			argc = n
+
+		case tokenizer.RightBracket:
+			return nil, ErrMatrixValue
+
+		case tokenizer.Function:
+			if tokenizer.MatrixFunctions[token.Value] {
+				return nil, ErrMatrixValue
+			}
+			arity := tokenizer.ArityOf(token.Value)
+			if argc == 0 {
+				argc = arity.Min
+			}
+			if !arity.Accepts(argc) {
+				return nil, tokenizer.ErrArgumentCount(token.Value, token.Pos)
+			}
+			if s.Len() < argc {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			args := make([]*big.Rat, argc)
+			for i := argc - 1; i >= 0; i-- {
+				args[i] = s.Pop()
+			}
+			argc = 0
+
+			result, err := apply(token, args)
+			if err != nil {
+				return nil, err
+			}
+			s.Push(result)
+
+		case tokenizer.UnaryOperator:
+			if s.IsEmpty() {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			x := s.Pop()
+			switch token.Value {
+			case "-":
+				s.Push(new(big.Rat).Neg(x))
+			case "!", "!!":
+				result, err := apply(token, []*big.Rat{x})
+				if err != nil {
+					return nil, err
+				}
+				s.Push(result)
+			default:
+				return nil, unsupportedOperator(token.Value)
+			}
+
+		case tokenizer.Operator:
+			if s.Len() < 2 {
+				return nil, ErrInvalidRPNSyntax
+			}
+
+			b := s.Pop()
+			a := s.Pop()
+
+			result := new(big.Rat)
+			switch token.Value {
+			case "+":
+				result.Add(a, b)
+			case "-":
+				result.Sub(a, b)
+			case "*":
+				result.Mul(a, b)
+			case "/":
+				if b.Sign() == 0 {
+					return nil, &DivisionError{token.Value, token.Pos}
+				}
+				result.Quo(a, b)
+			case "%", "//":
+				if b.Sign() == 0 {
+					return nil, &DivisionError{token.Value, token.Pos}
+				}
+				result = ratModulo(token.Value, a, b)
+			case "^":
+				if a.Sign() == 0 && b.Sign() < 0 {
+					return nil, &DomainError{token.Value, 0, token.Pos}
+				}
+				var ok bool
+				if result, ok = ratPow(a, b); !ok {
+					fa, _ := a.Float64()
+					fb, _ := b.Float64()
+					if err := checkDomain(token.Value, []float64{fa, fb}, token.Pos); err != nil {
+						return nil, err
+					}
+					f := math.Pow(fa, fb)
+					if err := checkOverflow(f); err != nil {
+						return nil, err
+					}
+					inexact(token.Value)
+					result = new(big.Rat).SetFloat64(f)
+				}
+			default:
+				return nil, unsupportedOperator(token.Value)
+			}
+			s.Push(result)
+
+		default:
+			return nil, ErrInvalidRPNSyntax
+		}
+	}
+
+	if s.Len() != 1 {
+		return nil, ErrInvalidRPNSyntax
+	}
+	return &Rational{Value: s.Pop(), Approximate: approximate}, nil
+}
+
+// ratFunction evaluates a function exactly, reporting false when the result
+// is not rational.
+func ratFunction(name string, args []*big.Rat) (*big.Rat, bool) {
+	if tokenizer.Statistics[name] {
+		return ratStatistic(name, args)
+	}
+	x := args[0]
+
+	switch name {
+	case "abs":
+		return new(big.Rat).Abs(x), true
+	case "re", "conj":
+		return x, true
+	case "im":
+		return new(big.Rat), true
+	case "arg":
+		if x.Sign() >= 0 {
+			return new(big.Rat), true
+		}
+	case "min", "max":
+		result := x
+		for _, a := range args[1:] {
+			if c := a.Cmp(result); name == "min" && c < 0 || name == "max" && c > 0 {
+				result = a
+			}
+		}
+		return result, true
+	case "sqrt":
+		if x.Sign() < 0 {
+			return nil, false
+		}
+		return ratPow(x, big.NewRat(1, 2))
+	case "pow":
+		if args[0].Sign() == 0 && args[1].Sign() < 0 {
+			return nil, false
+		}
+		return ratPow(args[0], args[1])
+	case "rem", "mod":
+		// A zero divisor falls back to float64, which reports it.
+		if args[1].Sign() != 0 {
+			return ratModulo(name, args[0], args[1]), true
+		}
+	case "hypot":
+		sum := new(big.Rat).Mul(args[0], args[0])
+		sum.Add(sum, new(big.Rat).Mul(args[1], args[1]))
+		return ratPow(sum, big.NewRat(1, 2))
+	case "log2":
+		return ratLog(big.NewRat(2, 1), x)
+	case "log10":
+		return ratLog(big.NewRat(10, 1), x)
+	case "log":
+		if len(args) == 2 {
+			return ratLog(args[0], args[1])
+		}
+	case "!", "!!":
+		if x.IsInt() {
+			return ratInt(exactFactorial(name, x.Num()))
+		}
+	case "gamma":
+		// gamma(n) is (n-1)! for positive integers.
+		if x.IsInt() && x.Sign() > 0 {
+			return ratInt(exactFactorial("!", new(big.Int).Sub(x.Num(), big.NewInt(1))))
+		}
+	case "beta":
+		// beta(a, b) is (a-1)! (b-1)! / (a+b-1)! for positive integers.
+		a, b := args[0], args[1]
+		if !a.IsInt() || !b.IsInt() || a.Sign() <= 0 || b.Sign() <= 0 {
+			return nil, false
+		}
+		one := big.NewInt(1)
+		fa, ok := exactFactorial("!", new(big.Int).Sub(a.Num(), one))
+		if !ok {
+			return nil, false
+		}
+		fb, ok := exactFactorial("!", new(big.Int).Sub(b.Num(), one))
+		if !ok {
+			return nil, false
+		}
+		sum := new(big.Int).Add(a.Num(), b.Num())
+		fab, ok := exactFactorial("!", sum.Sub(sum, one))
+		if !ok {
+			return nil, false
+		}
+		return new(big.Rat).SetFrac(fa.Mul(fa, fb), fab), true
+	case "nCr", "nPr":
+		if args[0].IsInt() && args[1].IsInt() {
+			return ratInt(exactCombinations(name, args[0].Num(), args[1].Num()))
+		}
+	}
+	return nil, false
+}
+
+// ratInt converts the result of an exact integer computation.
+func ratInt(n *big.Int, ok bool) (*big.Rat, bool) {
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetInt(n), true
+}
+
+// ratPow computes a**b when the result is rational and reasonably small.
+func ratPow(a, b *big.Rat) (*big.Rat, bool) {
+	if !b.Denom().IsInt64() || b.Denom().Int64() > maxRootDegree {
+		return nil, false
+	}
+	if a.Sign() == 0 {
+		return new(big.Rat), b.Sign() >= 0
+	}
+
+	num, den := new(big.Int).Abs(a.Num()), new(big.Int).Set(a.Denom())
+	degree := b.Denom().Int64()
+	if degree > 1 {
+		if a.Sign() < 0 && degree%2 == 0 {
+			return nil, false
+		}
+		var ok bool
+		if num, ok = intRoot(num, degree); !ok {
+			return nil, false
+		}
+		if den, ok = intRoot(den, degree); !ok {
+			return nil, false
+		}
+	}
+
+	exp := new(big.Int).Abs(b.Num())
+	bits := max(num.BitLen(), den.BitLen())
+	if !exp.IsInt64() || exp.Int64()*int64(bits) > maxExactBits {
+		return nil, false
+	}
+	num.Exp(num, exp, nil)
+	den.Exp(den, exp, nil)
+
+	result := new(big.Rat).SetFrac(num, den)
+	if a.Sign() < 0 && b.Num().Bit(0) == 1 {
+		result.Neg(result)
+	}
+	if b.Sign() < 0 {
+		result.Inv(result)
+	}
+	return result, true
+}
+
+// ratLog computes the logarithm of x to base when it is an integer, such as
+// log10(1000) or log(2, 1/8).
+func ratLog(base, x *big.Rat) (*big.Rat, bool) {
+	if base.Sign() <= 0 || x.Sign() <= 0 || base.Cmp(big.NewRat(1, 1)) == 0 {
+		return nil, false
+	}
+
+	approx := math.Log(ratFloat(x)) / math.Log(ratFloat(base))
+	n := math.Round(approx)
+	if math.IsNaN(n) || math.Abs(n) > maxExactBits {
+		return nil, false
+	}
+	k := big.NewRat(int64(n), 1)
+	if p, ok := ratPow(base, k); ok && p.Cmp(x) == 0 {
+		return k, true
+	}
+	return nil, false
+}
+
+// intRoot returns the k-th root of x when x is a perfect k-th power.
+func intRoot(x *big.Int, k int64) (*big.Int, bool) {
+	if x.Sign() == 0 {
+		return new(big.Int), true
+	}
+	if k == 2 {
+		r := new(big.Int).Sqrt(x)
+		return r, new(big.Int).Mul(r, r).Cmp(x) == 0
+	}
+
+	// Newton's method from an initial guess above the root.
+	bigK := big.NewInt(k)
+	kMinus1 := big.NewInt(k - 1)
+	y := new(big.Int).Lsh(big.NewInt(1), uint(x.BitLen()/int(k)+1))
+	for {
+		// next = ((k−1)·y + x / y**(k−1)) / k
+		t := new(big.Int).Exp(y, kMinus1, nil)
+		t.Quo(x, t)
+		next := new(big.Int).Mul(y, kMinus1)
+		next.Add(next, t)
+		next.Quo(next, bigK)
+		if next.Cmp(y) >= 0 {
+			break
+		}
+		y = next
+	}
+	return y, new(big.Int).Exp(y, bigK, nil).Cmp(x) == 0
+}
+
+func ratFloat(x *big.Rat) float64 {
+	f, _ := x.Float64()
+	return f
+}
+
+// FormatRational prints an exact value as an integer, a terminating decimal
+// such as 0.75, or a fraction such as 1/3. Approximate values are printed
+// as floating-point numbers.
+func FormatRational(r *Rational) string {
+	if !r.Exact() {
+		return strconv.FormatFloat(ratFloat(r.Value), 'g', -1, 64)
+	}
+
+	x := r.Value
+	if x.IsInt() {
+		return x.Num().String()
+	}
+	if digits, ok := decimalDigits(x.Denom()); ok {
+		return x.FloatString(digits)
+	}
+	return x.String()
+}
+
+// decimalDigits reports how many fractional digits 1/d needs when d has no
+// prime factors other than 2 and 5.
+func decimalDigits(d *big.Int) (int, bool) {
+	d = new(big.Int).Set(d)
+	twos, fives := 0, 0
+	two, five := big.NewInt(2), big.NewInt(5)
+	m := new(big.Int)
+	for {
+		if d.QuoRem(d, two, m); m.Sign() != 0 {
+			d.Mul(d, two).Add(d, m)
+			break
+		}
+		twos++
+	}
+	for {
+		if d.QuoRem(d, five, m); m.Sign() != 0 {
+			d.Mul(d, five).Add(d, m)
+			break
+		}
+		fives++
+	}
+	return max(twos, fives), d.Cmp(big.NewInt(1)) == 0
+}
@@ -0,0 +1,72 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestCalculateRational(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		exact bool
+	}{
+		{"decimal sum", "0.1 + 0.2", "0.3", true},
+		{"third", "1 / 3", "1/3", true},
+		{"negative fraction", "-2 / 6", "-1/3", true},
+		{"thirds add up", "1/3 + 1/3 + 1/3", "1", true},
+		{"negative power", "2 ^ -2", "0.25", true},
+		{"large power", "3 ^ 40", "12157665459056928801", true},
+		{"fractional power", "(8/27) ^ (2/3)", "4/9", true},
+		{"perfect square", "sqrt(16/9)", "4/3", true},
+		{"hypot", "hypot(3, 4)", "5", true},
+		{"log of power", "log10(1000)", "3", true},
+		{"log with base", "log(2, 1/8)", "-3", true},
+		{"min", "min(1/2, 1/3)", "1/3", true},
+		{"irrational sqrt", "sqrt(2)", "1.4142135623730951", false},
+		{"sine", "sin(30)", "0.49999999999999994", false},
+		{"constant", "pi", "3.141592653589793", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rpn := toRPN(t, tt.input)
+			got, err := evaluation.CalculateRational(rpn, false)
+			if err != nil {
+				t.Fatalf("CalculateRational(%q) failed: %v", tt.input, err)
+			}
+			if s := evaluation.FormatRational(got); s != tt.want {
+				t.Errorf("CalculateRational(%q) = %s, want %s", tt.input, s, tt.want)
+			}
+			if got.Exact() != tt.exact {
+				t.Errorf("CalculateRational(%q).Exact() = %v, want %v", tt.input, got.Exact(), tt.exact)
+			}
+			if tt.exact == (got.Warning() != "") {
+				t.Errorf("CalculateRational(%q).Warning() = %q", tt.input, got.Warning())
+			}
+		})
+	}
+}
+
+func TestCalculateRationalErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"1 / 0", evaluation.ErrDivisionByZero},
+		{"1 / (1/3 - 1/3)", evaluation.ErrDivisionByZero},
+		{"0 ^ -1", evaluation.ErrDomain},
+		{"sqrt(-4)", evaluation.ErrDomain},
+		{"(-8) ^ (1/2)", evaluation.ErrDomain},
+	}
+
+	for _, tt := range tests {
+		rpn := toRPN(t, tt.input)
+		if _, err := evaluation.CalculateRational(rpn, false); !errors.Is(err, tt.want) {
+			t.Errorf("CalculateRational(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
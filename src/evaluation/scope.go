@@ -0,0 +1,277 @@
+package evaluation
+
+import (
+	"sort"
+	"time"
+
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// DefaultMaxDepth is the default limit on nested calls of user-defined functions.
+const DefaultMaxDepth = 256
+
+// Function is a user-defined function such as "f(x, y) = x^2 + y". Body is
+// in RPN and refers to the parameters as Variable tokens.
+type Function struct {
+	Name   string
+	Params []string
+	Body   []tokenizer.Token
+}
+
+// Scope is the environment an expression is evaluated in. It holds the
+// variables created by assignments such as "x = 3*4" and the user-defined
+// functions. Function bodies are evaluated in a child scope that binds the
+// parameters and falls back to the global scope for everything else.
+type Scope struct {
+	vars   map[string]float64
+	funcs  map[string]*Function
+	parent *Scope
+	depth  int
+
+	// integrals and solutions record the integrals and the roots found by
+	// solve in the global scope.
+	integrals []Integral
+	solutions [][]float64
+
+	// MaxDepth limits how deeply user-defined functions may call each other,
+	// including recursive calls.
+	MaxDepth int
+	// Timeout limits the calculation time of CalculateInScope.
+	Timeout time.Duration
+}
+
+func NewScope() *Scope {
+	return &Scope{
+		vars:     make(map[string]float64),
+		funcs:    make(map[string]*Function),
+		MaxDepth: DefaultMaxDepth,
+		Timeout:  DefaultCalculationTime,
+	}
+}
+
+func (s *Scope) HasVariable(name string) bool {
+	_, ok := s.Get(name)
+	return ok
+}
+
+func (s *Scope) HasFunction(name string) bool {
+	_, ok := s.Function(name)
+	return ok
+}
+
+func (s *Scope) Get(name string) (float64, bool) {
+	if v, ok := s.vars[name]; ok {
+		return v, true
+	}
+	if s.parent != nil {
+		return s.parent.Get(name)
+	}
+	return 0, false
+}
+
+func (s *Scope) Set(name string, value float64) {
+	s.vars[name] = value
+}
+
+// Delete removes the variable name from s.
+func (s *Scope) Delete(name string) {
+	delete(s.vars, name)
+}
+
+// Function returns the user-defined function with the given name. It is safe
+// to call on a nil scope.
+func (s *Scope) Function(name string) (*Function, bool) {
+	if s == nil {
+		return nil, false
+	}
+	fn, ok := s.global().funcs[name]
+	return fn, ok
+}
+
+// Define registers fn, replacing an earlier user-defined function of the same
+// name. Built-in functions, constants and variables cannot be redefined.
+func (s *Scope) Define(fn *Function) error {
+	global := s.global()
+	if isBuiltin(fn.Name) || global.HasVariable(fn.Name) {
+		return ErrRedefinition(fn.Name)
+	}
+	seen := make(map[string]bool, len(fn.Params))
+	for _, p := range fn.Params {
+		if seen[p] {
+			return ErrDuplicateParameter(p)
+		}
+		seen[p] = true
+	}
+	global.funcs[fn.Name] = fn
+	return nil
+}
+
+// Variables returns the names of all defined variables in sorted order.
+func (s *Scope) Variables() []string {
+	names := make([]string, 0, len(s.vars))
+	for name := range s.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Functions returns the names of all user-defined functions in sorted order.
+func (s *Scope) Functions() []string {
+	funcs := s.global().funcs
+	names := make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TakeIntegrals returns the integrals evaluated in s since the last call,
+// in the order they were computed, with their error estimates.
+func (s *Scope) TakeIntegrals() []Integral {
+	global := s.global()
+	integrals := global.integrals
+	global.integrals = nil
+	return integrals
+}
+
+// TakeSolutions returns the roots found by each call of solve in s since
+// the last call, in increasing order.
+func (s *Scope) TakeSolutions() [][]float64 {
+	global := s.global()
+	solutions := global.solutions
+	global.solutions = nil
+	return solutions
+}
+
+// recordIntegral keeps an integral for TakeIntegrals. Only integrals
+// evaluated in the global scope are kept, not those nested in another
+// binder or in a user-defined function, which may be evaluated many times.
+func (s *Scope) recordIntegral(integral Integral) {
+	if s == nil || s.parent != nil {
+		return
+	}
+	s.integrals = append(s.integrals, integral)
+}
+
+// recordSolution keeps the roots found by solve like recordIntegral.
+func (s *Scope) recordSolution(roots []float64) {
+	if s == nil || s.parent != nil {
+		return
+	}
+	s.solutions = append(s.solutions, roots)
+}
+
+// bind returns a child scope of s, which may be nil, with a variable for
+// the bound variable of a binder.
+func (s *Scope) bind(name string) *Scope {
+	local := &Scope{vars: map[string]float64{name: 0}, parent: s}
+	if s != nil {
+		local.depth = s.depth
+	}
+	return local
+}
+
+func (s *Scope) global() *Scope {
+	for s.parent != nil {
+		s = s.parent
+	}
+	return s
+}
+
+func (s *Scope) call(fn *Function, args []float64, pos int, useRadians bool, timeout time.Duration) (float64, error) {
+	if len(args) != len(fn.Params) {
+		return 0, tokenizer.ErrArgumentCount(fn.Name, pos)
+	}
+	global := s.global()
+	if s.depth >= global.MaxDepth {
+		return 0, ErrRecursionDepth
+	}
+
+	local := &Scope{
+		vars:   make(map[string]float64, len(args)),
+		parent: global,
+		depth:  s.depth + 1,
+	}
+	for i, p := range fn.Params {
+		local.vars[p] = args[i]
+	}
+	return calculate(fn.Body, useRadians, local, timeout)
+}
+
+// assign evaluates an assignment or function definition in RPN form, as
+// produced by ToRPN. A definition yields zero.
+func assign(tokens []tokenizer.Token, useRadians bool, scope *Scope, timeout time.Duration) (float64, error) {
+	n := len(tokens)
+	op := tokens[n-1]
+	if scope == nil || n < 3 {
+		return 0, tokenizer.ErrInvalidAssignment(op.Pos)
+	}
+
+	target := tokens[0]
+	switch target.Type {
+	case tokenizer.Variable:
+		if isBuiltin(target.Value) || scope.HasFunction(target.Value) {
+			return 0, ErrRedefinition(target.Value)
+		}
+		value, err := calculate(tokens[1:n-1], useRadians, scope, timeout)
+		if err != nil {
+			return 0, err
+		}
+		scope.Set(target.Value, value)
+		return value, nil
+
+	case tokenizer.Function:
+		fn, err := parseDefinition(tokens[:n-1])
+		if err != nil {
+			return 0, err
+		}
+		return 0, scope.Define(fn)
+	}
+
+	return 0, tokenizer.ErrInvalidAssignment(op.Pos)
+}
+
+func parseDefinition(tokens []tokenizer.Token) (*Function, error) {
+	name := tokens[0]
+	if len(tokens) < 2 || tokens[1].Type != tokenizer.LeftBrace {
+		return nil, tokenizer.ErrInvalidAssignment(name.Pos)
+	}
+
+	fn := &Function{Name: name.Value}
+	i := 2
+	for {
+		if i+1 >= len(tokens) || tokens[i].Type != tokenizer.Variable {
+			return nil, tokenizer.ErrInvalidAssignment(name.Pos)
+		}
+		fn.Params = append(fn.Params, tokens[i].Value)
+		sep := tokens[i+1]
+		i += 2
+		if sep.Type == tokenizer.RightBrace {
+			break
+		}
+		if sep.Type != tokenizer.Comma {
+			return nil, tokenizer.ErrInvalidAssignment(sep.Pos)
+		}
+	}
+
+	fn.Body = tokens[i:]
+	if len(fn.Body) == 0 {
+		return nil, tokenizer.ErrInvalidAssignment(name.Pos)
+	}
+	return fn, nil
+}
+
+func isBuiltin(name string) bool {
+	_, isFunction := tokenizer.Functions[name]
+	_, isConstant := tokenizer.Constants[name]
+	return isFunction || isConstant
+}
+
+// IsDefinition reports whether rpn defines a function rather than computing
+// a value.
+func IsDefinition(rpn []tokenizer.Token) bool {
+	n := len(rpn)
+	return n > 0 && rpn[0].Type == tokenizer.Function && rpn[n-1].Type == tokenizer.Assign
+}
@@ -0,0 +1,238 @@
+package evaluation_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+func TestCalculateInScope(t *testing.T) {
+	scope := evaluation.NewScope()
+	steps := []struct {
+		input string
+		want  float64
+	}{
+		{"x = 3 * 4", 12},
+		{"y = x / 2", 6},
+		{"x + y ^ 2", 48},
+		{"x = x + 1", 13},
+		{"max(x, y) - sqrt(y + 3)", 10},
+	}
+
+	for _, step := range steps {
+		got, err := evaluateInScope(step.input, scope)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", step.input, err)
+		}
+		if !almostEqual(got, step.want) {
+			t.Errorf("%q = %v, want %v", step.input, got, step.want)
+		}
+	}
+
+	if got := scope.Variables(); len(got) != 2 || got[0] != "x" || got[1] != "y" {
+		t.Errorf("Variables() = %v, want [x y]", got)
+	}
+}
+
+func TestCalculateInScopeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		pos   int
+	}{
+		{"undefined variable", "1 + z", 4},
+		{"assign to constant", "pi = 3", 3},
+		{"assign to number", "2 = 3", 2},
+		{"assignment inside expression", "1 + 2 = 3", 6},
+		{"chained assignment", "x = y = 2", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := evaluateInScope(tt.input, evaluation.NewScope())
+			var tokErr *tokenizer.Error
+			if !errors.As(err, &tokErr) {
+				t.Fatalf("%q: error = %v, want *tokenizer.Error", tt.input, err)
+			}
+			if tokErr.Pos != tt.pos {
+				t.Errorf("%q: error position = %d, want %d", tt.input, tokErr.Pos, tt.pos)
+			}
+		})
+	}
+}
+
+func TestUserDefinedFunctions(t *testing.T) {
+	scope := evaluation.NewScope()
+	steps := []struct {
+		input string
+		want  float64
+	}{
+		{"f(x, y) = x^2 + y", 0},
+		{"f(3, 1)", 10},
+		{"k = 2", 2},
+		{"scale(x) = k * x", 0},
+		{"scale(f(1, 1)) + 1", 5},
+		{"k = 10", 10},
+		{"scale(2)", 20},
+		{"g(k) = k + 1", 0},
+		{"g(1) + k", 12},
+		{"f(x) = x / 2", 0},
+		{"f(max(4, 6))", 3},
+	}
+
+	for _, step := range steps {
+		got, err := evaluateInScope(step.input, scope)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", step.input, err)
+		}
+		if !almostEqual(got, step.want) {
+			t.Errorf("%q = %v, want %v", step.input, got, step.want)
+		}
+	}
+
+	if got := scope.Functions(); len(got) != 3 {
+		t.Errorf("Functions() = %v, want 3 functions", got)
+	}
+}
+
+func TestRecursiveFunctions(t *testing.T) {
+	scope := evaluation.NewScope()
+	steps := []struct {
+		input string
+		want  float64
+	}{
+		{"fact(n) = if(n, n * fact(n - 1), 1)", 0},
+		{"fact(10)", 3628800},
+		{"fib(n) = if(n - 1, if(n, fib(n - 1) + fib(n - 2), 0), 1)", 0},
+		{"fib(15)", 610},
+		{"gcd(a, b) = if(b, gcd(b, a % b), a)", 0},
+		{"gcd(1071, 462)", 21},
+		// Only the branch taken is evaluated.
+		{"if(0, 1 / 0, 2) + if(3, 4, sqrt(-1))", 6},
+	}
+
+	for _, step := range steps {
+		got, err := evaluateInScope(step.input, scope)
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", step.input, err)
+		}
+		if !almostEqual(got, step.want) {
+			t.Errorf("%q = %v, want %v", step.input, got, step.want)
+		}
+	}
+
+	scope.MaxDepth = 5
+	if _, err := evaluateInScope("fact(10)", scope); !errors.Is(err, evaluation.ErrRecursionDepth) {
+		t.Errorf("fact(10) with MaxDepth 5: error = %v, want %v", err, evaluation.ErrRecursionDepth)
+	}
+	if got, err := evaluateInScope("fact(4)", scope); err != nil || got != 24 {
+		t.Errorf("fact(4) with MaxDepth 5 = %v, %v, want 24", got, err)
+	}
+}
+
+func TestUserDefinedFunctionErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  []string
+		input  string
+		target error
+	}{
+		{
+			name:   "unbounded recursion",
+			setup:  []string{"f(x) = f(x - 1) + 1"},
+			input:  "f(3)",
+			target: evaluation.ErrRecursionDepth,
+		},
+		{
+			name:   "mutual recursion",
+			setup:  []string{"a(x) = x", "b(x) = a(x)", "a(x) = b(x)"},
+			input:  "a(1)",
+			target: evaluation.ErrRecursionDepth,
+		},
+		{
+			name:  "wrong argument count",
+			setup: []string{"f(x, y) = x + y"},
+			input: "1 + f(1)",
+		},
+		{
+			name:  "redefine built-in function",
+			input: "sqrt(x) = x",
+		},
+		{
+			name:  "redefine variable as function",
+			setup: []string{"v = 1"},
+			input: "v(x) = x",
+		},
+		{
+			name:  "redefine function as variable",
+			setup: []string{"f(x) = x"},
+			input: "f = 1",
+		},
+		{
+			name:  "duplicate parameter",
+			input: "f(x, x) = x",
+		},
+		{
+			name:  "undefined variable in body",
+			input: "f(x) = x + y",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scope := evaluation.NewScope()
+			scope.MaxDepth = 20
+			for _, input := range tt.setup {
+				if _, err := evaluateInScope(input, scope); err != nil {
+					t.Fatalf("%q: unexpected error: %v", input, err)
+				}
+			}
+
+			_, err := evaluateInScope(tt.input, scope)
+			if err == nil {
+				t.Fatalf("%q: expected error but got none", tt.input)
+			}
+			if tt.target != nil && !errors.Is(err, tt.target) {
+				t.Errorf("%q: error = %v, want %v", tt.input, err, tt.target)
+			}
+		})
+	}
+}
+
+func TestRedefinitionError(t *testing.T) {
+	scope := evaluation.NewScope()
+	if _, err := evaluateInScope("f(x) = x", scope); err != nil {
+		t.Fatalf("f(x) = x: unexpected error: %v", err)
+	}
+	for _, input := range []string{"sin = 2", "f = 1", "sqrt(x) = x"} {
+		if _, err := evaluateInScope(input, scope); evaluation.ErrorCode(err) != "redefinition" {
+			t.Errorf("%q: error = %v, want redefinition", input, err)
+		}
+	}
+}
+
+func TestUndefinedVariableInRPN(t *testing.T) {
+	rpn := []tokenizer.Token{
+		{Type: tokenizer.Variable, Value: "x", Pos: 0},
+		{Type: tokenizer.Number, Value: "1", Pos: 4},
+		{Type: tokenizer.Operator, Value: "+", Pos: 2},
+	}
+
+	if _, err := evaluation.Calculate(rpn, false); err == nil {
+		t.Error("Expected error for variable without scope")
+	}
+}
+
+func evaluateInScope(input string, scope *evaluation.Scope) (float64, error) {
+	tokens, err := tokenizer.TokenizeWithScope(input, scope)
+	if err != nil {
+		return 0, err
+	}
+	rpn, err := evaluation.ToRPN(tokens)
+	if err != nil {
+		return 0, err
+	}
+	return evaluation.CalculateInScope(rpn, false, scope)
+}
@@ -0,0 +1,45 @@
+package evaluation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrShape matches every *ShapeError with errors.Is.
+var ErrShape = newError("shape_mismatch", "operands have incompatible shapes")
+
+// ShapeError reports an operator or function applied to vectors or
+// matrices whose shapes do not fit, such as [1, 2] + [1, 2, 3] or the
+// determinant of a vector.
+type ShapeError struct {
+	Op     string
+	Shapes [][]int
+	Pos    int
+}
+
+func (e *ShapeError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.message(), e.Pos)
+}
+
+func (e *ShapeError) message() string {
+	names := make([]string, len(e.Shapes))
+	for i, shape := range e.Shapes {
+		names[i] = shapeName(shape)
+	}
+	return fmt.Sprintf("%s: cannot apply to %s", e.Op, strings.Join(names, " and "))
+}
+
+// Is makes errors.Is(err, ErrShape) hold for shape errors.
+func (e *ShapeError) Is(target error) bool {
+	return target == ErrShape
+}
+
+func shapeName(shape []int) string {
+	switch len(shape) {
+	case 0:
+		return "scalar"
+	case 1:
+		return fmt.Sprintf("vector of %d", shape[0])
+	}
+	return fmt.Sprintf("%dx%d matrix", shape[0], shape[1])
+}
@@ -0,0 +1,334 @@
+package evaluation
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+const (
+	// DefaultSolveFrom and DefaultSolveTo bound the roots solve looks for
+	// when no interval is given.
+	DefaultSolveFrom = -100
+	DefaultSolveTo   = 100
+
+	// solveSamples is the number of subintervals Roots scans for sign
+	// changes.
+	solveSamples = 2000
+	// maxIterations bounds Brent's and Newton's method.
+	maxIterations = 200
+	// maxBracketSteps bounds how often Root doubles the distance from the
+	// guess while looking for a sign change.
+	maxBracketSteps = 80
+)
+
+// Roots returns the real roots of f in [a, b] in increasing order. The
+// interval is sampled, and every sign change is narrowed down with Brent's
+// method and refined with Newton's method. Sampled points close to a local
+// minimum of |f| are refined with Newton's method too, which finds roots
+// without a sign change such as that of x^2, as are the ends of the
+// interval. Points where f is undefined,
+// such as the poles of tan, are skipped, and sign changes across a pole are
+// not reported. Roots returns ErrNoRoot when it finds none.
+func Roots(f func(float64) (float64, error), a, b float64) ([]float64, error) {
+	if a > b {
+		a, b = b, a
+	}
+
+	xs := make([]float64, solveSamples+1)
+	ys := make([]float64, solveSamples+1)
+	ok := make([]bool, solveSamples+1)
+	for i := range xs {
+		xs[i] = a + (b-a)*float64(i)/solveSamples
+		var err error
+		if ys[i], ok[i], err = sample(f, xs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var roots []float64
+	for i := range xs {
+		if ok[i] && ys[i] == 0 {
+			roots = append(roots, xs[i])
+			continue
+		}
+
+		if i+1 < len(xs) && ok[i] && ok[i+1] && ys[i+1] != 0 && math.Signbit(ys[i]) != math.Signbit(ys[i+1]) {
+			r, err := brent(f, xs[i], xs[i+1], ys[i], ys[i+1])
+			if errors.Is(err, ErrNoConvergence) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			r, y, err := polish(f, r)
+			if err != nil {
+				return nil, err
+			}
+			// Near a pole f changes sign without vanishing.
+			if math.Abs(y) <= 1e-6*math.Max(1, math.Min(math.Abs(ys[i]), math.Abs(ys[i+1]))) {
+				roots = append(roots, r)
+			}
+			continue
+		}
+
+		if i > 0 && i+1 < len(xs) && ok[i-1] && ok[i] && ok[i+1] &&
+			math.Abs(ys[i]) < math.Abs(ys[i-1]) && math.Abs(ys[i]) <= math.Abs(ys[i+1]) &&
+			math.Signbit(ys[i-1]) == math.Signbit(ys[i]) && math.Signbit(ys[i]) == math.Signbit(ys[i+1]) {
+			r, converged, err := newton(f, xs[i])
+			if err != nil {
+				return nil, err
+			}
+			if converged && xs[i-1] < r && r < xs[i+1] {
+				roots = append(roots, r)
+			}
+		}
+
+		// At an end of the interval f may miss a root there by rounding, as
+		// sin(360) is -2.4e-16 in degrees, without changing sign.
+		if i == 0 || i == solveSamples {
+			j := 1
+			if i == solveSamples {
+				j = i - 1
+			}
+			if ok[i] && ok[j] && math.Abs(ys[i]) < math.Abs(ys[j]) && math.Signbit(ys[i]) == math.Signbit(ys[j]) {
+				r, converged, err := newton(f, xs[i])
+				if err != nil {
+					return nil, err
+				}
+				tol := 1e-9 * math.Max(1, math.Abs(xs[i]))
+				if converged && math.Min(xs[i], xs[j])-tol <= r && r <= math.Max(xs[i], xs[j])+tol {
+					roots = append(roots, math.Max(a, math.Min(b, r)))
+				}
+			}
+		}
+	}
+
+	roots = uniqueRoots(roots)
+	if len(roots) == 0 {
+		return nil, ErrNoRoot
+	}
+	return roots, nil
+}
+
+// Root returns a root of f near guess. It looks for a sign change at
+// growing distances on both sides of guess and narrows it down with
+// Brent's method, falling back to Newton's method from guess for roots
+// without a sign change. ErrNoConvergence is returned when neither
+// succeeds.
+func Root(f func(float64) (float64, error), guess float64) (float64, error) {
+	y, ok, err := sample(f, guess)
+	if err != nil {
+		return 0, err
+	}
+	if ok && y == 0 {
+		return guess, nil
+	}
+
+	// xs, ys and oks hold the last point evaluated below and above guess.
+	xs := [2]float64{guess, guess}
+	ys := [2]float64{y, y}
+	oks := [2]bool{ok, ok}
+	step := 1e-3 * math.Max(1, math.Abs(guess))
+	for i := 0; i < maxBracketSteps; i++ {
+		for side, x := range [2]float64{guess - step, guess + step} {
+			y, ok, err := sample(f, x)
+			if err != nil {
+				return 0, err
+			}
+			if !ok {
+				continue
+			}
+			if oks[side] && math.Signbit(y) != math.Signbit(ys[side]) {
+				r, err := brent(f, x, xs[side], y, ys[side])
+				if err != nil {
+					return 0, err
+				}
+				r, _, err = polish(f, r)
+				return r, err
+			}
+			xs[side], ys[side], oks[side] = x, y, true
+		}
+		step *= 2
+	}
+
+	r, converged, err := newton(f, guess)
+	if err != nil {
+		return 0, err
+	}
+	if !converged {
+		return 0, ErrNoConvergence
+	}
+	return r, nil
+}
+
+// sample evaluates f at x. ok is false where f is undefined, which is not
+// an error for root finding.
+func sample(f func(float64) (float64, error), x float64) (y float64, ok bool, err error) {
+	y, err = f(x)
+	if errors.Is(err, ErrDomain) || errors.Is(err, ErrArithmeticOverflow) || errors.Is(err, ErrDivisionByZero) {
+		return 0, false, nil
+	}
+	return y, err == nil, err
+}
+
+// brent finds a root of f in [a, b], where f(a) = fa and f(b) = fb differ
+// in sign, with Brent's method.
+func brent(f func(float64) (float64, error), a, b, fa, fb float64) (float64, error) {
+	c, fc := b, fb
+	var d, e float64
+	for i := 0; i < maxIterations; i++ {
+		if math.Signbit(fb) == math.Signbit(fc) {
+			c, fc = a, fa
+			d = b - a
+			e = d
+		}
+		if math.Abs(fc) < math.Abs(fb) {
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+
+		tol := 2*epsilon*math.Abs(b) + 1e-300
+		m := (c - b) / 2
+		if math.Abs(m) <= tol || fb == 0 {
+			return b, nil
+		}
+
+		if math.Abs(e) >= tol && math.Abs(fa) > math.Abs(fb) {
+			// Inverse quadratic interpolation, or the secant method.
+			var p, q float64
+			s := fb / fa
+			if a == c {
+				p = 2 * m * s
+				q = 1 - s
+			} else {
+				q = fa / fc
+				r := fb / fc
+				p = s * (2*m*q*(q-r) - (b-a)*(r-1))
+				q = (q - 1) * (r - 1) * (s - 1)
+			}
+			if p > 0 {
+				q = -q
+			} else {
+				p = -p
+			}
+			if 2*p < math.Min(3*m*q-math.Abs(tol*q), math.Abs(e*q)) {
+				e = d
+				d = p / q
+			} else {
+				d = m
+				e = d
+			}
+		} else {
+			d = m
+			e = d
+		}
+
+		a, fa = b, fb
+		if math.Abs(d) > tol {
+			b += d
+		} else {
+			b += math.Copysign(tol, m)
+		}
+		var ok bool
+		var err error
+		if fb, ok, err = sample(f, b); err != nil {
+			return 0, err
+		} else if !ok {
+			return 0, ErrNoConvergence
+		}
+	}
+	return 0, ErrNoConvergence
+}
+
+// epsilon is the difference between 1 and the next float64.
+const epsilon = 0x1p-52
+
+// newton runs Newton's method from x with a numerical derivative. It
+// reports whether the steps became negligible before maxIterations.
+func newton(f func(float64) (float64, error), x float64) (float64, bool, error) {
+	for i := 0; i < maxIterations; i++ {
+		y, d, ok, err := slope(f, x)
+		if err != nil || !ok {
+			return x, false, err
+		}
+		if y == 0 {
+			return x, true, nil
+		}
+		if d == 0 {
+			return x, false, nil
+		}
+		step := y / d
+		x -= step
+		if math.IsInf(x, 0) || math.IsNaN(x) {
+			return x, false, nil
+		}
+		if math.Abs(step) <= 1e-12*math.Max(1, math.Abs(x)) {
+			y, ok, err := sample(f, x)
+			return x, ok && math.Abs(y) <= 1e-9, err
+		}
+	}
+	return x, false, nil
+}
+
+// polish refines a root found by Brent's method with Newton steps for as
+// long as they bring f closer to zero, and returns it with f at it.
+func polish(f func(float64) (float64, error), x float64) (float64, float64, error) {
+	y, ok, err := sample(f, x)
+	if err != nil || !ok {
+		return x, math.Inf(1), err
+	}
+	for i := 0; i < 3 && y != 0; i++ {
+		_, d, ok, err := slope(f, x)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !ok || d == 0 {
+			break
+		}
+		next := x - y/d
+		ny, ok, err := sample(f, next)
+		if err != nil {
+			return 0, 0, err
+		}
+		if !ok || math.Abs(ny) >= math.Abs(y) {
+			break
+		}
+		x, y = next, ny
+	}
+	return x, y, nil
+}
+
+// slope returns f(x) and the central difference of f at x.
+func slope(f func(float64) (float64, error), x float64) (y, d float64, ok bool, err error) {
+	h := 1e-7 * math.Max(1, math.Abs(x))
+	y, ok, err = sample(f, x)
+	if err != nil || !ok {
+		return 0, 0, false, err
+	}
+	y1, ok1, err := sample(f, x-h)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	y2, ok2, err := sample(f, x+h)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if !ok1 || !ok2 {
+		return y, 0, false, nil
+	}
+	return y, (y2 - y1) / (2 * h), true, nil
+}
+
+// uniqueRoots sorts roots and merges those that differ only by rounding.
+func uniqueRoots(roots []float64) []float64 {
+	sort.Float64s(roots)
+	var unique []float64
+	for _, r := range roots {
+		if n := len(unique); n > 0 && math.Abs(r-unique[n-1]) <= 1e-9*math.Max(1, math.Abs(r)) {
+			continue
+		}
+		unique = append(unique, r)
+	}
+	return unique
+}
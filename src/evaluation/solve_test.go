@@ -0,0 +1,109 @@
+package evaluation_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestSolveRoots(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []float64
+	}{
+		{"solve(x^2 = 4, x)", []float64{-2, 2}},
+		{"solve(x^3 - 6*x^2 + 11*x = 6, x)", []float64{1, 2, 3}},
+		{"solve(x^2, x)", []float64{0}},
+		{"solve((x - 1)^2 * (x + 3), x)", []float64{-3, 1}},
+		{"solve(sqrt(x) = 3, x)", []float64{9}},
+		{"solve(2^x = 10, x, 0, 10)", []float64{math.Log2(10)}},
+		{"solve(tan(x), x, -200, 200)", []float64{-180, 0, 180}},
+		{"solve(1 / x = x, x)", []float64{-1, 1}},
+		{"solve(sin(x), x, 0, 360)", []float64{0, 180, 360}},
+		{"solve(sin(x), x, -360, 0)", []float64{-360, -180, 0}},
+		{"solve(x = 5, x, 0, 5)", []float64{5}},
+		{"solve(x = -5, x, -5, 5)", []float64{-5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			scope := evaluation.NewScope()
+			got, err := evaluateInScope(tt.input, scope)
+			if err != nil {
+				t.Fatalf("%q failed: %v", tt.input, err)
+			}
+			if math.Abs(got-tt.want[0]) > 1e-7 {
+				t.Errorf("%q = %v, want %v", tt.input, got, tt.want[0])
+			}
+
+			solutions := scope.TakeSolutions()
+			if len(solutions) != 1 || len(solutions[0]) != len(tt.want) {
+				t.Fatalf("TakeSolutions() after %q = %v, want [%v]", tt.input, solutions, tt.want)
+			}
+			for i, r := range solutions[0] {
+				if math.Abs(r-tt.want[i]) > 1e-7 {
+					t.Errorf("root %d of %q = %v, want %v", i, tt.input, r, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRoot(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"root(x^2 - 2, x, 1)", math.Sqrt2},
+		{"root(x^2 - 2, x, -1)", -math.Sqrt2},
+		{"root(x^2 - 2, x, 1000)", math.Sqrt2},
+		{"root(cos(x) - x * pi / 180, x, 30)", 0.7390851332151607 * 180 / math.Pi},
+		{"root((x - 5)^2, x, 4)", 5},
+		{"root(log(x) - 1, x, 0.5)", math.E},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := evaluation.Calculate(toRPN(t, tt.input), false)
+			if err != nil {
+				t.Fatalf("Calculate(%q) failed: %v", tt.input, err)
+			}
+			if math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("Calculate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+	}{
+		{"solve(x^2 = -1, x)", "no_root"},
+		{"solve(x = 200, x)", "no_root"},
+		{"solve(x = 5.001, x, 0, 5)", "no_root"},
+		{"solve(1 / x, x)", "no_root"},
+		{"root(x^2 + 1, x, 0)", "no_convergence"},
+		{"solve(x = 1, x, 0)", "argument_count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := evaluation.Calculate(toRPN(t, tt.input), false)
+			if code := evaluation.ErrorCode(err); code != tt.code {
+				t.Errorf("Calculate(%q) error = %v (%s), want %s", tt.input, err, code, tt.code)
+			}
+		})
+	}
+
+	_, err := evaluation.Calculate(toRPN(t, "root(x^2 + 1, x, 0)"), false)
+	if errors.Is(err, evaluation.ErrTimeout) {
+		t.Errorf("root without a root: error = %v, want it to differ from ErrTimeout", err)
+	}
+	if _, err := evaluation.CompileRPN(toRPN(t, "solve(x = 1, x)"), false); evaluation.ErrorCode(err) != "not_compilable" {
+		t.Errorf("CompileRPN(solve): error = %v, want not_compilable", err)
+	}
+}
@@ -0,0 +1,297 @@
+package evaluation
+
+import (
+	"cmp"
+	"math"
+	"math/big"
+	"slices"
+
+	"github.com/a1sarpi/gocalc/src/bigmath"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// Summary describes a list of numbers with every statistics function.
+type Summary struct {
+	Count  int
+	Sum    float64
+	Mean   float64
+	Median float64
+	Mode   float64
+	Min    float64
+	Max    float64
+	Range  float64
+	// Q1 and Q3 are the 25th and 75th percentiles.
+	Q1 float64
+	Q3 float64
+	// Var and StdDev are the sample variance and standard deviation, which
+	// are NaN for a single number. PVar and PStdDev are those of the
+	// population.
+	Var     float64
+	StdDev  float64
+	PVar    float64
+	PStdDev float64
+}
+
+// Summarize computes the Summary of xs, which must not be empty.
+func Summarize(xs []float64) (Summary, error) {
+	if len(xs) == 0 {
+		return Summary{}, ErrNoData
+	}
+	sorted := sortedFloats(xs)
+	s := Summary{
+		Count:   len(xs),
+		Sum:     sum(xs),
+		Mean:    mean(xs),
+		Median:  percentile(sorted, 50),
+		Mode:    mode(xs, cmp.Compare[float64]),
+		Min:     sorted[0],
+		Max:     sorted[len(sorted)-1],
+		Range:   sorted[len(sorted)-1] - sorted[0],
+		Q1:      percentile(sorted, 25),
+		Q3:      percentile(sorted, 75),
+		Var:     math.NaN(),
+		StdDev:  math.NaN(),
+		PVar:    variance(xs, 0),
+		PStdDev: math.Sqrt(variance(xs, 0)),
+	}
+	if len(xs) > 1 {
+		s.Var = variance(xs, 1)
+		s.StdDev = math.Sqrt(s.Var)
+	}
+
+	for _, x := range []float64{s.Sum, s.Mean, s.Range, s.PVar} {
+		if err := checkOverflow(x); err != nil {
+			return Summary{}, err
+		}
+	}
+	return s, nil
+}
+
+// statistic adapts a statistics function to the floatFunction signature.
+func statistic(f func([]float64) float64) floatFunction {
+	return func(args []float64, useRadians bool) float64 {
+		return f(args)
+	}
+}
+
+// sum adds xs with Neumaier's compensated summation, so sum(1e16, 1,
+// -1e16) is 1 rather than 0.
+func sum(xs []float64) float64 {
+	var s, c float64
+	for _, x := range xs {
+		t := s + x
+		if math.Abs(s) >= math.Abs(x) {
+			c += (s - t) + x
+		} else {
+			c += (x - t) + s
+		}
+		s = t
+	}
+	return s + c
+}
+
+func mean(xs []float64) float64 {
+	return sum(xs) / float64(len(xs))
+}
+
+// variance computes the sum of squared deviations from the mean divided
+// by len(xs) - ddof: 1 for the sample variance and 0 for the population.
+func variance(xs []float64, ddof int) float64 {
+	m := mean(xs)
+	d := make([]float64, len(xs))
+	for i, x := range xs {
+		d[i] = (x - m) * (x - m)
+	}
+	return sum(d) / float64(len(xs)-ddof)
+}
+
+// percentile interpolates linearly between the closest ranks of sorted, so
+// the 0th percentile is the minimum, the 50th the median and the 100th the
+// maximum.
+func percentile(sorted []float64, p float64) float64 {
+	h := float64(len(sorted)-1) * p / 100
+	lo := int(h)
+	if lo >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lo] + (h-float64(lo))*(sorted[lo+1]-sorted[lo])
+}
+
+// mode returns the most frequent of xs, the smallest of them on a tie.
+func mode[T any](xs []T, cmp func(a, b T) int) T {
+	sorted := slices.Clone(xs)
+	slices.SortFunc(sorted, cmp)
+	best, count := sorted[0], 0
+	for i := 0; i < len(sorted); {
+		j := i + 1
+		for j < len(sorted) && cmp(sorted[j], sorted[i]) == 0 {
+			j++
+		}
+		if j-i > count {
+			best, count = sorted[i], j-i
+		}
+		i = j
+	}
+	return best
+}
+
+func sortedFloats(xs []float64) []float64 {
+	s := slices.Clone(xs)
+	slices.Sort(s)
+	return s
+}
+
+// ratStatistic evaluates a statistics function exactly, reporting false
+// when the result is not rational or the percentile is out of range.
+func ratStatistic(name string, args []*big.Rat) (*big.Rat, bool) {
+	if name == "percentile" {
+		p := args[0]
+		if p.Sign() < 0 || p.Cmp(big.NewRat(100, 1)) > 0 {
+			return nil, false
+		}
+		return ratPercentile(sortedRats(args[1:]), p), true
+	}
+
+	switch name {
+	case "sum":
+		return ratSum(args), true
+	case "mean":
+		return ratMean(args), true
+	case "median":
+		return ratPercentile(sortedRats(args), big.NewRat(50, 1)), true
+	case "mode":
+		return mode(args, (*big.Rat).Cmp), true
+	case "range":
+		sorted := sortedRats(args)
+		return new(big.Rat).Sub(sorted[len(sorted)-1], sorted[0]), true
+	case "var":
+		return ratVariance(args, 1), true
+	case "pvar":
+		return ratVariance(args, 0), true
+	case "stddev":
+		return ratPow(ratVariance(args, 1), big.NewRat(1, 2))
+	case "pstddev":
+		return ratPow(ratVariance(args, 0), big.NewRat(1, 2))
+	}
+	return nil, false
+}
+
+func ratSum(xs []*big.Rat) *big.Rat {
+	s := new(big.Rat)
+	for _, x := range xs {
+		s.Add(s, x)
+	}
+	return s
+}
+
+func ratMean(xs []*big.Rat) *big.Rat {
+	return new(big.Rat).Quo(ratSum(xs), big.NewRat(int64(len(xs)), 1))
+}
+
+func ratVariance(xs []*big.Rat, ddof int) *big.Rat {
+	m := ratMean(xs)
+	s := new(big.Rat)
+	for _, x := range xs {
+		d := new(big.Rat).Sub(x, m)
+		s.Add(s, d.Mul(d, d))
+	}
+	return s.Quo(s, big.NewRat(int64(len(xs)-ddof), 1))
+}
+
+// ratPercentile interpolates like percentile, for p between 0 and 100.
+func ratPercentile(sorted []*big.Rat, p *big.Rat) *big.Rat {
+	h := new(big.Rat).Mul(big.NewRat(int64(len(sorted)-1), 1), p)
+	h.Quo(h, big.NewRat(100, 1))
+	lo := new(big.Int).Quo(h.Num(), h.Denom())
+	i := int(lo.Int64())
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := h.Sub(h, new(big.Rat).SetInt(lo))
+	d := new(big.Rat).Sub(sorted[i+1], sorted[i])
+	return d.Add(sorted[i], d.Mul(d, frac))
+}
+
+func sortedRats(xs []*big.Rat) []*big.Rat {
+	s := slices.Clone(xs)
+	slices.SortFunc(s, (*big.Rat).Cmp)
+	return s
+}
+
+// bigStatistic evaluates a statistics function with prec bits of mantissa.
+func bigStatistic(name string, args []*big.Float, prec uint, pos int) (*big.Float, error) {
+	if name == "percentile" {
+		p := args[0]
+		if p.Sign() < 0 || p.Cmp(big.NewFloat(100)) > 0 {
+			return nil, bigDomainError(name, p, pos)
+		}
+		return bigPercentile(sortedBigs(args[1:]), p, prec), nil
+	}
+
+	switch name {
+	case "sum":
+		return bigSum(args, prec), nil
+	case "mean":
+		return bigMean(args, prec), nil
+	case "median":
+		return bigPercentile(sortedBigs(args), big.NewFloat(50), prec), nil
+	case "mode":
+		return mode(args, (*big.Float).Cmp), nil
+	case "range":
+		sorted := sortedBigs(args)
+		return new(big.Float).SetPrec(prec).Sub(sorted[len(sorted)-1], sorted[0]), nil
+	case "var":
+		return bigVariance(args, 1, prec), nil
+	case "pvar":
+		return bigVariance(args, 0, prec), nil
+	case "stddev":
+		return bigmath.Sqrt(bigVariance(args, 1, prec), prec), nil
+	case "pstddev":
+		return bigmath.Sqrt(bigVariance(args, 0, prec), prec), nil
+	}
+	return nil, tokenizer.ErrUnknownFunction(name)
+}
+
+func bigSum(xs []*big.Float, prec uint) *big.Float {
+	s := new(big.Float).SetPrec(prec)
+	for _, x := range xs {
+		s.Add(s, x)
+	}
+	return s
+}
+
+func bigMean(xs []*big.Float, prec uint) *big.Float {
+	s := bigSum(xs, prec)
+	return s.Quo(s, new(big.Float).SetInt64(int64(len(xs))))
+}
+
+func bigVariance(xs []*big.Float, ddof int, prec uint) *big.Float {
+	m := bigMean(xs, prec)
+	s := new(big.Float).SetPrec(prec)
+	for _, x := range xs {
+		d := new(big.Float).SetPrec(prec).Sub(x, m)
+		s.Add(s, d.Mul(d, d))
+	}
+	return s.Quo(s, new(big.Float).SetInt64(int64(len(xs)-ddof)))
+}
+
+// bigPercentile interpolates like percentile, for p between 0 and 100.
+func bigPercentile(sorted []*big.Float, p *big.Float, prec uint) *big.Float {
+	h := new(big.Float).SetPrec(prec).SetInt64(int64(len(sorted) - 1))
+	h.Mul(h, p)
+	h.Quo(h, big.NewFloat(100))
+	lo, _ := h.Int64()
+	i := int(lo)
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := h.Sub(h, new(big.Float).SetInt64(lo))
+	d := new(big.Float).SetPrec(prec).Sub(sorted[i+1], sorted[i])
+	return d.Add(sorted[i], d.Mul(d, frac))
+}
+
+func sortedBigs(xs []*big.Float) []*big.Float {
+	s := slices.Clone(xs)
+	slices.SortFunc(s, (*big.Float).Cmp)
+	return s
+}
@@ -0,0 +1,148 @@
+package evaluation_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+func TestStatistics(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+		exact string
+	}{
+		{"sum(1, 2, 3, 4)", 10, "10"},
+		{"sum(1e16, 1, 0 - 1e16)", 1, "1"},
+		{"mean(1, 2, 3, 4)", 2.5, "5/2"},
+		{"median(3, 1, 2)", 2, "2"},
+		{"median(4, 1, 3, 2)", 2.5, "5/2"},
+		{"mode(3, 1, 3, 2, 1)", 1, "1"},
+		{"mode(5)", 5, "5"},
+		{"range(5, 1, 9)", 8, "8"},
+		{"var(1, 2, 3, 4)", 5.0 / 3, "5/3"},
+		{"pvar(1, 2, 3, 4)", 1.25, "5/4"},
+		{"stddev(2, 4, 4, 4, 5, 5, 7, 9)", math.Sqrt(32.0 / 7), ""},
+		{"pstddev(2, 4, 4, 4, 5, 5, 7, 9)", 2, "2"},
+		{"percentile(25, 1, 2, 3, 4, 5)", 2, "2"},
+		{"percentile(10, 1, 2, 3, 4, 5)", 1.4, "7/5"},
+		{"percentile(0, 5, 1)", 1, "1"},
+		{"percentile(100, 5, 1)", 5, "5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rpn := toRPN(t, tt.input)
+			got, err := evaluation.Calculate(rpn, false)
+			if err != nil {
+				t.Fatalf("Calculate(%q) failed: %v", tt.input, err)
+			}
+			if !almostEqual(got, tt.want) {
+				t.Errorf("Calculate(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+
+			big, err := evaluation.CalculateBig(rpn, false, 128)
+			if err != nil {
+				t.Fatalf("CalculateBig(%q) failed: %v", tt.input, err)
+			}
+			if f, _ := big.Float64(); !almostEqual(f, tt.want) {
+				t.Errorf("CalculateBig(%q) = %v, want %v", tt.input, f, tt.want)
+			}
+
+			r, err := evaluation.CalculateRational(rpn, false)
+			if err != nil {
+				t.Fatalf("CalculateRational(%q) failed: %v", tt.input, err)
+			}
+			if r.Exact() != (tt.exact != "") {
+				t.Errorf("CalculateRational(%q) exact = %v, want %v", tt.input, r.Exact(), tt.exact != "")
+			}
+			if tt.exact != "" && r.Value.RatString() != tt.exact {
+				t.Errorf("CalculateRational(%q) = %s, want %s", tt.input, r.Value.RatString(), tt.exact)
+			}
+		})
+	}
+}
+
+func TestStatisticsErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"percentile(-1, 1, 2)", evaluation.ErrDomain},
+		{"percentile(101, 1, 2)", evaluation.ErrDomain},
+		{"sum(1, i)", evaluation.ErrComplexValue},
+	}
+
+	for _, tt := range tests {
+		rpn := toRPN(t, tt.input)
+		if _, err := evaluation.Calculate(rpn, false); !errors.Is(err, tt.want) {
+			t.Errorf("Calculate(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+		if _, err := evaluation.CalculateRational(rpn, false); !errors.Is(err, tt.want) {
+			t.Errorf("CalculateRational(%q) error = %v, want %v", tt.input, err, tt.want)
+		}
+	}
+
+	for _, input := range []string{"var(1)", "stddev(1)", "percentile(50)"} {
+		_, err := evaluateInScope(input, evaluation.NewScope())
+		if code := evaluation.ErrorCode(err); code != "argument_count" {
+			t.Errorf("ErrorCode(%q) = %q, want argument_count", input, code)
+		}
+	}
+}
+
+func TestStatisticsOtherModes(t *testing.T) {
+	z, err := evaluation.CalculateComplex(toRPN(t, "mean(1 + i, 3 - 3i)"), false)
+	if err != nil || z != 2-1i {
+		t.Errorf("CalculateComplex(mean) = %v, %v, want (2-1i)", z, err)
+	}
+	if _, err := evaluation.CalculateComplex(toRPN(t, "median(1, i)"), false); evaluation.ErrorCode(err) != "not_real" {
+		t.Errorf("CalculateComplex(median) error = %v, want not_real", err)
+	}
+
+	n, err := evaluation.CalculateInteger(toRPN(t, "sum(1, 2, 3) + range(4, 9) + mode(7, 2, 7)"), evaluation.Int64)
+	if err != nil || n.Int64() != 18 {
+		t.Errorf("CalculateInteger = %v, %v, want 18", n, err)
+	}
+	if _, err := evaluation.CalculateInteger(toRPN(t, "mean(1, 2)"), evaluation.Int64); evaluation.ErrorCode(err) != "not_integer" {
+		t.Errorf("CalculateInteger(mean) error = %v, want not_integer", err)
+	}
+
+	v, err := evaluation.CalculateMatrix(toRPN(t, "mean([1, 2, 3], [[4, 5], [6, 7]])"), false)
+	if err != nil || evaluation.FormatValue(v) != "4" {
+		t.Errorf("CalculateMatrix(mean) = %v, %v, want 4", v, err)
+	}
+
+	p, err := evaluation.Compile("percentile(p, x, 2 * x, 3 * x)")
+	if err != nil {
+		t.Fatalf("Compile failed: %v", err)
+	}
+	if got, err := p.Eval(map[string]float64{"p": 75, "x": 2}); err != nil || got != 5 {
+		t.Errorf("Eval(percentile) = %v, %v, want 5", got, err)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s, err := evaluation.Summarize([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	if err != nil {
+		t.Fatalf("Summarize failed: %v", err)
+	}
+	want := evaluation.Summary{
+		Count: 8, Sum: 40, Mean: 5, Median: 4.5, Mode: 4, Min: 2, Max: 9, Range: 7,
+		Q1: 4, Q3: 5.5, Var: 32.0 / 7, StdDev: math.Sqrt(32.0 / 7), PVar: 4, PStdDev: 2,
+	}
+	if s != want {
+		t.Errorf("Summarize = %+v, want %+v", s, want)
+	}
+
+	s, err = evaluation.Summarize([]float64{3})
+	if err != nil || !math.IsNaN(s.Var) || !math.IsNaN(s.StdDev) || s.PVar != 0 {
+		t.Errorf("Summarize([3]) = %+v, %v, want NaN sample variance", s, err)
+	}
+
+	if _, err := evaluation.Summarize(nil); !errors.Is(err, evaluation.ErrNoData) {
+		t.Errorf("Summarize(nil) error = %v, want %v", err, evaluation.ErrNoData)
+	}
+}
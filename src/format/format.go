@@ -0,0 +1,207 @@
+// Package format prints calculation results in fixed, scientific,
+// engineering or SI notation.
+package format
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Mode selects the notation of a Formatter.
+type Mode int
+
+const (
+	// Shortest prints the fewest digits that read back as the same value,
+	// switching to an exponent for very large or small magnitudes.
+	Shortest Mode = iota
+	// Fixed prints a fixed number of decimals without an exponent, unless
+	// none of them would be significant.
+	Fixed
+	// Scientific prints one digit before the point and an exponent, 1.5e+03.
+	Scientific
+	// Engineering is like Scientific with the exponent a multiple of 3,
+	// 15e+03.
+	Engineering
+	// SI replaces the exponent of Engineering with a prefix such as k or µ.
+	SI
+)
+
+var modeNames = map[Mode]string{
+	Shortest:    "shortest",
+	Fixed:       "fixed",
+	Scientific:  "sci",
+	Engineering: "eng",
+	SI:          "si",
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode parses the name of a mode: shortest, fixed, sci, eng or si.
+func ParseMode(name string) (Mode, error) {
+	for m, n := range modeNames {
+		if n == name {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown format %q, want shortest, fixed, sci, eng or si", name)
+}
+
+// Formatter prints float64 results.
+type Formatter struct {
+	Mode Mode
+	// Precision is the number of decimals after the point. A negative
+	// precision uses as many as needed to represent the value exactly.
+	// Shortest ignores it.
+	Precision int
+	// Grouping separates thousands in the integer part with commas. In
+	// Shortest mode it keeps values below 1e21 out of exponent form, which
+	// would leave a single digit to group.
+	Grouping bool
+}
+
+// Default prints 15 decimals in fixed notation.
+var Default = Formatter{Mode: Fixed, Precision: 15}
+
+// siPrefixes maps exponents that are multiples of 3 to their SI prefix.
+var siPrefixes = map[int]string{
+	-24: "y", -21: "z", -18: "a", -15: "f", -12: "p", -9: "n", -6: "µ", -3: "m",
+	0: "", 3: "k", 6: "M", 9: "G", 12: "T", 15: "P", 18: "E", 21: "Z", 24: "Y",
+}
+
+// Format prints x according to f.
+func (f Formatter) Format(x float64) string {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	}
+
+	var s string
+	switch f.Mode {
+	case Fixed:
+		s = strconv.FormatFloat(x, 'f', f.Precision, 64)
+		if x != 0 && !strings.ContainsAny(s, "123456789") {
+			// Too small for the decimals, as 1e-20 is for 15: print the
+			// shortest form rather than a zero.
+			s = strconv.FormatFloat(x, 'g', -1, 64)
+		}
+	case Scientific:
+		s = strconv.FormatFloat(x, 'e', f.Precision, 64)
+	case Engineering:
+		mantissa, exp := f.engineering(x)
+		s = mantissa + formatExponent(exp)
+	case SI:
+		mantissa, exp := f.engineering(x)
+		prefix, ok := siPrefixes[exp]
+		if !ok {
+			return mantissa + formatExponent(exp)
+		}
+		s = mantissa + prefix
+	default:
+		if f.Grouping && math.Abs(x) >= 1e6 && math.Abs(x) < 1e21 {
+			s = strconv.FormatFloat(x, 'f', -1, 64)
+		} else {
+			s = strconv.FormatFloat(x, 'g', -1, 64)
+		}
+	}
+
+	if f.Grouping {
+		s = group(s)
+	}
+	return s
+}
+
+// engineering splits x into a mantissa in [1, 1000) and an exponent that is
+// a multiple of 3. The mantissa is built from the decimal digits strconv
+// prints for x, moving the point rather than dividing by a power of ten,
+// which would add rounding error.
+func (f Formatter) engineering(x float64) (string, int) {
+	if x == 0 {
+		return strconv.FormatFloat(0, 'f', f.Precision, 64), 0
+	}
+
+	_, exp := splitExponent(strconv.FormatFloat(x, 'e', -1, 64))
+	for {
+		shift := ((exp % 3) + 3) % 3
+		prec := -1
+		if f.Precision >= 0 {
+			prec = shift + f.Precision
+		}
+		digits, e := splitExponent(strconv.FormatFloat(x, 'e', prec, 64))
+		if e == exp {
+			return shiftPoint(digits, shift), exp - shift
+		}
+		// Rounding carried into the next power of ten, as for 999.99 with
+		// one decimal.
+		exp = e
+	}
+}
+
+// splitExponent splits a number printed in the 'e' format into its
+// mantissa and exponent.
+func splitExponent(s string) (string, int) {
+	mantissa, exp, _ := strings.Cut(s, "e")
+	n, _ := strconv.Atoi(exp)
+	return mantissa, n
+}
+
+// shiftPoint moves the decimal point of mantissa, which has one digit
+// before the point, shift places to the right.
+func shiftPoint(mantissa string, shift int) string {
+	sign := ""
+	if strings.HasPrefix(mantissa, "-") {
+		sign, mantissa = "-", mantissa[1:]
+	}
+	digits := strings.Replace(mantissa, ".", "", 1)
+	for len(digits) < shift+1 {
+		digits += "0"
+	}
+	if len(digits) == shift+1 {
+		return sign + digits
+	}
+	return sign + digits[:shift+1] + "." + digits[shift+1:]
+}
+
+// formatExponent prints exp the way strconv does for the 'e' format.
+func formatExponent(exp int) string {
+	sign := '+'
+	if exp < 0 {
+		sign = '-'
+		exp = -exp
+	}
+	return fmt.Sprintf("e%c%02d", sign, exp)
+}
+
+// group inserts a comma between every three digits of the integer part of
+// the number at the start of s.
+func group(s string) string {
+	start := 0
+	if strings.HasPrefix(s, "-") {
+		start = 1
+	}
+	end := start
+	for end < len(s) && '0' <= s[end] && s[end] <= '9' {
+		end++
+	}
+
+	digits := s[start:end]
+	if len(digits) <= 3 {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(s[:start])
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	b.WriteString(s[end:])
+	return b.String()
+}
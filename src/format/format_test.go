@@ -0,0 +1,69 @@
+package format_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/format"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		f    format.Formatter
+		x    float64
+		want string
+	}{
+		{"default", format.Default, 0.5, "0.500000000000000"},
+		{"shortest", format.Formatter{Mode: format.Shortest}, 1.0 / 3, "0.3333333333333333"},
+		{"shortest tiny", format.Formatter{Mode: format.Shortest}, 1e-20, "1e-20"},
+		{"shortest huge", format.Formatter{Mode: format.Shortest}, 1e300, "1e+300"},
+		{"fixed", format.Formatter{Mode: format.Fixed, Precision: 2}, math.Pi, "3.14"},
+		{"fixed tiny", format.Default, 1e-20, "1e-20"},
+		{"fixed tiny negative", format.Formatter{Mode: format.Fixed, Precision: 2}, -0.001, "-0.001"},
+		{"fixed rounded", format.Formatter{Mode: format.Fixed, Precision: 2}, 0.005, "0.01"},
+		{"fixed exact", format.Formatter{Mode: format.Fixed, Precision: -1}, 2.5, "2.5"},
+		{"scientific", format.Formatter{Mode: format.Scientific, Precision: 3}, 123456, "1.235e+05"},
+		{"scientific shortest", format.Formatter{Mode: format.Scientific, Precision: -1}, 1e-20, "1e-20"},
+		{"engineering", format.Formatter{Mode: format.Engineering, Precision: -1}, 123456, "123.456e+03"},
+		{"engineering small", format.Formatter{Mode: format.Engineering, Precision: 1}, -0.00047, "-470.0e-06"},
+		{"engineering carry", format.Formatter{Mode: format.Engineering, Precision: 1}, 999.99, "1.0e+03"},
+		{"engineering exact", format.Formatter{Mode: format.Engineering, Precision: -1}, -0.00012, "-120e-06"},
+		{"engineering padded", format.Formatter{Mode: format.Engineering, Precision: 2}, 1e5, "100.00e+03"},
+		{"engineering zero", format.Formatter{Mode: format.Engineering, Precision: -1}, 0, "0e+00"},
+		{"si kilo", format.Formatter{Mode: format.SI, Precision: -1}, 4700, "4.7k"},
+		{"si micro", format.Formatter{Mode: format.SI, Precision: 2}, 2.2e-6, "2.20µ"},
+		{"si unit", format.Formatter{Mode: format.SI, Precision: -1}, 12, "12"},
+		{"si out of range", format.Formatter{Mode: format.SI, Precision: -1}, 1e30, "1e+30"},
+		{"grouping", format.Formatter{Mode: format.Fixed, Precision: 2, Grouping: true}, -1234567.891, "-1,234,567.89"},
+		{"grouping short", format.Formatter{Mode: format.Shortest, Grouping: true}, 999, "999"},
+		{"grouping shortest", format.Formatter{Mode: format.Shortest, Grouping: true}, 1234, "1,234"},
+		{"grouping shortest large", format.Formatter{Mode: format.Shortest, Grouping: true}, -1234567.5, "-1,234,567.5"},
+		{"grouping shortest huge", format.Formatter{Mode: format.Shortest, Grouping: true}, 1e300, "1e+300"},
+		{"infinity", format.Default, math.Inf(1), "+Inf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.f.Format(tt.x); got != tt.want {
+				t.Errorf("Format(%v) = %q, want %q", tt.x, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for _, m := range []format.Mode{format.Shortest, format.Fixed, format.Scientific, format.Engineering, format.SI} {
+		got, err := format.ParseMode(m.String())
+		if err != nil {
+			t.Fatalf("ParseMode(%q) failed: %v", m, err)
+		}
+		if got != m {
+			t.Errorf("ParseMode(%q) = %v, want %v", m, got, m)
+		}
+	}
+
+	if _, err := format.ParseMode("hex"); err == nil {
+		t.Error("ParseMode(\"hex\"): expected error but got none")
+	}
+}
@@ -0,0 +1,107 @@
+package parser
+
+import "github.com/a1sarpi/gocalc/src/tokenizer"
+
+// Span is the range of source positions [Start, End) covered by a node.
+type Span struct {
+	Start int
+	End   int
+}
+
+// Node is an element of the syntax tree produced by Parse.
+type Node interface {
+	Span() Span
+	String() string
+}
+
+// Literal is a number such as 42, -1.5 or 1e-9.
+type Literal struct {
+	Value string
+	Pos   int
+}
+
+// Ident is a reference to a constant or a variable.
+type Ident struct {
+	Name     string
+	Pos      int
+	Constant bool
+}
+
+// UnaryExpr is a prefix operator applied to an operand, such as -x, or a
+// postfix one such as x!.
+type UnaryExpr struct {
+	Op    string
+	OpPos int
+	X     Node
+}
+
+// BinaryExpr is an infix operator applied to two operands.
+type BinaryExpr struct {
+	Op    string
+	OpPos int
+	X     Node
+	Y     Node
+}
+
+// Call is a function call. Func is a built-in or user-defined function name.
+type Call struct {
+	Func    string
+	FuncPos int
+	Args    []Node
+	Rparen  int
+}
+
+// Vector is a bracketed list of elements such as [1, 2, 3]. A vector whose
+// elements are vectors of equal length is a matrix, [[1, 2], [3, 4]].
+type Vector struct {
+	Lbrack int
+	Elems  []Node
+	Rbrack int
+}
+
+// Assign stores Value into Target, which is an Ident for a variable or a Call
+// whose arguments are Idents for a function definition.
+type Assign struct {
+	Target Node
+	OpPos  int
+	Value  Node
+}
+
+// Equation is the first argument lhs = rhs of solve. It evaluates to
+// lhs - rhs, which is zero where the equation holds.
+type Equation struct {
+	Lhs   Node
+	OpPos int
+	Rhs   Node
+}
+
+func (n *Literal) Span() Span    { return Span{n.Pos, n.Pos + len(n.Value)} }
+func (n *Ident) Span() Span      { return Span{n.Pos, n.Pos + len(n.Name)} }
+func (n *BinaryExpr) Span() Span { return Span{n.X.Span().Start, n.Y.Span().End} }
+func (n *Call) Span() Span       { return Span{n.FuncPos, n.Rparen + 1} }
+func (n *Vector) Span() Span     { return Span{n.Lbrack, n.Rbrack + 1} }
+func (n *Assign) Span() Span     { return Span{n.Target.Span().Start, n.Value.Span().End} }
+func (n *Equation) Span() Span   { return Span{n.Lhs.Span().Start, n.Rhs.Span().End} }
+
+func (n *UnaryExpr) Span() Span {
+	if tokenizer.PostfixOperators[n.Op] {
+		return Span{n.X.Span().Start, n.OpPos + len(n.Op)}
+	}
+	return Span{n.OpPos, n.X.Span().End}
+}
+
+func (n *Literal) String() string    { return Format(n) }
+func (n *Ident) String() string      { return Format(n) }
+func (n *UnaryExpr) String() string  { return Format(n) }
+func (n *BinaryExpr) String() string { return Format(n) }
+func (n *Call) String() string       { return Format(n) }
+func (n *Vector) String() string     { return Format(n) }
+func (n *Assign) String() string     { return Format(n) }
+func (n *Equation) String() string   { return Format(n) }
+
+func identType(n *Ident) tokenizer.TokenType {
+	if n.Constant {
+		return tokenizer.Constant
+	}
+	return tokenizer.Variable
+}
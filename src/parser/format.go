@@ -0,0 +1,103 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// Format prints n as an infix expression, adding only the parentheses needed
+// to preserve its structure.
+func Format(n Node) string {
+	var b strings.Builder
+	format(&b, n)
+	return b.String()
+}
+
+func format(b *strings.Builder, n Node) {
+	switch n := n.(type) {
+	case *Literal:
+		b.WriteString(n.Value)
+
+	case *Ident:
+		b.WriteString(n.Name)
+
+	case *UnaryExpr:
+		if tokenizer.PostfixOperators[n.Op] {
+			formatOperand(b, n.X, operandPrecedence(n.X) < primaryPrecedence || isNegative(n.X))
+			b.WriteString(n.Op)
+			break
+		}
+		b.WriteString(n.Op)
+		formatOperand(b, n.X, operandPrecedence(n.X) <= unaryPrecedence || isNegative(n.X))
+
+	case *BinaryExpr:
+		prec := precedence[n.Op]
+		formatOperand(b, n.X, operandPrecedence(n.X) < prec)
+		b.WriteString(" " + n.Op + " ")
+		formatOperand(b, n.Y, operandPrecedence(n.Y) <= prec)
+
+	case *Call:
+		b.WriteString(n.Func)
+		b.WriteByte('(')
+		for i, arg := range n.Args {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			format(b, arg)
+		}
+		b.WriteByte(')')
+
+	case *Vector:
+		b.WriteByte('[')
+		for i, elem := range n.Elems {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			format(b, elem)
+		}
+		b.WriteByte(']')
+
+	case *Assign:
+		format(b, n.Target)
+		b.WriteString(" = ")
+		format(b, n.Value)
+
+	case *Equation:
+		format(b, n.Lhs)
+		b.WriteString(" = ")
+		format(b, n.Rhs)
+	}
+}
+
+func formatOperand(b *strings.Builder, n Node, parens bool) {
+	if parens {
+		b.WriteByte('(')
+	}
+	format(b, n)
+	if parens {
+		b.WriteByte(')')
+	}
+}
+
+// primaryPrecedence is how tightly literals, names and calls bind.
+const primaryPrecedence = 100
+
+// operandPrecedence returns how tightly n binds when used as an operand.
+func operandPrecedence(n Node) int {
+	switch n := n.(type) {
+	case *BinaryExpr:
+		return precedence[n.Op]
+	case *UnaryExpr:
+		if tokenizer.PostfixOperators[n.Op] {
+			return postfixPrecedence
+		}
+		return unaryPrecedence
+	}
+	return primaryPrecedence
+}
+
+func isNegative(n Node) bool {
+	l, ok := n.(*Literal)
+	return ok && strings.HasPrefix(l.Value, "-")
+}
@@ -0,0 +1,285 @@
+package parser
+
+import "github.com/a1sarpi/gocalc/src/tokenizer"
+
+// precedence of the binary operators. All of them are left-associative.
+// The bitwise operators bind more loosely than arithmetic, so 1 + 2 << 3
+// is (1 + 2) << 3.
+var precedence = map[string]int{
+	"|":   1,
+	"xor": 2,
+	"&":   3,
+	"<<":  4,
+	">>":  4,
+	"+":   5,
+	"-":   5,
+	"*":   6,
+	".*":  6,
+	"/":   6,
+	"//":  6,
+	"%":   6,
+	"^":   8,
+}
+
+// unaryPrecedence places prefix minus and bitwise not between
+// multiplication and exponentiation, so -x^2 is -(x^2).
+const unaryPrecedence = 7
+
+// postfixPrecedence places the factorials above every other operator, so
+// -3! is -(3!) and 2^3! is 2^(3!).
+const postfixPrecedence = 9
+
+type parser struct {
+	tokens []tokenizer.Token
+	pos    int
+}
+
+// Parse builds the syntax tree of a tokenized statement: an expression, an
+// assignment "x = expr" or a function definition "f(x, y) = expr".
+func Parse(tokens []tokenizer.Token) (Node, error) {
+	if len(tokens) == 0 {
+		return nil, tokenizer.ErrInvalidRPNSyntax(0)
+	}
+
+	p := &parser{tokens: tokens}
+	n, err := p.parseStatement()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok {
+		if t.Type == tokenizer.RightBrace {
+			return nil, tokenizer.ErrMismatchedParentheses(t.Pos)
+		}
+		return nil, tokenizer.ErrInvalidRPNSyntax(t.Pos)
+	}
+	return n, nil
+}
+
+// ParseString tokenizes input with the given scope, which may be nil, and
+// parses the result.
+func ParseString(input string, scope tokenizer.Scope) (Node, error) {
+	tokens, err := tokenizer.TokenizeWithScope(input, scope)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(tokens)
+}
+
+func (p *parser) parseStatement() (Node, error) {
+	left, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := p.peek()
+	if !ok || t.Type != tokenizer.Assign {
+		return left, nil
+	}
+	p.pos++
+	if !isAssignTarget(left) {
+		return nil, tokenizer.ErrInvalidAssignment(t.Pos)
+	}
+	value, err := p.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+	return &Assign{Target: left, OpPos: t.Pos, Value: value}, nil
+}
+
+func (p *parser) parseBinary(minPrec int) (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t, ok := p.peek()
+		if !ok || t.Type != tokenizer.Operator {
+			return left, nil
+		}
+		prec, ok := precedence[t.Value]
+		if !ok {
+			return nil, tokenizer.ErrInvalidRPNSyntax(t.Pos)
+		}
+		if prec < minPrec {
+			return left, nil
+		}
+		p.pos++
+
+		right, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: t.Value, OpPos: t.Pos, X: left, Y: right}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	t, ok := p.peek()
+	if ok && t.Type == tokenizer.Operator && (t.Value == "-" || t.Value == "~") {
+		p.pos++
+		x, err := p.parseBinary(unaryPrecedence + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: t.Value, OpPos: t.Pos, X: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (Node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t, ok := p.peek()
+		if !ok || t.Type != tokenizer.PostfixOperator {
+			return x, nil
+		}
+		p.pos++
+		x = &UnaryExpr{Op: t.Value, OpPos: t.Pos, X: x}
+	}
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t, ok := p.next()
+	if !ok {
+		return nil, tokenizer.ErrInvalidRPNSyntax(p.endPos())
+	}
+
+	switch t.Type {
+	case tokenizer.Number:
+		return &Literal{Value: t.Value, Pos: t.Pos}, nil
+
+	case tokenizer.Constant:
+		return &Ident{Name: t.Value, Pos: t.Pos, Constant: true}, nil
+
+	case tokenizer.Variable:
+		return &Ident{Name: t.Value, Pos: t.Pos}, nil
+
+	case tokenizer.LeftBrace:
+		x, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		if r, ok := p.next(); !ok || r.Type != tokenizer.RightBrace {
+			return nil, tokenizer.ErrMismatchedParentheses(t.Pos)
+		}
+		return x, nil
+
+	case tokenizer.LeftBracket:
+		return p.parseVector(t)
+
+	case tokenizer.Function:
+		return p.parseCall(t)
+	}
+
+	return nil, tokenizer.ErrInvalidRPNSyntax(t.Pos)
+}
+
+func (p *parser) parseVector(lbrack tokenizer.Token) (Node, error) {
+	v := &Vector{Lbrack: lbrack.Pos}
+	for {
+		elem, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		v.Elems = append(v.Elems, elem)
+
+		t, ok := p.next()
+		if !ok {
+			return nil, tokenizer.ErrMismatchedParentheses(lbrack.Pos)
+		}
+		if t.Type == tokenizer.RightBracket {
+			v.Rbrack = t.Pos
+			return v, nil
+		}
+		if t.Type != tokenizer.Comma {
+			return nil, tokenizer.ErrInvalidRPNSyntax(t.Pos)
+		}
+	}
+}
+
+func (p *parser) parseCall(fn tokenizer.Token) (Node, error) {
+	lparen, ok := p.next()
+	if !ok || lparen.Type != tokenizer.LeftBrace {
+		return nil, tokenizer.ErrInvalidRPNSyntax(fn.Pos)
+	}
+
+	call := &Call{Func: fn.Value, FuncPos: fn.Pos}
+	for {
+		arg, err := p.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.peek(); ok && t.Type == tokenizer.Assign && len(call.Args) == 0 && tokenizer.Equations[fn.Value] {
+			p.pos++
+			rhs, err := p.parseBinary(1)
+			if err != nil {
+				return nil, err
+			}
+			arg = &Equation{Lhs: arg, OpPos: t.Pos, Rhs: rhs}
+		}
+		call.Args = append(call.Args, arg)
+
+		t, ok := p.next()
+		if !ok {
+			return nil, tokenizer.ErrMismatchedParentheses(lparen.Pos)
+		}
+		if t.Type == tokenizer.RightBrace {
+			call.Rparen = t.Pos
+			break
+		}
+		if t.Type != tokenizer.Comma {
+			return nil, tokenizer.ErrInvalidRPNSyntax(t.Pos)
+		}
+	}
+
+	if !tokenizer.ArityOf(fn.Value).Accepts(len(call.Args)) {
+		return nil, tokenizer.ErrArgumentCount(fn.Value, fn.Pos)
+	}
+	if tokenizer.Binders[fn.Value] {
+		if id, ok := call.Args[1].(*Ident); !ok || id.Constant {
+			return nil, tokenizer.ErrExpectedVariable(call.Args[1].Span().Start)
+		}
+	}
+	return call, nil
+}
+
+func (p *parser) peek() (tokenizer.Token, bool) {
+	if p.pos >= len(p.tokens) {
+		return tokenizer.Token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) next() (tokenizer.Token, bool) {
+	t, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+	return t, ok
+}
+
+func (p *parser) endPos() int {
+	last := p.tokens[len(p.tokens)-1]
+	return last.Pos + len(last.Value)
+}
+
+// isAssignTarget reports whether n can appear left of '=': a variable, or a
+// call whose arguments are all variables, which defines a function.
+func isAssignTarget(n Node) bool {
+	switch n := n.(type) {
+	case *Ident:
+		return !n.Constant
+	case *Call:
+		for _, arg := range n.Args {
+			if id, ok := arg.(*Ident); !ok || id.Constant {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package parser_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/parser"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+type testScope map[string]tokenizer.TokenType
+
+func (s testScope) HasVariable(name string) bool {
+	return s[name] == tokenizer.Variable
+}
+
+func (s testScope) HasFunction(name string) bool {
+	return s[name] == tokenizer.Function
+}
+
+var scope = testScope{"x": tokenizer.Variable, "y": tokenizer.Variable, "g": tokenizer.Function}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1+2*3", "1 + 2 * 3"},
+		{"(1+2)*3", "(1 + 2) * 3"},
+		{"((1 - 2)) - 3", "1 - 2 - 3"},
+		{"1 - (2 - 3)", "1 - (2 - 3)"},
+		{"2 ^ 3 ^ 2", "2 ^ 3 ^ 2"},
+		{"2 ^ (3 ^ 2)", "2 ^ (3 ^ 2)"},
+		{"-(x + 1)", "-(x + 1)"},
+		{"-x ^ 2", "-x ^ 2"},
+		{"(-x) ^ 2", "(-x) ^ 2"},
+		{"2 * -sin(30)", "2 * -sin(30)"},
+		{"max(1, 2*pi, -3)", "max(1, 2 * pi, -3)"},
+		{"g(x, y) / (x * y)", "g(x, y) / (x * y)"},
+		{"z = x ^ 2", "z = x ^ 2"},
+		{"f(a, b) = a * g(b)", "f(a, b) = a * g(b)"},
+		{"1+2<<3", "1 + 2 << 3"},
+		{"1 << (2 | 3)", "1 << (2 | 3)"},
+		{"(0xF0 | 0x0F) xor 0b11 & ~x", "(0xF0 | 0x0F) xor 0b11 & ~x"},
+		{"~(x & 1)", "~(x & 1)"},
+		{"7 % 3 * 2", "7 % 3 * 2"},
+		{"x // (2 * y) % 3", "x // (2 * y) % 3"},
+		{"solve(x^2=y+1, x)", "solve(x ^ 2 = y + 1, x)"},
+		{"[[1,2],[x,-y]] .* 2", "[[1, 2], [x, -y]] .* 2"},
+		{"det([1+x, 2]*3)", "det([1 + x, 2] * 3)"},
+		{"-x! + 2^3!!", "-x! + 2 ^ 3!!"},
+		{"(x!)! * (-3)!", "(x!)! * (-3)!"},
+		{"(x + 1)!", "(x + 1)!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, err := parser.ParseString(tt.input, scope)
+			if err != nil {
+				t.Fatalf("ParseString(%q) failed: %v", tt.input, err)
+			}
+			if got := n.String(); got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+
+			again, err := parser.ParseString(tt.want, scope)
+			if err != nil {
+				t.Fatalf("ParseString(%q) failed: %v", tt.want, err)
+			}
+			if got := again.String(); got != tt.want {
+				t.Errorf("Format is not stable: %q became %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSpans(t *testing.T) {
+	n, err := parser.ParseString("1 + max(x, 22) * 3", scope)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+
+	sum, ok := n.(*parser.BinaryExpr)
+	if !ok || sum.Op != "+" {
+		t.Fatalf("root = %#v, want + expression", n)
+	}
+	if got, want := sum.Span(), (parser.Span{Start: 0, End: 18}); got != want {
+		t.Errorf("sum span = %v, want %v", got, want)
+	}
+
+	product := sum.Y.(*parser.BinaryExpr)
+	call := product.X.(*parser.Call)
+	if got, want := call.Span(), (parser.Span{Start: 4, End: 14}); got != want {
+		t.Errorf("call span = %v, want %v", got, want)
+	}
+	if got, want := call.Args[1].Span(), (parser.Span{Start: 11, End: 13}); got != want {
+		t.Errorf("argument span = %v, want %v", got, want)
+	}
+}
+
+func TestRPN(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1 + 2 * 3", "1 2 3 * +"},
+		{"(1 + 2) * 3", "1 2 + 3 *"},
+		{"-sin(30) ^ 2", "30 sin 2 ^ -"},
+		{"max(1, 2, 3) + log(2, 8)", "1 2 3 3 max 2 8 2 log +"},
+		{"atan2(y, x)", "y x atan2"},
+		{"g(x)", "x 1 g"},
+		{"z = x - 1", "z x 1 - ="},
+		{"integrate(x^2, x, 0, y)", "3 x 2 ^ 1 x 0 y integrate"},
+		{"solve(x^2 = 4, x, 0, 5)", "5 x 2 ^ 4 - 1 x 0 5 4 solve"},
+		{"[[1, 2], [x, y]] * [x]", "1 2 2 ] x y 2 ] 2 ] x 1 ] *"},
+		{"[1, 2] .* 3 + 1", "1 2 2 ] 3 .* 1 +"},
+		{"-x!", "x ! -"},
+		{"if(x, y + 1, 2)", "x 3 y 1 + 1 2 if"},
+		{"x - 7 // 2 % y", "x 7 2 // y % -"},
+		{"2^3! * y!!", "2 3 ! ^ y !! *"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			n, err := parser.ParseString(tt.input, scope)
+			if err != nil {
+				t.Fatalf("ParseString(%q) failed: %v", tt.input, err)
+			}
+			var values []string
+			for _, token := range parser.RPN(n) {
+				values = append(values, token.Value)
+			}
+			if got := strings.Join(values, " "); got != tt.want {
+				t.Errorf("RPN(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens []tokenizer.Token
+		pos    int
+	}{
+		{
+			name:   "empty input",
+			tokens: nil,
+			pos:    0,
+		},
+		{
+			name: "unclosed parenthesis",
+			tokens: []tokenizer.Token{
+				{Type: tokenizer.LeftBrace, Value: "(", Pos: 0},
+				{Type: tokenizer.Number, Value: "1", Pos: 1},
+			},
+			pos: 0,
+		},
+		{
+			name: "extra closing parenthesis",
+			tokens: []tokenizer.Token{
+				{Type: tokenizer.Number, Value: "1", Pos: 0},
+				{Type: tokenizer.RightBrace, Value: ")", Pos: 1},
+			},
+			pos: 1,
+		},
+		{
+			name: "missing operand",
+			tokens: []tokenizer.Token{
+				{Type: tokenizer.Number, Value: "1", Pos: 0},
+				{Type: tokenizer.Operator, Value: "*", Pos: 2},
+			},
+			pos: 3,
+		},
+		{
+			name: "wrong arity",
+			tokens: []tokenizer.Token{
+				{Type: tokenizer.Number, Value: "1", Pos: 0},
+				{Type: tokenizer.Operator, Value: "+", Pos: 2},
+				{Type: tokenizer.Function, Value: "atan2", Pos: 4},
+				{Type: tokenizer.LeftBrace, Value: "(", Pos: 9},
+				{Type: tokenizer.Number, Value: "1", Pos: 10},
+				{Type: tokenizer.RightBrace, Value: ")", Pos: 11},
+			},
+			pos: 4,
+		},
+		{
+			name: "assignment to expression",
+			tokens: []tokenizer.Token{
+				{Type: tokenizer.Variable, Value: "x", Pos: 0},
+				{Type: tokenizer.Operator, Value: "+", Pos: 2},
+				{Type: tokenizer.Number, Value: "1", Pos: 4},
+				{Type: tokenizer.Assign, Value: "=", Pos: 6},
+				{Type: tokenizer.Number, Value: "2", Pos: 8},
+			},
+			pos: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parser.Parse(tt.tokens)
+			var tokErr *tokenizer.Error
+			if !errors.As(err, &tokErr) {
+				t.Fatalf("Parse() error = %v, want *tokenizer.Error", err)
+			}
+			if tokErr.Pos != tt.pos {
+				t.Errorf("Parse() error position = %d, want %d", tokErr.Pos, tt.pos)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package parser
+
+import (
+	"strconv"
+
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// RPN emits n in reverse Polish notation. A prefix or postfix operator
+// becomes a UnaryOperator token. A call to a function whose arity is not fixed is
+// preceded by a Comma token whose value holds the number of arguments. An
+// assignment keeps its target in infix form, followed by the converted
+// right-hand side and the Assign token. The arguments passed unevaluated, as
+// reported by tokenizer.Quoted, are each preceded by a Quote token holding
+// their length, and an equation
+// lhs = rhs becomes lhs - rhs. A vector becomes its elements followed by a
+// Comma token holding their number and a RightBracket token.
+func RPN(n Node) []tokenizer.Token {
+	var out []tokenizer.Token
+	return appendRPN(out, n)
+}
+
+func appendRPN(out []tokenizer.Token, n Node) []tokenizer.Token {
+	switch n := n.(type) {
+	case *Literal:
+		out = append(out, tokenizer.Token{Type: tokenizer.Number, Value: n.Value, Pos: n.Pos})
+
+	case *Ident:
+		out = append(out, tokenizer.Token{Type: identType(n), Value: n.Name, Pos: n.Pos})
+
+	case *UnaryExpr:
+		out = appendRPN(out, n.X)
+		out = append(out, tokenizer.Token{Type: tokenizer.UnaryOperator, Value: n.Op, Pos: n.OpPos})
+
+	case *BinaryExpr:
+		out = appendRPN(out, n.X)
+		out = appendRPN(out, n.Y)
+		out = append(out, tokenizer.Token{Type: tokenizer.Operator, Value: n.Op, Pos: n.OpPos})
+
+	case *Call:
+		for i, arg := range n.Args {
+			if tokenizer.Quoted(n.Func, i) {
+				quoted := appendRPN(nil, arg)
+				out = append(out, tokenizer.Token{Type: tokenizer.Quote, Value: strconv.Itoa(len(quoted)), Pos: arg.Span().Start})
+				out = append(out, quoted...)
+				continue
+			}
+			out = appendRPN(out, arg)
+		}
+		if !tokenizer.ArityOf(n.Func).Fixed() {
+			out = append(out, tokenizer.Token{Type: tokenizer.Comma, Value: strconv.Itoa(len(n.Args)), Pos: n.FuncPos})
+		}
+		out = append(out, tokenizer.Token{Type: tokenizer.Function, Value: n.Func, Pos: n.FuncPos})
+
+	case *Vector:
+		for _, elem := range n.Elems {
+			out = appendRPN(out, elem)
+		}
+		out = append(out, tokenizer.Token{Type: tokenizer.Comma, Value: strconv.Itoa(len(n.Elems)), Pos: n.Lbrack})
+		out = append(out, tokenizer.Token{Type: tokenizer.RightBracket, Value: "]", Pos: n.Lbrack})
+
+	case *Equation:
+		out = appendRPN(out, n.Lhs)
+		out = appendRPN(out, n.Rhs)
+		out = append(out, tokenizer.Token{Type: tokenizer.Operator, Value: "-", Pos: n.OpPos})
+
+	case *Assign:
+		out = appendTarget(out, n.Target)
+		out = appendRPN(out, n.Value)
+		out = append(out, tokenizer.Token{Type: tokenizer.Assign, Value: "=", Pos: n.OpPos})
+	}
+	return out
+}
+
+func appendTarget(out []tokenizer.Token, target Node) []tokenizer.Token {
+	switch t := target.(type) {
+	case *Ident:
+		out = append(out, tokenizer.Token{Type: tokenizer.Variable, Value: t.Name, Pos: t.Pos})
+
+	case *Call:
+		out = append(out, tokenizer.Token{Type: tokenizer.Function, Value: t.Func, Pos: t.FuncPos})
+		out = append(out, tokenizer.Token{Type: tokenizer.LeftBrace, Value: "(", Pos: t.FuncPos + len(t.Func)})
+		for i, arg := range t.Args {
+			span := arg.Span()
+			if i > 0 {
+				out = append(out, tokenizer.Token{Type: tokenizer.Comma, Value: ",", Pos: span.Start - 1})
+			}
+			out = appendRPN(out, arg)
+		}
+		out = append(out, tokenizer.Token{Type: tokenizer.RightBrace, Value: ")", Pos: t.Rparen})
+	}
+	return out
+}
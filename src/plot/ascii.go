@@ -0,0 +1,100 @@
+package plot
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// markers draw the series of an ASCII chart in turn.
+var markers = []byte{'*', '+', 'o', 'x', '#', '@'}
+
+// ASCII draws series as a chart of opts.Width by opts.Height characters,
+// not counting the axis labels. The axes are drawn through zero when it is
+// in range, and a legend follows when there is more than one series.
+// Consecutive points more than a row apart are joined by a vertical line.
+func ASCII(w io.Writer, series []Series, opts Options) error {
+	width, height := opts.Width, opts.Height
+	if width < 2 || height < 2 {
+		return fmt.Errorf("chart size %dx%d is too small", width, height)
+	}
+	xa := xAxis(series, max(width/10, 1))
+	ya := yAxis(series, opts, max(height/4, 1))
+	col := func(x float64) int { return int(math.Round(xa.scale(x, float64(width-1)))) }
+	row := func(y float64) int { return height - 1 - int(math.Round(ya.scale(y, float64(height-1)))) }
+
+	grid := make([][]byte, height)
+	for r := range grid {
+		grid[r] = []byte(strings.Repeat(" ", width))
+	}
+	if ya.contains(0) {
+		for c := range grid[row(0)] {
+			grid[row(0)][c] = '-'
+		}
+	}
+	if xa.contains(0) {
+		for r := range grid {
+			grid[r][col(0)] = '|'
+		}
+		if ya.contains(0) {
+			grid[row(0)][col(0)] = '+'
+		}
+	}
+
+	for i, s := range series {
+		marker := markers[i%len(markers)]
+		prev := -1
+		for j, x := range s.X {
+			y := s.Y[j]
+			if math.IsNaN(y) || !ya.contains(y) {
+				prev = -1
+				continue
+			}
+			c, r := col(x), row(y)
+			grid[r][c] = marker
+			if prev >= 0 {
+				for k := min(prev, r) + 1; k < max(prev, r); k++ {
+					grid[k][c] = marker
+				}
+			}
+			prev = r
+		}
+	}
+
+	labels := make(map[int]string)
+	labelWidth := 0
+	for _, t := range ya.ticks() {
+		labels[row(t)] = label(t)
+		labelWidth = max(labelWidth, len(label(t)))
+	}
+
+	var b strings.Builder
+	for r, line := range grid {
+		fmt.Fprintf(&b, "%*s |%s\n", labelWidth, labels[r], strings.TrimRight(string(line), " "))
+	}
+	fmt.Fprintf(&b, "%*s +%s\n", labelWidth, "", strings.Repeat("-", width))
+
+	// x labels are centred on their column unless they would overlap.
+	xLabels := []byte(strings.Repeat(" ", labelWidth+2+width+8))
+	end := 0
+	for _, t := range xa.ticks() {
+		text := label(t)
+		start := labelWidth + 2 + col(t) - len(text)/2
+		if start < end || start+len(text) > len(xLabels) {
+			continue
+		}
+		copy(xLabels[start:], text)
+		end = start + len(text) + 1
+	}
+	b.WriteString(strings.TrimRight(string(xLabels), " ") + "\n")
+
+	if len(series) > 1 {
+		for i, s := range series {
+			fmt.Fprintf(&b, "%*s %c %s\n", labelWidth, "", markers[i%len(markers)], s.Label)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,160 @@
+// Package plot samples expressions and draws them as ASCII charts for the
+// terminal or as SVG images.
+package plot
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// Series is a sampled function. Y is NaN where the function is undefined,
+// which leaves a gap in the chart.
+type Series struct {
+	Label string
+	X, Y  []float64
+}
+
+// Options control the size of a chart and the range of its y axis.
+type Options struct {
+	// Width and Height are in characters for ASCII charts and in pixels
+	// for SVG.
+	Width, Height int
+	// YMin and YMax fix the range of the y axis. When they are equal, the
+	// axis is scaled to the data.
+	YMin, YMax float64
+}
+
+// Expression returns expr as a function of the variable name, evaluated by
+// the calculator. Names other than name and the built-in constants and
+// functions are rejected.
+func Expression(expr, name string, useRadians bool) (func(float64) (float64, error), error) {
+	scope := evaluation.NewScope()
+	scope.Set(name, 0)
+	tokens, err := tokenizer.TokenizeWithScope(expr, scope)
+	if err != nil {
+		return nil, err
+	}
+	rpn, err := evaluation.ToRPN(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return func(x float64) (float64, error) {
+		scope.Set(name, x)
+		return evaluation.CalculateInScope(rpn, useRadians, scope)
+	}, nil
+}
+
+// Sample evaluates f at n evenly spaced points from from to to. Points
+// where f returns a domain, overflow or division by zero error become
+// gaps; any other error is returned.
+func Sample(label string, f func(float64) (float64, error), from, to float64, n int) (Series, error) {
+	s := Series{Label: label, X: make([]float64, n), Y: make([]float64, n)}
+	for i := range s.X {
+		x := from
+		if n > 1 {
+			x = from + (to-from)*float64(i)/float64(n-1)
+		}
+		y, err := f(x)
+		if isGap(err) {
+			y = math.NaN()
+		} else if err != nil {
+			return Series{}, err
+		}
+		s.X[i], s.Y[i] = x, y
+	}
+	return s, nil
+}
+
+func isGap(err error) bool {
+	return errors.Is(err, evaluation.ErrDomain) ||
+		errors.Is(err, evaluation.ErrArithmeticOverflow) ||
+		errors.Is(err, evaluation.ErrDivisionByZero)
+}
+
+// axis is the range of an axis with the distance between its ticks.
+type axis struct {
+	lo, hi, step float64
+}
+
+// xAxis spans the x values of series with about n ticks.
+func xAxis(series []Series, n int) axis {
+	lo, hi := bounds(series, func(s Series) []float64 { return s.X })
+	return axis{lo, hi, niceStep((hi - lo) / float64(n))}
+}
+
+// yAxis spans the y values of series, extended to the nearest ticks, with
+// about n ticks, unless opts fixes the range.
+func yAxis(series []Series, opts Options, n int) axis {
+	if opts.YMin != opts.YMax {
+		lo, hi := math.Min(opts.YMin, opts.YMax), math.Max(opts.YMin, opts.YMax)
+		return axis{lo, hi, niceStep((hi - lo) / float64(n))}
+	}
+
+	lo, hi := bounds(series, func(s Series) []float64 { return s.Y })
+	step := niceStep((hi - lo) / float64(n))
+	return axis{math.Floor(lo/step) * step, math.Ceil(hi/step) * step, step}
+}
+
+// bounds returns the smallest and largest finite values of series, or
+// -1 and 1 when there are none. Equal bounds are moved apart by 1.
+func bounds(series []Series, values func(Series) []float64) (float64, float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, s := range series {
+		for _, v := range values(s) {
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+		}
+	}
+	switch {
+	case lo > hi:
+		return -1, 1
+	case lo == hi:
+		return lo - 1, hi + 1
+	}
+	return lo, hi
+}
+
+// niceStep rounds a tick distance up to 1, 2 or 5 times a power of 10.
+func niceStep(raw float64) float64 {
+	if raw <= 0 || math.IsNaN(raw) || math.IsInf(raw, 0) {
+		return 1
+	}
+	p := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*p {
+			return m * p
+		}
+	}
+	return 10 * p
+}
+
+// ticks returns the multiples of step in the axis.
+func (a axis) ticks() []float64 {
+	var ts []float64
+	for i := math.Ceil(a.lo/a.step - 1e-9); i*a.step <= a.hi+a.step*1e-9; i++ {
+		ts = append(ts, i*a.step)
+	}
+	return ts
+}
+
+// scale maps v from the axis to [0, size], which is flipped for y.
+func (a axis) scale(v, size float64) float64 {
+	return (v - a.lo) / (a.hi - a.lo) * size
+}
+
+func (a axis) contains(v float64) bool {
+	return a.lo <= v && v <= a.hi
+}
+
+// label formats a tick value with up to 6 significant digits.
+func label(v float64) string {
+	if v == 0 {
+		return "0"
+	}
+	return strconv.FormatFloat(v, 'g', 6, 64)
+}
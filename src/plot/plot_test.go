@@ -0,0 +1,125 @@
+package plot_test
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/plot"
+)
+
+func sample(t *testing.T, expr string, from, to float64, n int) plot.Series {
+	t.Helper()
+	f, err := plot.Expression(expr, "x", false)
+	if err != nil {
+		t.Fatalf("Expression(%q) failed: %v", expr, err)
+	}
+	s, err := plot.Sample(expr, f, from, to, n)
+	if err != nil {
+		t.Fatalf("Sample(%q) failed: %v", expr, err)
+	}
+	return s
+}
+
+func TestSample(t *testing.T) {
+	s := sample(t, "sqrt(x)", -4, 4, 9)
+	for i, x := range s.X {
+		if x != float64(i-4) {
+			t.Errorf("X[%d] = %v, want %v", i, x, i-4)
+		}
+		switch {
+		case x < 0 && !math.IsNaN(s.Y[i]):
+			t.Errorf("sqrt(%v) = %v, want a gap", x, s.Y[i])
+		case x >= 0 && s.Y[i] != math.Sqrt(x):
+			t.Errorf("sqrt(%v) = %v, want %v", x, s.Y[i], math.Sqrt(x))
+		}
+	}
+
+	s = sample(t, "1 / x + 10^(x*400)", -1, 1, 3)
+	for i, y := range s.Y {
+		if !math.IsNaN(y) != (i == 0) {
+			t.Errorf("Y[%d] = %v, want a gap only at 0 and 1", i, y)
+		}
+	}
+
+	fail := func(float64) (float64, error) { return 0, evaluation.ErrTimeout }
+	if _, err := plot.Sample("slow", fail, 0, 1, 10); !errors.Is(err, evaluation.ErrTimeout) {
+		t.Errorf("Sample with a failing function: error = %v, want ErrTimeout", err)
+	}
+
+	if _, err := plot.Expression("x + y", "x", false); evaluation.ErrorCode(err) != "unknown_symbol" {
+		t.Errorf("Expression(x + y) error = %v, want unknown_symbol", err)
+	}
+}
+
+func TestASCII(t *testing.T) {
+	series := []plot.Series{
+		sample(t, "sqrt(x)", -4, 4, 40),
+		sample(t, "x / 2", -4, 4, 40),
+	}
+	var b strings.Builder
+	if err := plot.ASCII(&b, series, plot.Options{Width: 40, Height: 10}); err != nil {
+		t.Fatalf("ASCII failed: %v", err)
+	}
+	out := b.String()
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+
+	// 10 rows, the x axis, its labels and a legend line per series.
+	if len(lines) != 14 {
+		t.Fatalf("ASCII has %d lines, want 14:\n%s", len(lines), out)
+	}
+	if !strings.HasPrefix(lines[0], " 2 |") || !strings.HasPrefix(lines[9], "-2 |") {
+		t.Errorf("y axis should span -2 to 2:\n%s", out)
+	}
+	for _, label := range []string{"-4", "-2", "0", "2", "4"} {
+		if !strings.Contains(" "+lines[11]+" ", " "+label+" ") {
+			t.Errorf("x labels %q lack %s", lines[11], label)
+		}
+	}
+	if !strings.Contains(lines[12], "* sqrt(x)") || !strings.Contains(lines[13], "+ x / 2") {
+		t.Errorf("legend = %q, want both series", lines[12:])
+	}
+
+	// sqrt is undefined left of the y axis, at column 19 of the chart.
+	for _, line := range lines[:10] {
+		chart := line[strings.Index(line, "|")+1:]
+		if i := strings.IndexByte(chart, '*'); i >= 0 && i < 19 {
+			t.Errorf("sqrt(x) drawn at column %d for x < 0:\n%s", i, out)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	series := []plot.Series{sample(t, "1 / x", -1, 1, 101)}
+	var b strings.Builder
+	if err := plot.SVG(&b, series, plot.Options{Width: 400, Height: 300, YMin: -10, YMax: 10}); err != nil {
+		t.Fatalf("SVG failed: %v", err)
+	}
+
+	polylines := 0
+	dec := xml.NewDecoder(strings.NewReader(b.String()))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("SVG is not well-formed XML: %v\n%s", err, b.String())
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Local == "polyline" {
+			polylines++
+		}
+	}
+	// The gap at x = 0 splits 1 / x in two.
+	if polylines != 2 {
+		t.Errorf("SVG has %d polylines, want 2", polylines)
+	}
+
+	if err := plot.SVG(io.Discard, series, plot.Options{Width: 10, Height: 10}); err == nil {
+		t.Error("SVG of 10x10 pixels succeeded, want an error")
+	}
+}
@@ -0,0 +1,105 @@
+package plot
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+// colors stroke the series of an SVG chart in turn.
+var colors = []string{"#1f77b4", "#d62728", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b"}
+
+// Margins around the plot area of an SVG chart, in pixels.
+const (
+	marginLeft   = 60
+	marginRight  = 20
+	marginTop    = 20
+	marginBottom = 40
+)
+
+// SVG draws series as an SVG image of opts.Width by opts.Height pixels
+// with a grid at the ticks of both axes and a legend. Each run of defined
+// points is drawn as its own polyline, so gaps stay empty.
+func SVG(w io.Writer, series []Series, opts Options) error {
+	width, height := opts.Width, opts.Height
+	plotWidth := float64(width - marginLeft - marginRight)
+	plotHeight := float64(height - marginTop - marginBottom)
+	if plotWidth < 1 || plotHeight < 1 {
+		return fmt.Errorf("chart size %dx%d is too small", width, height)
+	}
+	xa := xAxis(series, max(width/100, 1))
+	ya := yAxis(series, opts, max(height/60, 1))
+	px := func(x float64) float64 { return marginLeft + xa.scale(x, plotWidth) }
+	py := func(y float64) float64 { return marginTop + plotHeight - ya.scale(y, plotHeight) }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="white"/>`+"\n", width, height)
+	fmt.Fprintf(&b, `<clipPath id="plot"><rect x="%d" y="%d" width="%g" height="%g"/></clipPath>`+"\n",
+		marginLeft, marginTop, plotWidth, plotHeight)
+
+	for _, t := range xa.ticks() {
+		x := px(t)
+		fmt.Fprintf(&b, `<line x1="%.2f" y1="%d" x2="%.2f" y2="%.2f" stroke="%s"/>`+"\n",
+			x, marginTop, x, marginTop+plotHeight, gridColor(t))
+		fmt.Fprintf(&b, `<text x="%.2f" y="%.2f" text-anchor="middle">%s</text>`+"\n",
+			x, marginTop+plotHeight+16, label(t))
+	}
+	for _, t := range ya.ticks() {
+		y := py(t)
+		fmt.Fprintf(&b, `<line x1="%d" y1="%.2f" x2="%.2f" y2="%.2f" stroke="%s"/>`+"\n",
+			marginLeft, y, marginLeft+plotWidth, y, gridColor(t))
+		fmt.Fprintf(&b, `<text x="%d" y="%.2f" text-anchor="end">%s</text>`+"\n",
+			marginLeft-6, y+4, label(t))
+	}
+	fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%g" height="%g" fill="none" stroke="black"/>`+"\n",
+		marginLeft, marginTop, plotWidth, plotHeight)
+
+	for i, s := range series {
+		color := colors[i%len(colors)]
+		var points []string
+		flush := func() {
+			if len(points) > 0 {
+				fmt.Fprintf(&b, `<polyline clip-path="url(#plot)" fill="none" stroke="%s" stroke-width="1.5" points="%s"/>`+"\n",
+					color, strings.Join(points, " "))
+			}
+			points = points[:0]
+		}
+		for j, x := range s.X {
+			y := s.Y[j]
+			if math.IsNaN(y) || math.IsInf(y, 0) {
+				flush()
+				continue
+			}
+			points = append(points, fmt.Sprintf("%.2f,%.2f", px(x), py(clamp(y, ya))))
+		}
+		flush()
+
+		ly := marginTop + 16 + 16*i
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="%s" stroke-width="1.5"/>`+"\n",
+			marginLeft+10, ly-4, marginLeft+30, ly-4, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d">%s</text>`+"\n", marginLeft+36, ly, html.EscapeString(s.Label))
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// gridColor draws the grid lines through zero darker, as the axes.
+func gridColor(t float64) string {
+	if t == 0 {
+		return "#888"
+	}
+	return "#ddd"
+}
+
+// clamp keeps points far outside a fixed y range close to the plot area,
+// where clipping hides them, so renderers are not handed huge coordinates.
+func clamp(y float64, a axis) float64 {
+	margin := a.hi - a.lo
+	return math.Max(a.lo-margin, math.Min(a.hi+margin, y))
+}
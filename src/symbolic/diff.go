@@ -0,0 +1,343 @@
+package symbolic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/parser"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+var ErrNotDifferentiable = func(name string, pos int) error {
+	return &evaluation.Error{
+		Code:    "not_differentiable",
+		Message: fmt.Sprintf("%s is not differentiable (position %d)", name, pos),
+	}
+}
+
+// Derivative differentiates the expression expr with respect to x and
+// returns the derivative in infix form.
+func Derivative(expr, x string, useRadians bool) (string, error) {
+	n, err := Parse(expr)
+	if err != nil {
+		return "", err
+	}
+	d, err := Diff(n, x, useRadians)
+	if err != nil {
+		return "", err
+	}
+	return d.String(), nil
+}
+
+// IsDiff reports whether input calls diff(expr, x), as in diff(x^2, x) or
+// 2 * diff(x^2, x), which the calculator prints symbolically instead of
+// evaluating. Input that starts with diff but does not parse is reported
+// too, so the error is that of the call.
+func IsDiff(input string) bool {
+	n, err := Parse(input)
+	if err != nil {
+		name, rest, ok := strings.Cut(input, "(")
+		return ok && strings.TrimSpace(name) == "diff" && rest != ""
+	}
+	return hasDiff(n)
+}
+
+// hasDiff reports whether n contains a call of diff.
+func hasDiff(n parser.Node) bool {
+	switch n := n.(type) {
+	case *parser.UnaryExpr:
+		return hasDiff(n.X)
+	case *parser.BinaryExpr:
+		return hasDiff(n.X) || hasDiff(n.Y)
+	case *parser.Assign:
+		return hasDiff(n.Value)
+	case *parser.Call:
+		if n.Func == "diff" {
+			return true
+		}
+		for _, arg := range n.Args {
+			if hasDiff(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Expand replaces every call diff(expr, x) in n by the derivative of expr
+// with respect to x and simplifies the result, so 2 * diff(x^2, x) is
+// 4 * x.
+func Expand(n parser.Node, useRadians bool) (parser.Node, error) {
+	d := differ{useRadians: useRadians}
+	n, err := d.expand(n)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(n, useRadians), nil
+}
+
+// Diff returns the derivative of n with respect to the variable x,
+// simplified so that diff(x^2/4, x) is 0.5 * x. Angles are in degrees unless
+// useRadians, so the derivatives of the trigonometric functions carry a
+// factor of pi / 180 or 180 / pi.
+func Diff(n parser.Node, x string, useRadians bool) (parser.Node, error) {
+	d := differ{x: x, useRadians: useRadians}
+	n, err := d.expand(n)
+	if err != nil {
+		return nil, err
+	}
+	dn, err := d.diff(n)
+	if err != nil {
+		return nil, err
+	}
+	return Simplify(dn, useRadians), nil
+}
+
+type differ struct {
+	x          string
+	useRadians bool
+}
+
+func (d differ) diff(n parser.Node) (parser.Node, error) {
+	switch n := n.(type) {
+	case *parser.Literal:
+		return num(0), nil
+
+	case *parser.Ident:
+		if !n.Constant && n.Name == d.x {
+			return num(1), nil
+		}
+		return num(0), nil
+
+	case *parser.UnaryExpr:
+		if n.Op != "-" {
+			return nil, ErrNotDifferentiable(n.Op, n.OpPos)
+		}
+		dx, err := d.diff(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return neg(dx), nil
+
+	case *parser.BinaryExpr:
+		return d.diffBinary(n)
+
+	case *parser.Call:
+		return d.diffCall(n)
+
+	case *parser.Assign:
+		return nil, ErrNotDifferentiable("=", n.OpPos)
+	}
+	return nil, fmt.Errorf("unexpected node %T", n)
+}
+
+func (d differ) diffBinary(n *parser.BinaryExpr) (parser.Node, error) {
+	u, v := n.X, n.Y
+	du, err := d.diff(u)
+	if err != nil {
+		return nil, err
+	}
+	dv, err := d.diff(v)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.Op {
+	case "+":
+		return add(du, dv), nil
+	case "-":
+		return sub(du, dv), nil
+	case "*":
+		return add(mul(du, v), mul(u, dv)), nil
+	case "/":
+		if !d.dependsOn(v) {
+			return div(du, v), nil
+		}
+		return div(sub(mul(du, v), mul(u, dv)), pow(v, num(2))), nil
+	case "^":
+		return d.diffPow(u, v, du, dv), nil
+	}
+	return nil, ErrNotDifferentiable(n.Op, n.OpPos)
+}
+
+// diffPow differentiates u ^ v, using the power rule when the exponent is
+// constant and the rule for exponentials when the base is.
+func (d differ) diffPow(u, v, du, dv parser.Node) parser.Node {
+	switch {
+	case !d.dependsOn(v):
+		return mul(mul(v, pow(u, sub(v, num(1)))), du)
+	case !d.dependsOn(u):
+		return mul(mul(pow(u, v), logOf(u)), dv)
+	}
+	return mul(pow(u, v), add(mul(dv, logOf(u)), div(mul(v, du), u)))
+}
+
+func (d differ) diffCall(n *parser.Call) (parser.Node, error) {
+	args := n.Args
+	dargs := make([]parser.Node, len(args))
+	for i, arg := range args {
+		da, err := d.diff(arg)
+		if err != nil {
+			return nil, err
+		}
+		dargs[i] = da
+	}
+	u, du := args[0], dargs[0]
+
+	switch n.Func {
+	case "sin":
+		return mul(du, d.perDegree(call("cos", u))), nil
+	case "cos":
+		return neg(mul(du, d.perDegree(call("sin", u)))), nil
+	case "tan", "tg":
+		return mul(du, d.perDegree(div(num(1), pow(call("cos", u), num(2))))), nil
+	case "ctg":
+		return neg(mul(du, d.perDegree(div(num(1), pow(call("sin", u), num(2)))))), nil
+	case "asin":
+		return mul(du, d.inDegrees(div(num(1), call("sqrt", sub(num(1), pow(u, num(2))))))), nil
+	case "acos":
+		return neg(mul(du, d.inDegrees(div(num(1), call("sqrt", sub(num(1), pow(u, num(2)))))))), nil
+	case "atan":
+		return mul(du, d.inDegrees(div(num(1), add(num(1), pow(u, num(2)))))), nil
+	case "atan2":
+		y, x, dy, dx := args[0], args[1], dargs[0], dargs[1]
+		return d.inDegrees(div(sub(mul(x, dy), mul(y, dx)), add(pow(x, num(2)), pow(y, num(2))))), nil
+	case "exp":
+		return mul(du, call("exp", u)), nil
+	case "log":
+		if len(args) == 2 {
+			if !d.dependsOn(args[0]) {
+				return mul(dargs[1], div(num(1), mul(args[1], call("log", args[0])))), nil
+			}
+			// log(b, x) = log(x) / log(b)
+			return d.diff(div(call("log", args[1]), call("log", args[0])))
+		}
+		return div(du, u), nil
+	case "log2":
+		return div(du, mul(u, call("log", num(2)))), nil
+	case "log10":
+		return div(du, mul(u, call("log", num(10)))), nil
+	case "sqrt":
+		return div(du, mul(num(2), call("sqrt", u))), nil
+	case "abs":
+		// The sign of u, undefined at 0 like the derivative itself.
+		return mul(du, div(u, call("abs", u))), nil
+	case "pow":
+		return d.diffPow(args[0], args[1], dargs[0], dargs[1]), nil
+	case "hypot":
+		a, b, da, db := args[0], args[1], dargs[0], dargs[1]
+		return div(add(mul(a, da), mul(b, db)), call("hypot", a, b)), nil
+	case "sum", "mean":
+		result := du
+		for _, da := range dargs[1:] {
+			result = add(result, da)
+		}
+		if n.Func == "mean" {
+			result = div(result, num(float64(len(args))))
+		}
+		return result, nil
+	}
+	return nil, ErrNotDifferentiable(n.Func, n.FuncPos)
+}
+
+// expand replaces the diff calls in n, leaving the rest of it unchanged. A
+// derivative is an expression rather than a number, so it cannot be
+// assigned to a variable.
+func (d differ) expand(n parser.Node) (parser.Node, error) {
+	switch n := n.(type) {
+	case *parser.Assign:
+		if hasDiff(n.Value) {
+			return nil, tokenizer.ErrInvalidAssignment(n.OpPos)
+		}
+	case *parser.UnaryExpr:
+		x, err := d.expand(n.X)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.UnaryExpr{Op: n.Op, OpPos: n.OpPos, X: x}, nil
+
+	case *parser.BinaryExpr:
+		x, err := d.expand(n.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := d.expand(n.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &parser.BinaryExpr{Op: n.Op, OpPos: n.OpPos, X: x, Y: y}, nil
+
+	case *parser.Call:
+		if n.Func == "diff" {
+			return d.expandCall(n)
+		}
+		c := &parser.Call{Func: n.Func, FuncPos: n.FuncPos, Rparen: n.Rparen}
+		for _, arg := range n.Args {
+			a, err := d.expand(arg)
+			if err != nil {
+				return nil, err
+			}
+			c.Args = append(c.Args, a)
+		}
+		return c, nil
+	}
+	return n, nil
+}
+
+// expandCall returns the derivative requested by a call diff(expr, x).
+func (d differ) expandCall(n *parser.Call) (parser.Node, error) {
+	if len(n.Args) != 2 {
+		return nil, tokenizer.ErrArgumentCount(n.Func, n.FuncPos)
+	}
+	x, ok := n.Args[1].(*parser.Ident)
+	if !ok || x.Constant {
+		return nil, tokenizer.ErrExpectedVariable(n.Args[1].Span().Start)
+	}
+	return Diff(n.Args[0], x.Name, d.useRadians)
+}
+
+// perDegree applies the chain-rule factor of a trigonometric function whose
+// argument is in degrees.
+func (d differ) perDegree(n parser.Node) parser.Node {
+	if d.useRadians {
+		return n
+	}
+	return div(mul(n, ident("pi")), num(180))
+}
+
+// inDegrees applies the chain-rule factor of an inverse trigonometric
+// function whose result is in degrees.
+func (d differ) inDegrees(n parser.Node) parser.Node {
+	if d.useRadians {
+		return n
+	}
+	return div(mul(n, num(180)), ident("pi"))
+}
+
+// dependsOn reports whether n refers to the variable being differentiated.
+func (d differ) dependsOn(n parser.Node) bool {
+	switch n := n.(type) {
+	case *parser.Ident:
+		return !n.Constant && n.Name == d.x
+	case *parser.UnaryExpr:
+		return d.dependsOn(n.X)
+	case *parser.BinaryExpr:
+		return d.dependsOn(n.X) || d.dependsOn(n.Y)
+	case *parser.Call:
+		for _, arg := range n.Args {
+			if d.dependsOn(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// logOf returns log(u), which is 1 for the constant e.
+func logOf(u parser.Node) parser.Node {
+	if id, ok := u.(*parser.Ident); ok && id.Constant && id.Name == "e" {
+		return num(1)
+	}
+	return call("log", u)
+}
@@ -0,0 +1,182 @@
+package symbolic_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/parser"
+	"github.com/a1sarpi/gocalc/src/symbolic"
+)
+
+func TestDerivative(t *testing.T) {
+	tests := []struct {
+		expr       string
+		useRadians bool
+		want       string
+	}{
+		{"x^2", true, "2 * x"},
+		{"3*x^3 - 2*x + 1", true, "9 * x ^ 2 - 2"},
+		{"y * x", true, "y"},
+		{"sin(x)", true, "cos(x)"},
+		{"sin(x)", false, "cos(x) * pi / 180"},
+		{"sin(2*x)", false, "cos(2 * x) * pi / 90"},
+		{"tg(x)", true, "1 / cos(x) ^ 2"},
+		{"ctg(x)", true, "-1 / sin(x) ^ 2"},
+		{"ctg(3*x)", true, "-3 / sin(3 * x) ^ 2"},
+		{"asin(x)", false, "180 / (sqrt(1 - x ^ 2) * pi)"},
+		{"exp(x^2)", true, "2 * x * exp(x ^ 2)"},
+		{"log(2, x)", true, "1 / (x * log(2))"},
+		{"1/x", true, "-1 / x ^ 2"},
+		{"x^x", true, "x ^ x * (log(x) + 1)"},
+		{"abs(x)", true, "x / abs(x)"},
+		{"diff(x^3, x)", true, "6 * x"},
+		{"x/3", true, "1 / 3"},
+		{"x^2/4", true, "0.5 * x"},
+		{"x/y", true, "1 / y"},
+		{"3*x^2 - 2*x^2", true, "2 * x"},
+		{"y/x", true, "-(y / x ^ 2)"},
+		{"x*0.1*3", true, "0.3"},
+		{"x*1e300*1e300", true, "1e+300 * 1e300"},
+		{"0.1*x^2 + 0.2*x^2", true, "0.6 * x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := symbolic.Derivative(tt.expr, "x", tt.useRadians)
+			if err != nil {
+				t.Fatalf("Derivative(%q) failed: %v", tt.expr, err)
+			}
+			if got != tt.want {
+				t.Errorf("Derivative(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDerivativeNumerically compares derivatives with central differences.
+func TestDerivativeNumerically(t *testing.T) {
+	const x, h = 0.3, 1e-6
+
+	for _, expr := range []string{
+		"x^3 - 2*x",
+		"sin(x) * cos(x)",
+		"tan(x)",
+		"tg(x) + ctg(x)",
+		"asin(x) + acos(x / 2)",
+		"atan(x) + atan2(x, 2)",
+		"log(x) + log2(x) + log10(x) + log(3, x) + log(x + 1, 5)",
+		"exp(2*x) / sqrt(x)",
+		"abs(x - 1) + hypot(x, 2)",
+		"pow(x, 2.5) + 2^x + x^x",
+		"sum(x^2, 3*x, 1) + mean(x, sin(x))",
+	} {
+		for _, useRadians := range []bool{true, false} {
+			f := compile(t, expr, useRadians)
+			d, err := symbolic.Derivative(expr, "x", useRadians)
+			if err != nil {
+				t.Fatalf("Derivative(%q) failed: %v", expr, err)
+			}
+			df := compile(t, d, useRadians)
+
+			want := (eval(t, f, x+h) - eval(t, f, x-h)) / (2 * h)
+			if got := eval(t, df, x); math.Abs(got-want) > 1e-6*math.Max(1, math.Abs(want)) {
+				t.Errorf("d/dx %s = %s = %v at %v (radians %v), want %v", expr, d, got, x, useRadians, want)
+			}
+		}
+	}
+}
+
+func TestDerivativeErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		code string
+	}{
+		{"min(x, 1)", "not_differentiable"},
+		{"diff(x^2, 3)", "expected_variable"},
+		{"x +", "invalid_syntax"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := symbolic.Derivative(tt.expr, "x", true)
+			if code := evaluation.ErrorCode(err); code != tt.code {
+				t.Errorf("Derivative(%q) error = %v (%s), want %s", tt.expr, err, code, tt.code)
+			}
+		})
+	}
+}
+
+func compile(t *testing.T, expr string, useRadians bool) *evaluation.Program {
+	t.Helper()
+	n, err := symbolic.Parse(expr)
+	if err != nil {
+		t.Fatalf("Parse(%q) failed: %v", expr, err)
+	}
+	p, err := evaluation.CompileRPN(parser.RPN(n), useRadians)
+	if err != nil {
+		t.Fatalf("CompileRPN(%q) failed: %v", expr, err)
+	}
+	return p
+}
+
+func eval(t *testing.T, p *evaluation.Program, x float64) float64 {
+	t.Helper()
+	y, err := p.Eval(map[string]float64{"x": x})
+	if err != nil {
+		t.Fatalf("Eval(%v) failed: %v", x, err)
+	}
+	return y
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		input string
+		diff  bool
+		want  string
+	}{
+		{"diff(x^2, x)", true, "2 * x"},
+		{"2 * diff(x^2, x)", true, "4 * x"},
+		{"diff(x^2, x) + 1", true, "2 * x + 1"},
+		{"sqrt(diff(x^3, x))", true, "sqrt(3 * x ^ 2)"},
+		{"diff(x +, x)", true, ""},
+		{"x^2 + 1", false, ""},
+		{"different + 1", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := symbolic.IsDiff(tt.input); got != tt.diff {
+				t.Fatalf("IsDiff(%q) = %v, want %v", tt.input, got, tt.diff)
+			}
+			if tt.want == "" {
+				return
+			}
+			n, err := symbolic.Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+			}
+			d, err := symbolic.Expand(n, true)
+			if err != nil {
+				t.Fatalf("Expand(%q) failed: %v", tt.input, err)
+			}
+			if got := d.String(); got != tt.want {
+				t.Errorf("Expand(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandAssignment(t *testing.T) {
+	n, err := symbolic.Parse("y = diff(x^2, x)")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = symbolic.Expand(n, true)
+	if code := evaluation.ErrorCode(err); code != "invalid_assignment" {
+		t.Errorf("Expand(y = diff(x^2, x)) error = %v (%s), want invalid_assignment", err, code)
+	}
+	if pos, ok := evaluation.ErrorPos(err); !ok || pos != 2 {
+		t.Errorf("ErrorPos(%v) = %d, %v, want 2", err, pos, ok)
+	}
+}
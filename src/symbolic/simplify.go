@@ -0,0 +1,411 @@
+package symbolic
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/parser"
+)
+
+// maxFoldExponent bounds the integer powers folded exactly, so 10^100000
+// does not build a huge rational.
+const maxFoldExponent = 64
+
+// SimplifyString parses expr, simplifies it and returns the result in
+// infix form.
+func SimplifyString(expr string, useRadians bool) (string, error) {
+	n, err := Parse(expr)
+	if err != nil {
+		return "", err
+	}
+	return Simplify(n, useRadians).String(), nil
+}
+
+// Simplify folds constant subexpressions, applies identities such as
+// x * 1 = x, x + 0 = x, x ^ 1 = x and x - x = 0, and collects like terms
+// and factors, combining their numeric coefficients exactly: x + 2*x is
+// 3 * x and x * x / 3 is x ^ 2 / 3. Sums are not expanded, so 2 * (x + 1)
+// stays as it is. Subexpressions that cannot be evaluated exactly, such as
+// 1 / 0 or sqrt(2), are left unchanged.
+func Simplify(n parser.Node, useRadians bool) parser.Node {
+	s := simplifier{useRadians: useRadians}
+	return s.simplify(n)
+}
+
+type simplifier struct {
+	useRadians bool
+}
+
+// term is a product c * f1^e1 * f2^e2 * ... with a rational coefficient.
+type term struct {
+	coef    *big.Rat
+	factors []factor
+}
+
+// factor is a base raised to a rational exponent. Bases are literals that
+// are not rational, names, calls and sums.
+type factor struct {
+	base parser.Node
+	exp  *big.Rat
+}
+
+func (s simplifier) simplify(n parser.Node) parser.Node {
+	switch n := n.(type) {
+	case *parser.UnaryExpr:
+		x := s.simplify(n.X)
+		u := &parser.UnaryExpr{Op: n.Op, OpPos: n.OpPos, X: x}
+		if n.Op != "-" {
+			return u
+		}
+		return s.fold(s.collect(u))
+
+	case *parser.BinaryExpr:
+		x := s.simplify(n.X)
+		y := s.simplify(n.Y)
+		b := &parser.BinaryExpr{Op: n.Op, OpPos: n.OpPos, X: x, Y: y}
+		switch n.Op {
+		case "+", "-", "*", "/", "^":
+			return s.fold(s.collect(b))
+		}
+		return b
+
+	case *parser.Call:
+		c := &parser.Call{Func: n.Func, FuncPos: n.FuncPos, Rparen: n.Rparen}
+		for _, arg := range n.Args {
+			c.Args = append(c.Args, s.simplify(arg))
+		}
+		return s.fold(c)
+
+	case *parser.Assign:
+		return &parser.Assign{Target: n.Target, OpPos: n.OpPos, Value: s.simplify(n.Value)}
+	}
+	return n
+}
+
+// fold evaluates n if it does not refer to any variable. The constants pi
+// and e are kept as they are, as in 2 * pi, except in the arguments of a
+// call such as sin(pi). Folding is exact, so 1 / 3 stays a fraction, and
+// values that have no exact rational form, such as sin(30) or sqrt(2), are
+// left as they are.
+func (s simplifier) fold(n parser.Node) parser.Node {
+	if _, ok := n.(*parser.Literal); ok || hasNames(n, false) {
+		return n
+	}
+	if _, ok := n.(*parser.Call); !ok && hasNames(n, true) {
+		return n
+	}
+	x, err := evaluation.CalculateRational(parser.RPN(n), s.useRadians)
+	if err != nil || !x.Exact() || !fitsFloat(x.Value) {
+		return n
+	}
+	return rat(x.Value)
+}
+
+// collect rewrites a sum or product with like terms and factors combined.
+// n is left as it is when a coefficient does not fit a finite float64, as
+// in 1e300 * 1e300, or when a term that cancels out divides by zero, as in
+// 0 * (1 / 0), which would hide the division.
+func (s simplifier) collect(n parser.Node) parser.Node {
+	var terms []term
+	index := make(map[string]int)
+	for _, t := range s.sum(n) {
+		key := t.key()
+		if i, ok := index[key]; ok {
+			terms[i].coef.Add(terms[i].coef, t.coef)
+			continue
+		}
+		index[key] = len(terms)
+		terms = append(terms, t)
+	}
+	for _, t := range terms {
+		if !isDecimal(t.coef) && !fitsFloat(t.coef) || t.coef.Sign() == 0 && t.dividesByZero() {
+			return n
+		}
+	}
+
+	// The constant term goes last, as in x + 1, and a positive term first,
+	// as in 1 - x rather than -x + 1.
+	sort.SliceStable(terms, func(i, j int) bool {
+		return terms[i].key() != "" && terms[j].key() == ""
+	})
+	for i, t := range terms {
+		if t.coef.Sign() > 0 {
+			terms = append(append([]term{t}, terms[:i]...), terms[i+1:]...)
+			break
+		}
+	}
+
+	var result parser.Node
+	for _, t := range terms {
+		if t.coef.Sign() == 0 {
+			continue
+		}
+		switch {
+		case result == nil:
+			result = t.node()
+		case t.coef.Sign() < 0:
+			result = sub(result, term{new(big.Rat).Neg(t.coef), t.factors}.node())
+		default:
+			result = add(result, t.node())
+		}
+	}
+	if result == nil {
+		return num(0)
+	}
+	return result
+}
+
+// sum splits n into the terms added or subtracted at its top level.
+func (s simplifier) sum(n parser.Node) []term {
+	switch n := n.(type) {
+	case *parser.BinaryExpr:
+		switch n.Op {
+		case "+":
+			return append(s.sum(n.X), s.sum(n.Y)...)
+		case "-":
+			return append(s.sum(n.X), negate(s.sum(n.Y))...)
+		}
+	case *parser.UnaryExpr:
+		if n.Op == "-" {
+			return negate(s.sum(n.X))
+		}
+	}
+	return []term{s.product(n)}
+}
+
+// product splits n into a coefficient and the factors multiplied or
+// divided at its top level.
+func (s simplifier) product(n parser.Node) term {
+	switch n := n.(type) {
+	case *parser.Literal:
+		if r, ok := new(big.Rat).SetString(n.Value); ok {
+			return term{coef: r}
+		}
+
+	case *parser.UnaryExpr:
+		if n.Op == "-" {
+			t := s.product(n.X)
+			t.coef.Neg(t.coef)
+			return t
+		}
+
+	case *parser.BinaryExpr:
+		switch n.Op {
+		case "*":
+			return s.product(n.X).times(s.product(n.Y), 1)
+		case "/":
+			if d := s.product(n.Y); d.coef.Sign() != 0 {
+				return s.product(n.X).times(d, -1)
+			}
+		case "^":
+			if k, ok := number(n.Y); ok {
+				return s.power(n.X, k)
+			}
+		}
+	}
+	return term{coef: big.NewRat(1, 1), factors: []factor{{n, big.NewRat(1, 1)}}}
+}
+
+// power returns x ^ k. Integer powers distribute over the factors of x, as
+// in (2 * x) ^ 2 = 4 * x ^ 2; other powers keep x as the base.
+func (s simplifier) power(x parser.Node, k float64) term {
+	exp, ok := new(big.Rat).SetString(strconv.FormatFloat(k, 'g', -1, 64))
+	if !ok {
+		return term{coef: big.NewRat(1, 1), factors: []factor{{pow(x, num(k)), big.NewRat(1, 1)}}}
+	}
+
+	base := s.product(x)
+	if k != float64(int64(k)) || k > maxFoldExponent || k < -maxFoldExponent ||
+		k < 0 && base.coef.Sign() == 0 {
+		// (x ^ 2) ^ 0.5 is abs(x), so only integer powers are distributed.
+		return term{coef: big.NewRat(1, 1), factors: []factor{{x, exp}}}
+	}
+
+	t := term{coef: ratPow(base.coef, int(k))}
+	for _, f := range base.factors {
+		t.factors = append(t.factors, factor{f.base, new(big.Rat).Mul(f.exp, exp)})
+	}
+	return t
+}
+
+// times returns t * u^sign, where sign is 1 or -1, combining the exponents
+// of equal bases.
+func (t term) times(u term, sign int64) term {
+	result := term{coef: new(big.Rat).Set(t.coef)}
+	if sign < 0 {
+		result.coef.Quo(result.coef, u.coef)
+	} else {
+		result.coef.Mul(result.coef, u.coef)
+	}
+
+	result.factors = append(result.factors, t.factors...)
+	for _, f := range u.factors {
+		exp := new(big.Rat).Mul(f.exp, big.NewRat(sign, 1))
+		merged := false
+		for i, g := range result.factors {
+			if equal(g.base, f.base) {
+				result.factors[i].exp = new(big.Rat).Add(g.exp, exp)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			result.factors = append(result.factors, factor{f.base, exp})
+		}
+	}
+	return result
+}
+
+// key identifies the factors of t regardless of their order, so x * y and
+// y * x are like terms.
+func (t term) key() string {
+	var parts []string
+	for _, f := range t.factors {
+		if f.exp.Sign() != 0 {
+			parts = append(parts, parser.Format(f.base)+"^"+f.exp.RatString())
+		}
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// node builds the expression of t, with factors of negative exponent in
+// the denominator.
+func (t term) node() parser.Node {
+	var numer, denom parser.Node = num(1), num(1)
+	for _, f := range t.factors {
+		switch f.exp.Sign() {
+		case 1:
+			numer = mul(numer, pow(f.base, rat(f.exp)))
+		case -1:
+			denom = mul(denom, pow(f.base, rat(new(big.Rat).Neg(f.exp))))
+		}
+	}
+
+	coef := new(big.Rat).Abs(t.coef)
+	if isDecimal(coef) {
+		numer = mul(rat(coef), numer)
+	} else {
+		numer = mul(num(float64FromInt(coef.Num())), numer)
+		denom = mul(num(float64FromInt(coef.Denom())), denom)
+	}
+
+	n := div(numer, denom)
+	if t.coef.Sign() < 0 {
+		// A numeric numerator takes the sign, as in -1 / x ^ 2.
+		if q, ok := n.(*parser.BinaryExpr); ok && q.Op == "/" {
+			if _, ok := number(q.X); ok {
+				return div(neg(q.X), q.Y)
+			}
+		}
+		return neg(n)
+	}
+	return n
+}
+
+// dividesByZero reports whether a factor of t divides by a literal zero.
+func (t term) dividesByZero() bool {
+	for _, f := range t.factors {
+		if dividesByZero(f.base) || f.exp.Sign() < 0 && isNumber(f.base, 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// dividesByZero reports whether n divides by a literal zero, as 1 / 0 and
+// 0 ^ -1 do.
+func dividesByZero(n parser.Node) bool {
+	switch n := n.(type) {
+	case *parser.UnaryExpr:
+		return dividesByZero(n.X)
+	case *parser.BinaryExpr:
+		if n.Op == "/" && isNumber(n.Y, 0) {
+			return true
+		}
+		if k, ok := number(n.Y); ok && n.Op == "^" && k < 0 && isNumber(n.X, 0) {
+			return true
+		}
+		return dividesByZero(n.X) || dividesByZero(n.Y)
+	case *parser.Call:
+		for _, arg := range n.Args {
+			if dividesByZero(arg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func negate(terms []term) []term {
+	for _, t := range terms {
+		t.coef.Neg(t.coef)
+	}
+	return terms
+}
+
+// rat returns the literal for r, which prints in decimal or as a fraction.
+func rat(r *big.Rat) parser.Node {
+	if isDecimal(r) {
+		f, _ := r.Float64()
+		return num(f)
+	}
+	return div(num(float64FromInt(r.Num())), num(float64FromInt(r.Denom())))
+}
+
+// isDecimal reports whether r prints exactly in the shortest float64 form,
+// like 0.5 or 0.1, as opposed to 1/3.
+func isDecimal(r *big.Rat) bool {
+	f, _ := r.Float64()
+	back, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return ok && back.Cmp(r) == 0
+}
+
+// fitsFloat reports whether the numerator and denominator of r are exact
+// in float64, so rat prints r without rounding.
+func fitsFloat(r *big.Rat) bool {
+	return r.Num().IsInt64() && r.Denom().IsInt64() &&
+		math.Abs(float64(r.Num().Int64())) <= 1<<53 && r.Denom().Int64() <= 1<<53
+}
+
+func float64FromInt(x *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(x).Float64()
+	return f
+}
+
+func ratPow(r *big.Rat, k int) *big.Rat {
+	result := big.NewRat(1, 1)
+	base := new(big.Rat).Set(r)
+	if k < 0 {
+		base.Inv(base)
+		k = -k
+	}
+	for ; k > 0; k-- {
+		result.Mul(result, base)
+	}
+	return result
+}
+
+// hasNames reports whether n refers to a variable or, with constants, also
+// to pi or e.
+func hasNames(n parser.Node, constants bool) bool {
+	switch n := n.(type) {
+	case *parser.Ident:
+		return !n.Constant || constants
+	case *parser.UnaryExpr:
+		return hasNames(n.X, constants)
+	case *parser.BinaryExpr:
+		return hasNames(n.X, constants) || hasNames(n.Y, constants)
+	case *parser.Call:
+		for _, arg := range n.Args {
+			if hasNames(arg, constants) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package symbolic_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/symbolic"
+)
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"x * 1", "x"},
+		{"x + 0", "x"},
+		{"x ^ 1", "x"},
+		{"x - x", "0"},
+		{"x * y - y * x", "0"},
+		{"x + 2*x", "3 * x"},
+		{"3*x + 2 - x + 1", "2 * x + 3"},
+		{"-(x - 1)", "1 - x"},
+		{"x * 2 * x", "2 * x ^ 2"},
+		{"x/3 + x/3", "2 * x / 3"},
+		{"0.1*x + 0.2*x", "0.3 * x"},
+		{"a * b / a", "b"},
+		{"(2*x)^2", "4 * x ^ 2"},
+		{"(x+1) * (x+1)", "(x + 1) ^ 2"},
+		{"(x^2)^0.5", "x ^ 2 ^ 0.5"},
+		{"2 * (x + 1)", "2 * (x + 1)"},
+		{"2^10 * x", "1024 * x"},
+		{"sqrt(16) + x", "x + 4"},
+		{"2*pi + pi", "3 * pi"},
+		{"cos(x) * pi / 180 * 2", "cos(x) * pi / 90"},
+		{"1/3*x*3", "x"},
+		{"1/3 + 1/6 + x", "x + 0.5"},
+		{"sin(30) + x", "sin(30) + x"},
+		{"sqrt(2) * 2", "2 * sqrt(2)"},
+		{"1 / 0", "1 / 0"},
+		{"0 * (1 / 0)", "0 * (1 / 0)"},
+		{"x * 0 * (1 / 0)", "0 * (1 / 0)"},
+		{"1/0 - 1/0", "1 / 0 - 1 / 0"},
+		{"0 * 0^-1", "0 * (1 / 0)"},
+		{"x * 0 + 1", "1"},
+		{"1e300 * 1e300", "1e300 * 1e300"},
+		{"1e300 * 1e300 * x", "1e300 * 1e300 * x"},
+		{"2^1000", "2 ^ 1000"},
+		{"1e-300 * 1e-300", "1e-300 * 1e-300"},
+		{"z = x + x", "z = 2 * x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := symbolic.SimplifyString(tt.input, false)
+			if err != nil {
+				t.Fatalf("SimplifyString(%q) failed: %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("SimplifyString(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSimplifyKeepsValue checks that simplification does not change the
+// value of an expression.
+func TestSimplifyKeepsValue(t *testing.T) {
+	for _, expr := range []string{
+		"3*x^2 - x*x + 2*x/4 - 1",
+		"(x + 1) * (x + 1) / (x + 1) - x",
+		"sin(x)^2 * 2 + sin(x) * sin(x)",
+		"x^-1 * x^3 - x * x",
+		"-(x - 2) * -(x - 2)",
+		"2^x * 2^x / 4",
+	} {
+		simplified, err := symbolic.SimplifyString(expr, true)
+		if err != nil {
+			t.Fatalf("SimplifyString(%q) failed: %v", expr, err)
+		}
+		want := eval(t, compile(t, expr, true), 0.7)
+		if got := eval(t, compile(t, simplified, true), 0.7); math.Abs(got-want) > 1e-12 {
+			t.Errorf("%s simplified to %s = %v, want %v", expr, simplified, got, want)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+// Package symbolic transforms parsed expressions: it differentiates them
+// and prints the result back as infix.
+//
+// The builders in this file apply the identities that keep generated
+// expressions readable, such as 0 + x = x and 1 * x = x, and fold
+// operations on two numbers.
+package symbolic
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+
+	"github.com/a1sarpi/gocalc/src/parser"
+)
+
+// symbols resolves every name as a variable, so expressions can be parsed
+// without a scope. diff is the only function besides the built-in ones.
+type symbols struct{}
+
+func (symbols) HasVariable(name string) bool { return true }
+func (symbols) HasFunction(name string) bool { return name == "diff" }
+
+// Parse parses input, treating every name that is not a built-in constant
+// or function as a variable.
+func Parse(input string) (parser.Node, error) {
+	return parser.ParseString(input, symbols{})
+}
+
+func num(x float64) parser.Node {
+	return &parser.Literal{Value: strconv.FormatFloat(x, 'g', -1, 64)}
+}
+
+func ident(name string) parser.Node {
+	return &parser.Ident{Name: name, Constant: name == "pi" || name == "e"}
+}
+
+func call(fn string, args ...parser.Node) parser.Node {
+	return &parser.Call{Func: fn, Args: args}
+}
+
+// number returns the value of n if it is a literal.
+func number(n parser.Node) (float64, bool) {
+	l, ok := n.(*parser.Literal)
+	if !ok {
+		return 0, false
+	}
+	x, err := strconv.ParseFloat(l.Value, 64)
+	return x, err == nil
+}
+
+func isNumber(n parser.Node, x float64) bool {
+	v, ok := number(n)
+	return ok && v == x
+}
+
+func neg(x parser.Node) parser.Node {
+	if v, ok := number(x); ok {
+		return num(-v)
+	}
+	if u, ok := x.(*parser.UnaryExpr); ok && u.Op == "-" {
+		return u.X
+	}
+	return &parser.UnaryExpr{Op: "-", X: x}
+}
+
+func add(x, y parser.Node) parser.Node {
+	if n, ok := foldExact("+", x, y); ok {
+		return n
+	}
+	a, aok := number(x)
+	b, bok := number(y)
+	switch {
+	case aok && a == 0:
+		return y
+	case bok && b == 0:
+		return x
+	case bok && b < 0:
+		return sub(x, num(-b))
+	}
+	if u, ok := y.(*parser.UnaryExpr); ok && u.Op == "-" {
+		return sub(x, u.X)
+	}
+	return &parser.BinaryExpr{Op: "+", X: x, Y: y}
+}
+
+func sub(x, y parser.Node) parser.Node {
+	if n, ok := foldExact("-", x, y); ok {
+		return n
+	}
+	a, aok := number(x)
+	b, bok := number(y)
+	switch {
+	case bok && b == 0:
+		return x
+	case aok && a == 0:
+		return neg(y)
+	}
+	return &parser.BinaryExpr{Op: "-", X: x, Y: y}
+}
+
+func mul(x, y parser.Node) parser.Node {
+	if n, ok := foldExact("*", x, y); ok {
+		return n
+	}
+	a, aok := number(x)
+	b, bok := number(y)
+	switch {
+	case (aok && a == 0 || bok && b == 0) && !dividesByZero(x) && !dividesByZero(y):
+		return num(0)
+	case aok && a == 1:
+		return y
+	case bok && b == 1:
+		return x
+	case aok && a == -1:
+		return neg(y)
+	case bok && b == -1:
+		return neg(x)
+	case bok && !aok:
+		// Numeric factors go first: 2 * x rather than x * 2.
+		return mul(y, x)
+	case aok:
+		if m, ok := y.(*parser.BinaryExpr); ok && m.Op == "*" {
+			if c, ok := foldExact("*", x, m.X); ok {
+				return mul(c, m.Y)
+			}
+		}
+	}
+	if q, ok := y.(*parser.BinaryExpr); ok && q.Op == "/" {
+		return div(mul(x, q.X), q.Y)
+	}
+	if q, ok := x.(*parser.BinaryExpr); ok && q.Op == "/" {
+		return div(mul(q.X, y), q.Y)
+	}
+	if m, ok := y.(*parser.BinaryExpr); ok && m.Op == "*" {
+		return mul(mul(x, m.X), m.Y)
+	}
+	if u, ok := x.(*parser.UnaryExpr); ok && u.Op == "-" {
+		return neg(mul(u.X, y))
+	}
+	if u, ok := y.(*parser.UnaryExpr); ok && u.Op == "-" {
+		return neg(mul(x, u.X))
+	}
+	return &parser.BinaryExpr{Op: "*", X: x, Y: y}
+}
+
+func div(x, y parser.Node) parser.Node {
+	a, aok := number(x)
+	b, bok := number(y)
+	switch {
+	case aok && bok && b != 0 && isExact(a/b):
+		return num(a / b)
+	case aok && a == 0:
+		return num(0)
+	case bok && b == 1:
+		return x
+	case equal(x, y):
+		return num(1)
+	}
+	if q, ok := x.(*parser.BinaryExpr); ok && q.Op == "/" {
+		return div(q.X, mul(q.Y, y))
+	}
+	if q, ok := y.(*parser.BinaryExpr); ok && q.Op == "/" {
+		return div(mul(x, q.Y), q.X)
+	}
+	if u, ok := x.(*parser.UnaryExpr); ok && u.Op == "-" {
+		return neg(div(u.X, y))
+	}
+	return &parser.BinaryExpr{Op: "/", X: x, Y: y}
+}
+
+func pow(x, y parser.Node) parser.Node {
+	a, aok := number(x)
+	b, bok := number(y)
+	switch {
+	case aok && bok && isExact(math.Pow(a, b)):
+		return num(math.Pow(a, b))
+	case bok && b == 0:
+		return num(1)
+	case bok && b == 1:
+		return x
+	}
+	return &parser.BinaryExpr{Op: "^", X: x, Y: y}
+}
+
+// foldExact returns the literal for x op y when x and y are literals,
+// computed with rationals as Simplify folds, so 0.1 * 3 is 0.3 rather than
+// 0.30000000000000004. It reports false when the result does not print
+// exactly, such as one out of the range of float64.
+func foldExact(op string, x, y parser.Node) (parser.Node, bool) {
+	a, aok := literalRat(x)
+	b, bok := literalRat(y)
+	if !aok || !bok {
+		return nil, false
+	}
+	r := new(big.Rat)
+	switch op {
+	case "+":
+		r.Add(a, b)
+	case "-":
+		r.Sub(a, b)
+	case "*":
+		r.Mul(a, b)
+	}
+	if !isDecimal(r) {
+		return nil, false
+	}
+	return rat(r), true
+}
+
+// literalRat returns the exact value of n if it is a literal.
+func literalRat(n parser.Node) (*big.Rat, bool) {
+	l, ok := n.(*parser.Literal)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(l.Value)
+}
+
+// equal reports whether x and y are the same expression.
+func equal(x, y parser.Node) bool {
+	return parser.Format(x) == parser.Format(y)
+}
+
+// isExact reports whether folding produced a value that prints exactly, so
+// 1 / 3 is kept as a fraction rather than becoming 0.3333333333333333.
+func isExact(x float64) bool {
+	return x == math.Trunc(x) && math.Abs(x) < 1e15
+}
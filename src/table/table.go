@@ -0,0 +1,163 @@
+// Package table evaluates an expression over ranges of its variables and
+// prints the results as aligned text, CSV or Markdown.
+package table
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+// MaxRows bounds the number of rows of a table.
+const MaxRows = 1_000_000
+
+// Range steps the variable Name from From to To. To is included when the
+// steps reach it exactly.
+type Range struct {
+	Name     string
+	From, To float64
+	Step     float64
+}
+
+// count returns the number of values in r.
+func (r Range) count() (int, error) {
+	if r.Step == 0 || math.IsNaN(r.Step) || math.IsInf(r.Step, 0) {
+		return 0, fmt.Errorf("step of %s must be a nonzero number", r.Name)
+	}
+	n := (r.To - r.From) / r.Step
+	if n < 0 {
+		return 0, fmt.Errorf("step of %s must go from %v toward %v", r.Name, r.From, r.To)
+	}
+	// The tolerance keeps the last value when rounding leaves n just
+	// below an integer, as for 0 to 1 in steps of 0.1.
+	n = math.Floor(n + 1e-9)
+	if n >= MaxRows {
+		return 0, fmt.Errorf("range of %s has more than %d values", r.Name, MaxRows)
+	}
+	return int(n) + 1, nil
+}
+
+// value returns the ith value of r. When From and Step are short decimals
+// it is computed in integers scaled by a power of 10, so 0 to 1 in steps
+// of 0.1 gives 0.3 rather than 0.30000000000000004.
+func (r Range) value(i int) float64 {
+	scale := math.Pow10(max(decimals(r.From), decimals(r.Step)))
+	from, step := math.Round(r.From*scale), math.Round(r.Step*scale)
+	if scale > 1e15 || math.Abs(from)+float64(i)*math.Abs(step) > 1<<53 {
+		return r.From + float64(i)*r.Step
+	}
+	return (from + float64(i)*step) / scale
+}
+
+// decimals returns the number of digits after the point in the shortest
+// form of x, or more than 15 when it does not have one.
+func decimals(x float64) int {
+	s := strconv.FormatFloat(x, 'f', -1, 64)
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		return len(s) - i - 1
+	}
+	return 0
+}
+
+// Row is a line of a table: the values of the variables, in the order of
+// the ranges, and the value of the expression or the error evaluating it.
+type Row struct {
+	Values []float64
+	Result float64
+	Err    error
+}
+
+// Table is an expression prepared for evaluation over ranges.
+type Table struct {
+	Expr   string
+	Ranges []Range
+
+	counts []int
+	eval   func(vars map[string]float64) (float64, error)
+}
+
+// New tokenizes and compiles expr once for evaluation over ranges. Names
+// other than those of the ranges and the built-in constants and functions
+// are rejected. Expressions that cannot be compiled, such as those calling
+// integrate, are evaluated from their RPN instead.
+func New(expr string, ranges []Range, useRadians bool) (*Table, error) {
+	t := &Table{Expr: expr, Ranges: ranges}
+	rows := 1
+	scope := evaluation.NewScope()
+	for _, r := range ranges {
+		if scope.HasVariable(r.Name) {
+			return nil, fmt.Errorf("variable %s is ranged twice", r.Name)
+		}
+		n, err := r.count()
+		if err != nil {
+			return nil, err
+		}
+		if rows *= n; rows > MaxRows {
+			return nil, fmt.Errorf("table has more than %d rows", MaxRows)
+		}
+		t.counts = append(t.counts, n)
+		scope.Set(r.Name, r.From)
+	}
+
+	tokens, err := tokenizer.TokenizeWithScope(expr, scope)
+	if err != nil {
+		return nil, err
+	}
+	rpn, err := evaluation.ToRPN(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := evaluation.CompileRPN(rpn, useRadians)
+	switch {
+	case err == nil:
+		t.eval = p.Eval
+	case evaluation.ErrorCode(err) == "not_compilable":
+		t.eval = func(vars map[string]float64) (float64, error) {
+			for name, v := range vars {
+				scope.Set(name, v)
+			}
+			return evaluation.CalculateInScope(rpn, useRadians, scope)
+		}
+	default:
+		return nil, err
+	}
+	return t, nil
+}
+
+// Rows returns an iterator over the rows of t, with the last range varying
+// fastest. The Values of a row are only valid until the next row.
+func (t *Table) Rows() iter.Seq[Row] {
+	return func(yield func(Row) bool) {
+		vars := make(map[string]float64, len(t.Ranges))
+		index := make([]int, len(t.Ranges))
+		values := make([]float64, len(t.Ranges))
+		for {
+			for i, r := range t.Ranges {
+				values[i] = r.value(index[i])
+				vars[r.Name] = values[i]
+			}
+			x, err := t.eval(vars)
+			if !yield(Row{Values: values, Result: x, Err: err}) {
+				return
+			}
+
+			// Advance index like an odometer.
+			i := len(index) - 1
+			for ; i >= 0; i-- {
+				if index[i]++; index[i] < t.counts[i] {
+					break
+				}
+				index[i] = 0
+			}
+			if i < 0 {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package table_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/table"
+)
+
+func shortest(x float64) string {
+	return strconv.FormatFloat(x, 'g', -1, 64)
+}
+
+func TestRows(t *testing.T) {
+	tests := []struct {
+		expr   string
+		ranges []table.Range
+		want   []string
+	}{
+		{
+			expr:   "x^2",
+			ranges: []table.Range{{Name: "x", From: 0, To: 1, Step: 0.25}},
+			want:   []string{"0: 0", "0.25: 0.0625", "0.5: 0.25", "0.75: 0.5625", "1: 1"},
+		},
+		{
+			expr:   "x",
+			ranges: []table.Range{{Name: "x", From: 0, To: 0.35, Step: 0.1}},
+			want:   []string{"0: 0", "0.1: 0.1", "0.2: 0.2", "0.3: 0.3"},
+		},
+		{
+			expr:   "x - y",
+			ranges: []table.Range{{Name: "x", From: 1, To: 2, Step: 1}, {Name: "y", From: 3, To: 1, Step: -2}},
+			want:   []string{"1 3: -2", "1 1: 0", "2 3: -1", "2 1: 1"},
+		},
+		{
+			expr:   "sqrt(x)",
+			ranges: []table.Range{{Name: "x", From: -1, To: 1, Step: 1}},
+			want:   []string{"-1: domain", "0: 0", "1: 1"},
+		},
+		{
+			expr:   "integrate(t * x, t, 0, 1)",
+			ranges: []table.Range{{Name: "x", From: 2, To: 4, Step: 2}},
+			want:   []string{"2: 1", "4: 2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			tab, err := table.New(tt.expr, tt.ranges, false)
+			if err != nil {
+				t.Fatalf("New(%q) failed: %v", tt.expr, err)
+			}
+			var got []string
+			for row := range tab.Rows() {
+				var values []string
+				for _, v := range row.Values {
+					values = append(values, shortest(v))
+				}
+				result := shortest(row.Result)
+				if row.Err != nil {
+					result = evaluation.ErrorCode(row.Err)
+				}
+				got = append(got, strings.Join(values, " ")+": "+result)
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("Rows(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowsStop(t *testing.T) {
+	tab, err := table.New("x", []table.Range{{Name: "x", From: 0, To: 100, Step: 1}}, false)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	n := 0
+	for range tab.Rows() {
+		if n++; n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Errorf("iterated %d rows after break, want 3", n)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		expr   string
+		ranges []table.Range
+	}{
+		{"zero step", "x", []table.Range{{Name: "x", From: 0, To: 1, Step: 0}}},
+		{"wrong direction", "x", []table.Range{{Name: "x", From: 0, To: 1, Step: -1}}},
+		{"too many rows", "x", []table.Range{{Name: "x", From: 0, To: 1e9, Step: 1}}},
+		{"ranged twice", "x", []table.Range{{Name: "x", From: 0, To: 1, Step: 1}, {Name: "x", From: 0, To: 1, Step: 1}}},
+		{"unknown variable", "x + y", []table.Range{{Name: "x", From: 0, To: 1, Step: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := table.New(tt.expr, tt.ranges, false); err == nil {
+				t.Errorf("New(%q, %v) succeeded, want an error", tt.expr, tt.ranges)
+			}
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tab, err := table.New("10 / x", []table.Range{{Name: "x", From: 0, To: 10, Step: 5}}, false)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	tests := []struct {
+		style table.Style
+		want  string
+	}{
+		{table.Text, "" +
+			" x            10 / x\n" +
+			"--  ----------------\n" +
+			" 0  division_by_zero\n" +
+			" 5                 2\n" +
+			"10                 1\n"},
+		{table.CSV, "x,10 / x\n0,division_by_zero\n5,2\n10,1\n"},
+		{table.Markdown, "" +
+			"|  x |           10 / x |\n" +
+			"|---:|-----------------:|\n" +
+			"|  0 | division_by_zero |\n" +
+			"|  5 |                2 |\n" +
+			"| 10 |                1 |\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style.String(), func(t *testing.T) {
+			var b strings.Builder
+			if err := tab.Write(&b, tt.style, shortest); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("Write(%v) =\n%s\nwant\n%s", tt.style, got, tt.want)
+			}
+		})
+	}
+
+	for _, name := range []string{"text", "csv", "markdown"} {
+		if s, err := table.ParseStyle(name); err != nil || s.String() != name {
+			t.Errorf("ParseStyle(%q) = %v, %v", name, s, err)
+		}
+	}
+	if _, err := table.ParseStyle("html"); err == nil {
+		t.Error("ParseStyle(html) succeeded, want an error")
+	}
+}
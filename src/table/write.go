@@ -0,0 +1,155 @@
+package table
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+)
+
+// Style selects the output of Write.
+type Style int
+
+const (
+	// Text aligns the columns to the right, separated by two spaces.
+	Text Style = iota
+	// CSV writes comma-separated values as in RFC 4180.
+	CSV
+	// Markdown writes a table with right-aligned columns.
+	Markdown
+)
+
+var styleNames = map[Style]string{
+	Text:     "text",
+	CSV:      "csv",
+	Markdown: "markdown",
+}
+
+func (s Style) String() string {
+	if name, ok := styleNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("Style(%d)", int(s))
+}
+
+// ParseStyle parses the name of a style: text, csv or markdown.
+func ParseStyle(name string) (Style, error) {
+	for s, n := range styleNames {
+		if n == name {
+			return s, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown table style %q, want text, csv or markdown", name)
+}
+
+// Write prints the rows of t in style with a header naming the variables
+// and the expression. Variables are printed in their shortest form and
+// results with format. A row that failed shows the code of its error, such
+// as domain, in place of the result.
+func (t *Table) Write(w io.Writer, style Style, format func(float64) string) error {
+	header := make([]string, 0, len(t.Ranges)+1)
+	for _, r := range t.Ranges {
+		header = append(header, r.Name)
+	}
+	header = append(header, t.Expr)
+
+	if style == CSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+		for row := range t.Rows() {
+			if err := cw.Write(cells(row, format)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	rows := [][]string{header}
+	for row := range t.Rows() {
+		rows = append(rows, cells(row, format))
+	}
+
+	var b strings.Builder
+	switch style {
+	case Text:
+		widths := columnWidths(rows)
+		for i, row := range rows {
+			writeAligned(&b, row, widths, "  ")
+			b.WriteString("\n")
+			if i == 0 {
+				for j, n := range widths {
+					if j > 0 {
+						b.WriteString("  ")
+					}
+					b.WriteString(strings.Repeat("-", n))
+				}
+				b.WriteString("\n")
+			}
+		}
+
+	case Markdown:
+		for _, row := range rows {
+			for j, cell := range row {
+				row[j] = strings.ReplaceAll(cell, "|", `\|`)
+			}
+		}
+		widths := columnWidths(rows)
+		for i, row := range rows {
+			b.WriteString("| ")
+			writeAligned(&b, row, widths, " | ")
+			b.WriteString(" |\n")
+			if i == 0 {
+				b.WriteString("|")
+				for _, n := range widths {
+					b.WriteString(strings.Repeat("-", n+1) + ":|")
+				}
+				b.WriteString("\n")
+			}
+		}
+
+	default:
+		return fmt.Errorf("unknown table style %v", style)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func cells(row Row, format func(float64) string) []string {
+	cells := make([]string, 0, len(row.Values)+1)
+	for _, v := range row.Values {
+		cells = append(cells, strconv.FormatFloat(v, 'g', -1, 64))
+	}
+	if row.Err != nil {
+		return append(cells, evaluation.ErrorCode(row.Err))
+	}
+	return append(cells, format(row.Result))
+}
+
+func columnWidths(rows [][]string) []int {
+	widths := make([]int, len(rows[0]))
+	for _, row := range rows {
+		for j, cell := range row {
+			widths[j] = max(widths[j], utf8.RuneCountInString(cell))
+		}
+	}
+	return widths
+}
+
+// writeAligned writes row right-aligned to widths, joined by sep.
+func writeAligned(b *strings.Builder, row []string, widths []int, sep string) {
+	for j, cell := range row {
+		if j > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(strings.Repeat(" ", widths[j]-utf8.RuneCountInString(cell)))
+		b.WriteString(cell)
+	}
+}
@@ -2,7 +2,10 @@ package tokenizer
 
 import "fmt"
 
+// Error is a syntax error at a position of the input. Code identifies the
+// kind of error and does not change between releases.
 type Error struct {
+	Code    string
 	Message string
 	Pos     int
 }
@@ -12,24 +15,40 @@ func (e *Error) Error() string {
 }
 
 func NewError(msg string, pos int) *Error {
-	return &Error{msg, pos}
+	return &Error{Code: "syntax_error", Message: msg, Pos: pos}
+}
+
+func newError(code, msg string, pos int) *Error {
+	return &Error{Code: code, Message: msg, Pos: pos}
 }
 
 var (
 	ErrUnknownSymbol = func(pos int) *Error {
-		return NewError("Unknown symbol", pos)
+		return newError("unknown_symbol", "Unknown symbol", pos)
 	}
 	ErrInvalidNumber = func(pos int) *Error {
-		return NewError("Invalid number", pos)
+		return newError("invalid_number", "Invalid number", pos)
 	}
 )
 
 var (
 	ErrMismatchedParentheses = func(pos int) *Error {
-		return NewError("Mismatched parentheses", pos)
+		return newError("mismatched_parentheses", "Mismatched parentheses", pos)
 	}
 	ErrInvalidRPNSyntax = func(pos int) *Error {
-		return NewError("Invalid RPN syntax", pos)
+		return newError("invalid_syntax", "Invalid RPN syntax", pos)
+	}
+	ErrArgumentCount = func(fn string, pos int) *Error {
+		return newError("argument_count", fmt.Sprintf("Wrong number of arguments for %s", fn), pos)
+	}
+	ErrInvalidAssignment = func(pos int) *Error {
+		return newError("invalid_assignment", "Invalid assignment", pos)
+	}
+	ErrUndefinedVariable = func(name string, pos int) *Error {
+		return newError("undefined_variable", fmt.Sprintf("Undefined variable %s", name), pos)
+	}
+	ErrExpectedVariable = func(pos int) *Error {
+		return newError("expected_variable", "Expected a variable", pos)
 	}
 )
 
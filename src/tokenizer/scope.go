@@ -0,0 +1,170 @@
+package tokenizer
+
+import "unicode"
+
+// Scope resolves identifiers that are neither built-in constants nor functions.
+type Scope interface {
+	HasVariable(name string) bool
+	HasFunction(name string) bool
+}
+
+// definitionScope extends a Scope while tokenizing a function definition such
+// as "f(x, y) = x^2 + y": the parameters resolve as variables and shadow
+// outer names, and the function may refer to itself.
+type definitionScope struct {
+	Scope
+	name   string
+	params []string
+}
+
+func (d definitionScope) HasVariable(name string) bool {
+	return d.isParam(name) || d.Scope.HasVariable(name)
+}
+
+func (d definitionScope) HasFunction(name string) bool {
+	if d.isParam(name) {
+		return false
+	}
+	return name == d.name || d.Scope.HasFunction(name)
+}
+
+func (d definitionScope) isParam(name string) bool {
+	return contains(d.params, name)
+}
+
+// boundScope extends a Scope, which may be nil, with the variables bound by
+// binders such as integrate(x^2, x, 0, 1).
+type boundScope struct {
+	Scope
+	names []string
+}
+
+func (b boundScope) HasVariable(name string) bool {
+	return contains(b.names, name) || b.Scope != nil && b.Scope.HasVariable(name)
+}
+
+func (b boundScope) HasFunction(name string) bool {
+	return !contains(b.names, name) && b.Scope != nil && b.Scope.HasFunction(name)
+}
+
+// boundNames returns the variables named by the second argument of the
+// binder calls in runes. A second argument that is not a name is reported
+// as ErrExpectedVariable, rather than the first argument failing to
+// resolve the variable it was meant to bind.
+func boundNames(runes []rune) ([]string, error) {
+	var names []string
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) {
+			i++
+			continue
+		}
+		var name string
+		name, i = scanName(runes, i)
+		if !Binders[name] || nextRune(runes, i) != '(' {
+			continue
+		}
+
+		start := skipSpace(runes, i) + 1
+		first := argumentEnd(runes, start)
+		if first >= len(runes) || runes[first] != ',' {
+			continue
+		}
+		slot := skipSpace(runes, first+1)
+		param, end := scanName(runes, slot)
+		if r := nextRune(runes, end); param == "" || r != ',' && r != ')' {
+			return nil, ErrExpectedVariable(slot)
+		}
+		if !contains(names, param) {
+			names = append(names, param)
+		}
+	}
+	return names, nil
+}
+
+// argumentEnd returns the position of the comma or parenthesis that ends
+// the call argument starting at i.
+func argumentEnd(runes []rune, i int) int {
+	depth := 0
+	for ; i < len(runes); i++ {
+		switch runes[i] {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return i
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// definitionHeader recognizes the "name(p1, p2, ...) =" prefix of a function
+// definition and returns the function name and its parameters.
+func definitionHeader(runes []rune) (string, []string, bool) {
+	name, i := scanName(runes, skipSpace(runes, 0))
+	if name == "" || nextRune(runes, i) != '(' {
+		return "", nil, false
+	}
+	i = skipSpace(runes, i) + 1
+
+	var params []string
+	for {
+		var param string
+		param, i = scanName(runes, skipSpace(runes, i))
+		if param == "" {
+			return "", nil, false
+		}
+		params = append(params, param)
+		i = skipSpace(runes, i)
+		if i < len(runes) && runes[i] == ',' {
+			i++
+			continue
+		}
+		break
+	}
+
+	if i >= len(runes) || runes[i] != ')' || nextRune(runes, i+1) != '=' {
+		return "", nil, false
+	}
+	return name, params, true
+}
+
+func scanName(runes []rune, i int) (string, int) {
+	start := i
+	if i >= len(runes) || !unicode.IsLetter(runes[i]) {
+		return "", i
+	}
+	for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+		i++
+	}
+	return string(runes[start:i]), i
+}
+
+func skipSpace(runes []rune, i int) int {
+	for i < len(runes) && unicode.IsSpace(runes[i]) {
+		i++
+	}
+	return i
+}
+
+func nextRune(runes []rune, i int) rune {
+	i = skipSpace(runes, i)
+	if i == len(runes) {
+		return 0
+	}
+	return runes[i]
+}
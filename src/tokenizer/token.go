@@ -1,5 +1,7 @@
 package tokenizer
 
+import "strings"
+
 type TokenType int
 
 const (
@@ -10,6 +12,21 @@ const (
 	RightBrace
 	Comma
 	Constant
+	Variable
+	Assign
+	// UnaryOperator is a prefix operator in RPN output, where Operator
+	// always takes two operands.
+	UnaryOperator
+	// Quote precedes, in RPN output, the tokens of an argument that is
+	// passed to a binder unevaluated. Its value is the number of tokens.
+	Quote
+	// LeftBracket and RightBracket enclose the elements of a vector, as in
+	// [1, 2, 3], or the rows of a matrix, as in [[1, 2], [3, 4]].
+	LeftBracket
+	RightBracket
+	// PostfixOperator follows its operand, as ! in 5! and !! in 7!!. In
+	// RPN output it becomes a UnaryOperator.
+	PostfixOperator
 )
 
 type Token struct {
@@ -19,26 +36,240 @@ type Token struct {
 }
 
 var Operators = map[string]TokenType{
-	"+": Operator,
-	"-": Operator,
-	"*": Operator,
-	"/": Operator,
-	"^": Operator,
+	"+":   Operator,
+	"-":   Operator,
+	"*":   Operator,
+	"/":   Operator,
+	"//":  Operator,
+	"^":   Operator,
+	"%":   Operator,
+	"&":   Operator,
+	"|":   Operator,
+	"xor": Operator,
+	"<<":  Operator,
+	">>":  Operator,
+	"~":   Operator,
+	".*":  Operator,
+}
+
+// MatrixOperators and MatrixFunctions are only defined for vectors and
+// matrices and need matrix mode.
+var MatrixOperators = map[string]bool{
+	".*": true,
+}
+
+var MatrixFunctions = map[string]bool{
+	"transpose": true,
+	"det":       true,
+	"inv":       true,
+	"dot":       true,
+	"cross":     true,
+	"norm":      true,
+}
+
+// PostfixOperators are the factorial ! and the double factorial !!.
+var PostfixOperators = map[string]bool{
+	"!":  true,
+	"!!": true,
+}
+
+// IntegerOperators are only defined for integers and need integer mode.
+var IntegerOperators = map[string]bool{
+	"&":   true,
+	"|":   true,
+	"xor": true,
+	"<<":  true,
+	">>":  true,
+	"~":   true,
 }
 
 var Functions = map[string]TokenType{
 	"sin":   Function,
 	"cos":   Function,
+	"tan":   Function,
 	"tg":    Function,
 	"ctg":   Function,
+	"asin":  Function,
+	"acos":  Function,
+	"atan":  Function,
+	"exp":   Function,
 	"log":   Function,
 	"log2":  Function,
 	"log10": Function,
 	"sqrt":  Function,
 	"abs":   Function,
+	"min":   Function,
+	"max":   Function,
+	"atan2": Function,
+	"hypot": Function,
+	"pow":   Function,
+	"rem":   Function,
+	"mod":   Function,
+	"re":    Function,
+	"im":    Function,
+	"arg":   Function,
+	"conj":  Function,
+
+	"transpose": Function,
+	"det":       Function,
+	"inv":       Function,
+	"dot":       Function,
+	"cross":     Function,
+	"norm":      Function,
+
+	"sum":        Function,
+	"mean":       Function,
+	"median":     Function,
+	"mode":       Function,
+	"range":      Function,
+	"var":        Function,
+	"pvar":       Function,
+	"stddev":     Function,
+	"pstddev":    Function,
+	"percentile": Function,
+
+	"gamma":  Function,
+	"lgamma": Function,
+	"beta":   Function,
+	"nCr":    Function,
+	"nPr":    Function,
+
+	"integrate": Function,
+	"solve":     Function,
+	"root":      Function,
+	"if":        Function,
+}
+
+// Statistics are functions of a list of numbers, given as any number of
+// arguments. var and stddev are those of a sample and pvar and pstddev
+// those of a population; percentile takes the percentile first.
+var Statistics = map[string]bool{
+	"sum":        true,
+	"mean":       true,
+	"median":     true,
+	"mode":       true,
+	"range":      true,
+	"var":        true,
+	"pvar":       true,
+	"stddev":     true,
+	"pstddev":    true,
+	"percentile": true,
+}
+
+// Binders are functions whose second argument names a variable bound in
+// the first, as x in integrate(x^2, x, 0, 1). Both are passed unevaluated.
+var Binders = map[string]bool{
+	"integrate": true,
+	"solve":     true,
+	"root":      true,
+}
+
+// Conditionals evaluate only the branch their condition selects: if(c, a,
+// b) is a when c is not zero and b otherwise. The branches are passed
+// unevaluated, so a recursive function such as f(n) = if(n, n * f(n - 1), 1)
+// ends.
+var Conditionals = map[string]bool{
+	"if": true,
+}
+
+// Quoted reports whether argument i of the named function is passed
+// unevaluated: the body and variable of a binder, and the branches of a
+// conditional.
+func Quoted(name string, i int) bool {
+	switch {
+	case Binders[name]:
+		return i < 2
+	case Conditionals[name]:
+		return i > 0
+	}
+	return false
+}
+
+// Equations are binders whose first argument may be an equation such as
+// x^2 = 2, as in solve(x^2 = 2, x).
+var Equations = map[string]bool{
+	"solve": true,
+}
+
+// Variadic marks an Arity without an upper bound on the argument count.
+const Variadic = -1
+
+// Arity is the accepted number of arguments of a function.
+type Arity struct {
+	Min int
+	Max int
+}
+
+// Fixed reports whether the function always takes the same number of arguments.
+func (a Arity) Fixed() bool {
+	return a.Min == a.Max
+}
+
+// Accepts reports whether n arguments are valid for the function.
+func (a Arity) Accepts(n int) bool {
+	return n >= a.Min && (a.Max == Variadic || n <= a.Max)
+}
+
+// FunctionArity lists functions that do not take exactly one argument.
+var FunctionArity = map[string]Arity{
+	"log":   {1, 2},
+	"min":   {1, Variadic},
+	"max":   {1, Variadic},
+	"atan2": {2, 2},
+	"hypot": {2, 2},
+	"pow":   {2, 2},
+	"rem":   {2, 2},
+	"mod":   {2, 2},
+	"dot":   {2, 2},
+	"cross": {2, 2},
+
+	"sum":        {1, Variadic},
+	"mean":       {1, Variadic},
+	"median":     {1, Variadic},
+	"mode":       {1, Variadic},
+	"range":      {1, Variadic},
+	"var":        {2, Variadic},
+	"pvar":       {1, Variadic},
+	"stddev":     {2, Variadic},
+	"pstddev":    {1, Variadic},
+	"percentile": {2, Variadic},
+
+	"beta": {2, 2},
+	"nCr":  {2, 2},
+	"nPr":  {2, 2},
+
+	"integrate": {4, 4},
+	"solve":     {2, 4},
+	"root":      {3, 3},
+	"if":        {3, 3},
+}
+
+// ArityOf returns the arity of the named function. Functions missing from
+// the Functions map are user-defined and checked when they are called.
+func ArityOf(name string) Arity {
+	if a, ok := FunctionArity[name]; ok {
+		return a
+	}
+	if _, ok := Functions[name]; !ok {
+		return Arity{1, Variadic}
+	}
+	return Arity{1, 1}
 }
 
 var Constants = map[string]TokenType{
 	"pi": Constant,
 	"e":  Constant,
+	"i":  Constant,
+}
+
+// IsImaginary reports whether t is the imaginary unit or an imaginary
+// literal such as 4i.
+func IsImaginary(t Token) bool {
+	switch t.Type {
+	case Constant:
+		return t.Value == "i"
+	case Number:
+		return strings.HasSuffix(t.Value, "i")
+	}
+	return false
 }
@@ -1,6 +1,7 @@
 package tokenizer_test
 
 import (
+	"errors"
 	"testing"
 
 	"github.com/a1sarpi/gocalc/src/tokenizer"
@@ -313,6 +314,139 @@ func TestTokenizeOperations(t *testing.T) {
 				{tokenizer.RightBrace, ")", 10},
 			},
 		},
+		{
+			name:  "function with two arguments",
+			input: "atan2(1, -2)",
+			want: []tokenizer.Token{
+				{tokenizer.Function, "atan2", 0},
+				{tokenizer.LeftBrace, "(", 5},
+				{tokenizer.Number, "1", 6},
+				{tokenizer.Comma, ",", 7},
+				{tokenizer.Number, "-2", 9},
+				{tokenizer.RightBrace, ")", 11},
+			},
+		},
+		{
+			name:  "prefixed integers",
+			input: "0xFF & 0b1010 | -0o17",
+			want: []tokenizer.Token{
+				{tokenizer.Number, "0xFF", 0},
+				{tokenizer.Operator, "&", 5},
+				{tokenizer.Number, "0b1010", 7},
+				{tokenizer.Operator, "|", 14},
+				{tokenizer.Number, "-0o17", 16},
+			},
+		},
+		{
+			name:  "bitwise operators",
+			input: "~1 << 2 xor 8 >> 1 % 3",
+			want: []tokenizer.Token{
+				{tokenizer.Operator, "~", 0},
+				{tokenizer.Number, "1", 1},
+				{tokenizer.Operator, "<<", 3},
+				{tokenizer.Number, "2", 6},
+				{tokenizer.Operator, "xor", 8},
+				{tokenizer.Number, "8", 12},
+				{tokenizer.Operator, ">>", 14},
+				{tokenizer.Number, "1", 17},
+				{tokenizer.Operator, "%", 19},
+				{tokenizer.Number, "3", 21},
+			},
+		},
+		{
+			name:  "floor division",
+			input: "7//2 / mod(pi, 3)",
+			want: []tokenizer.Token{
+				{tokenizer.Number, "7", 0},
+				{tokenizer.Operator, "//", 1},
+				{tokenizer.Number, "2", 3},
+				{tokenizer.Operator, "/", 5},
+				{tokenizer.Function, "mod", 7},
+				{tokenizer.LeftBrace, "(", 10},
+				{tokenizer.Constant, "pi", 11},
+				{tokenizer.Comma, ",", 13},
+				{tokenizer.Number, "3", 15},
+				{tokenizer.RightBrace, ")", 16},
+			},
+		},
+		{
+			name:  "complex literal",
+			input: "3+4i",
+			want: []tokenizer.Token{
+				{tokenizer.Number, "3", 0},
+				{tokenizer.Operator, "+", 1},
+				{tokenizer.Number, "4i", 2},
+			},
+		},
+		{
+			name:  "matrix",
+			input: "[[1, -2], [3, 4]] .* [pi]",
+			want: []tokenizer.Token{
+				{tokenizer.LeftBracket, "[", 0},
+				{tokenizer.LeftBracket, "[", 1},
+				{tokenizer.Number, "1", 2},
+				{tokenizer.Comma, ",", 3},
+				{tokenizer.Number, "-2", 5},
+				{tokenizer.RightBracket, "]", 7},
+				{tokenizer.Comma, ",", 8},
+				{tokenizer.LeftBracket, "[", 10},
+				{tokenizer.Number, "3", 11},
+				{tokenizer.Comma, ",", 12},
+				{tokenizer.Number, "4", 14},
+				{tokenizer.RightBracket, "]", 15},
+				{tokenizer.RightBracket, "]", 16},
+				{tokenizer.Operator, ".*", 18},
+				{tokenizer.LeftBracket, "[", 21},
+				{tokenizer.Constant, "pi", 22},
+				{tokenizer.RightBracket, "]", 24},
+			},
+		},
+		{
+			name:  "minus before power",
+			input: "-2^2 - -1.5e3",
+			want: []tokenizer.Token{
+				{tokenizer.Operator, "-", 0},
+				{tokenizer.Number, "2", 1},
+				{tokenizer.Operator, "^", 2},
+				{tokenizer.Number, "2", 3},
+				{tokenizer.Operator, "-", 5},
+				{tokenizer.Number, "-1.5e3", 7},
+			},
+		},
+		{
+			name:  "imaginary unit before power",
+			input: "4i^2",
+			want: []tokenizer.Token{
+				{tokenizer.Number, "4", 0},
+				{tokenizer.Operator, "*", 1},
+				{tokenizer.Constant, "i", 1},
+				{tokenizer.Operator, "^", 2},
+				{tokenizer.Number, "2", 3},
+			},
+		},
+		{
+			name:  "factorials",
+			input: "-3! + 5!!",
+			want: []tokenizer.Token{
+				{tokenizer.Operator, "-", 0},
+				{tokenizer.Number, "3", 1},
+				{tokenizer.PostfixOperator, "!", 2},
+				{tokenizer.Operator, "+", 4},
+				{tokenizer.Number, "5", 6},
+				{tokenizer.PostfixOperator, "!!", 7},
+			},
+		},
+		{
+			name:  "imaginary unit",
+			input: "-2.5i * 1e3i * i",
+			want: []tokenizer.Token{
+				{tokenizer.Number, "-2.5i", 0},
+				{tokenizer.Operator, "*", 6},
+				{tokenizer.Number, "1e3i", 8},
+				{tokenizer.Operator, "*", 13},
+				{tokenizer.Constant, "i", 15},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -340,6 +474,12 @@ func TestInvalidExpressions(t *testing.T) {
 		{name: "invalid characters", input: "1 @ 2"},
 		{name: "space in number", input: "1 1 + 1"},
 		{name: "complex number", input: "1 + 4j"},
+		{name: "imaginary suffix with letter", input: "4in"},
+		{name: "hex prefix without digits", input: "0x"},
+		{name: "invalid binary digit", input: "0b102"},
+		{name: "invalid hex digit", input: "0xFG"},
+		{name: "single angle bracket", input: "1 < 2"},
+		{name: "dangling xor", input: "1 xor"},
 		{name: "incomplete scientific notation", input: "1.2e"},
 		{name: "scientific notation no exponent", input: "1.2e+"},
 		{name: "scientific notation decimal exponent", input: "1.2e1.2"},
@@ -352,6 +492,18 @@ func TestInvalidExpressions(t *testing.T) {
 		{name: "unknown constant", input: "unknown"},
 		{name: "constant with number", input: "pi2"},
 		{name: "constant with letter", input: "pix"},
+		{name: "comma outside function", input: "1, 2"},
+		{name: "comma in parentheses", input: "max((1, 2))"},
+		{name: "trailing comma", input: "max(1,)"},
+		{name: "leading comma", input: "max(, 1)"},
+		{name: "empty brackets", input: "[]"},
+		{name: "bracket closed by parenthesis", input: "[1, 2)"},
+		{name: "parenthesis closed by bracket", input: "(1, 2]"},
+		{name: "unclosed bracket", input: "[1, 2"},
+		{name: "leading factorial", input: "!5"},
+		{name: "number after factorial", input: "5!3"},
+		{name: "triple slash", input: "7 /// 2"},
+		{name: "elementwise without operand", input: "[1] .*"},
 	}
 
 	for _, tt := range tests {
@@ -364,6 +516,160 @@ func TestInvalidExpressions(t *testing.T) {
 	}
 }
 
+type testScope map[string]tokenizer.TokenType
+
+func (s testScope) HasVariable(name string) bool {
+	t, ok := s[name]
+	return ok && t == tokenizer.Variable
+}
+
+func (s testScope) HasFunction(name string) bool {
+	t, ok := s[name]
+	return ok && t == tokenizer.Function
+}
+
+func TestTokenizeWithScope(t *testing.T) {
+	scope := testScope{"x": tokenizer.Variable, "rate2": tokenizer.Variable, "g": tokenizer.Function}
+	tests := []struct {
+		name  string
+		input string
+		want  []tokenizer.Token
+	}{
+		{
+			name:  "known variables",
+			input: "x * rate2",
+			want: []tokenizer.Token{
+				{tokenizer.Variable, "x", 0},
+				{tokenizer.Operator, "*", 2},
+				{tokenizer.Variable, "rate2", 4},
+			},
+		},
+		{
+			name:  "assignment to new variable",
+			input: "y = -x",
+			want: []tokenizer.Token{
+				{tokenizer.Variable, "y", 0},
+				{tokenizer.Assign, "=", 2},
+				{tokenizer.Operator, "-", 4},
+				{tokenizer.Variable, "x", 5},
+			},
+		},
+		{
+			name:  "user-defined function call",
+			input: "g(x, 2)",
+			want: []tokenizer.Token{
+				{tokenizer.Function, "g", 0},
+				{tokenizer.LeftBrace, "(", 1},
+				{tokenizer.Variable, "x", 2},
+				{tokenizer.Comma, ",", 3},
+				{tokenizer.Number, "2", 5},
+				{tokenizer.RightBrace, ")", 6},
+			},
+		},
+		{
+			name:  "function definition",
+			input: "f(a, x) = a * f(x, g(a))",
+			want: []tokenizer.Token{
+				{tokenizer.Function, "f", 0},
+				{tokenizer.LeftBrace, "(", 1},
+				{tokenizer.Variable, "a", 2},
+				{tokenizer.Comma, ",", 3},
+				{tokenizer.Variable, "x", 5},
+				{tokenizer.RightBrace, ")", 6},
+				{tokenizer.Assign, "=", 8},
+				{tokenizer.Variable, "a", 10},
+				{tokenizer.Operator, "*", 12},
+				{tokenizer.Function, "f", 14},
+				{tokenizer.LeftBrace, "(", 15},
+				{tokenizer.Variable, "x", 16},
+				{tokenizer.Comma, ",", 17},
+				{tokenizer.Function, "g", 19},
+				{tokenizer.LeftBrace, "(", 20},
+				{tokenizer.Variable, "a", 21},
+				{tokenizer.RightBrace, ")", 22},
+				{tokenizer.RightBrace, ")", 23},
+			},
+		},
+		{
+			name:  "parameter shadows function",
+			input: "h(g) = g",
+			want: []tokenizer.Token{
+				{tokenizer.Function, "h", 0},
+				{tokenizer.LeftBrace, "(", 1},
+				{tokenizer.Variable, "g", 2},
+				{tokenizer.RightBrace, ")", 3},
+				{tokenizer.Assign, "=", 5},
+				{tokenizer.Variable, "g", 7},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tokenizer.TokenizeWithScope(tt.input, scope)
+			if err != nil {
+				t.Fatalf("TokenizeWithScope(%q) failed: %v", tt.input, err)
+			}
+			if !compareTokens(got, tt.want) {
+				t.Errorf("TokenizeWithScope(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+
+	for _, input := range []string{"y + 1", "x = ", "1 + y = 2", "x = 1 = 2", "f(x) + 1 = 2", "f(pi) = 1", "f(x, 2) = x", "f(x) = y"} {
+		if _, err := tokenizer.TokenizeWithScope(input, scope); err == nil {
+			t.Errorf("Expected error for input: %q", input)
+		}
+	}
+	if _, err := tokenizer.Tokenize("x = 1"); err == nil {
+		t.Error("Expected error for assignment without scope")
+	}
+}
+
+func TestTokenizeBoundVariables(t *testing.T) {
+	got, err := tokenizer.Tokenize("integrate(t^2, t, 0, 1)")
+	if err != nil {
+		t.Fatalf("Tokenize failed: %v", err)
+	}
+	want := []tokenizer.Token{
+		{Type: tokenizer.Function, Value: "integrate", Pos: 0},
+		{Type: tokenizer.LeftBrace, Value: "(", Pos: 9},
+		{Type: tokenizer.Variable, Value: "t", Pos: 10},
+		{Type: tokenizer.Operator, Value: "^", Pos: 11},
+		{Type: tokenizer.Number, Value: "2", Pos: 12},
+		{Type: tokenizer.Comma, Value: ",", Pos: 13},
+		{Type: tokenizer.Variable, Value: "t", Pos: 15},
+		{Type: tokenizer.Comma, Value: ",", Pos: 16},
+		{Type: tokenizer.Number, Value: "0", Pos: 18},
+		{Type: tokenizer.Comma, Value: ",", Pos: 19},
+		{Type: tokenizer.Number, Value: "1", Pos: 21},
+		{Type: tokenizer.RightBrace, Value: ")", Pos: 22},
+	}
+	if !compareTokens(got, want) {
+		t.Errorf("Tokenize = %v, want %v", got, want)
+	}
+
+	for _, input := range []string{"integrate(t, 2 * t, 0, 1)", "integrate(t, s, 0, 1)"} {
+		if _, err := tokenizer.Tokenize(input); err == nil {
+			t.Errorf("Expected error for input: %q", input)
+		}
+	}
+
+	// The variable slot is checked before the body, whose names it binds.
+	for input, pos := range map[string]int{
+		"integrate(x, 1, 0, 1)":      13,
+		"integrate(t, 2 * t, 0, 1)":  13,
+		"solve(x^2 - 1,  (x), 0, 2)": 16,
+		"1 + root(x, x y, 0)":        12,
+	} {
+		_, err := tokenizer.Tokenize(input)
+		var tokErr *tokenizer.Error
+		if !errors.As(err, &tokErr) || tokErr.Code != "expected_variable" || tokErr.Pos != pos {
+			t.Errorf("Tokenize(%q) error = %v, want expected_variable at position %d", input, err, pos)
+		}
+	}
+}
+
 func compareTokens(a, b []tokenizer.Token) bool {
 	if len(a) != len(b) {
 		return false
@@ -5,8 +5,30 @@ import (
 )
 
 func Tokenize(input string) ([]Token, error) {
+	return TokenizeWithScope(input, nil)
+}
+
+// TokenizeWithScope tokenizes input, emitting Variable and Function tokens
+// for names known to scope. A name not yet in scope is accepted as the target
+// of an assignment or function definition at the start of the input. A nil
+// scope rejects every name that is not a built-in constant or function, or
+// a variable bound by a binder such as integrate.
+func TokenizeWithScope(input string, scope Scope) ([]Token, error) {
 	var tokens []Token
 	runes := []rune(input)
+	assignable := scope != nil
+	if scope != nil {
+		if name, params, ok := definitionHeader(runes); ok {
+			scope = definitionScope{scope, name, params}
+		}
+	}
+	names, err := boundNames(runes)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 {
+		scope = boundScope{scope, names}
+	}
 	i := 0
 	var prevToken Token
 
@@ -17,19 +39,40 @@ func Tokenize(input string) ([]Token, error) {
 		case unicode.IsSpace(r):
 			i++
 
-		case r == '-' && (i == 0 || isOperator(prevToken) || prevToken.Type == LeftBrace):
+		case r == '-' && (i == 0 || isOperator(prevToken) || prevToken.Type == LeftBrace || prevToken.Type == LeftBracket || prevToken.Type == Comma || prevToken.Type == Assign):
 			start := i
 			i++
 			for i < len(runes) && unicode.IsSpace(runes[i]) {
 				i++
 			}
 			if i < len(runes) {
-				if unicode.IsDigit(runes[i]) {
-					for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
-						i++
+				if integerPrefix(runes, i) != 0 {
+					end, err := scanInteger(runes, i)
+					if err != nil {
+						return nil, err
+					}
+					if bindsTighter(runes, end) {
+						tokens = append(tokens, Token{Operator, "-", start})
+					} else {
+						i = end
+						tokens = append(tokens, Token{Number, string(runes[start:i]), start})
 					}
-					tokens = append(tokens, Token{Number, string(runes[start:i]), start})
-				} else if runes[i] == '(' || unicode.IsLetter(runes[i]) {
+				} else if unicode.IsDigit(runes[i]) {
+					end := i
+					for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+						end++
+					}
+					end = scanExponent(runes, end)
+					if imaginarySuffix(runes, end) {
+						end++
+					}
+					if bindsTighter(runes, end) {
+						tokens = append(tokens, Token{Operator, "-", start})
+					} else {
+						i = end
+						tokens = append(tokens, Token{Number, string(runes[start:i]), start})
+					}
+				} else if runes[i] == '(' || runes[i] == '[' || runes[i] == '~' || unicode.IsLetter(runes[i]) {
 					tokens = append(tokens, Token{Operator, "-", start})
 				} else {
 					return nil, ErrInvalidNumber(start)
@@ -39,6 +82,23 @@ func Tokenize(input string) ([]Token, error) {
 			}
 			prevToken = tokens[len(tokens)-1]
 
+		case integerPrefix(runes, i) != 0:
+			end, err := scanInteger(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Number, string(runes[i:end]), i})
+			prevToken = tokens[len(tokens)-1]
+			i = end
+
+		case r == '.' && i+1 < len(runes) && runes[i+1] == '*':
+			if len(tokens) > 0 && isOperator(prevToken) {
+				return nil, ErrInvalidRPNSyntax(i)
+			}
+			tokens = append(tokens, Token{Operator, ".*", i})
+			prevToken = tokens[len(tokens)-1]
+			i += 2
+
 		case unicode.IsDigit(r) || r == '.':
 			start := i
 			dotCount := 0
@@ -75,12 +135,23 @@ func Tokenize(input string) ([]Token, error) {
 					i++
 				}
 
-				if i < len(runes) && unicode.IsLetter(runes[i]) {
+				if i < len(runes) && unicode.IsLetter(runes[i]) && !imaginarySuffix(runes, i) {
 					return nil, ErrInvalidNumber(i)
 				}
 			}
-
-			tokens = append(tokens, Token{Number, string(runes[start:i]), start})
+			if imaginarySuffix(runes, i) && bindsTighter(runes, i+1) {
+				// A power or factorial applies to the unit alone, as the
+				// one after a leading minus applies to the number: 4i^2 is
+				// 4 * i^2.
+				tokens = append(tokens, Token{Number, string(runes[start:i]), start},
+					Token{Operator, "*", i}, Token{Constant, "i", i})
+				i++
+			} else {
+				if imaginarySuffix(runes, i) {
+					i++
+				}
+				tokens = append(tokens, Token{Number, string(runes[start:i]), start})
+			}
 			prevToken = tokens[len(tokens)-1]
 
 		case unicode.IsLetter(r):
@@ -92,15 +163,50 @@ func Tokenize(input string) ([]Token, error) {
 				return nil, ErrInvalidNumber(start)
 			}
 			name := string(runes[start:i])
-			if _, ok := Constants[name]; ok {
+			// A function name assigned to, as in sin = 2, is a variable
+			// here so that the evaluator reports the redefinition.
+			target := assignable && len(tokens) == 0 && nextRune(runes, i) == '='
+			if _, ok := Operators[name]; ok {
+				tokens = append(tokens, Token{Operator, name, start})
+			} else if _, ok := Constants[name]; ok {
 				tokens = append(tokens, Token{Constant, name, start})
+			} else if target {
+				tokens = append(tokens, Token{Variable, name, start})
 			} else if _, ok := Functions[name]; ok {
 				tokens = append(tokens, Token{Function, name, start})
+			} else if scope != nil && scope.HasFunction(name) {
+				tokens = append(tokens, Token{Function, name, start})
+			} else if scope != nil && scope.HasVariable(name) {
+				tokens = append(tokens, Token{Variable, name, start})
 			} else {
 				return nil, ErrUnknownSymbol(start)
 			}
 			prevToken = tokens[len(tokens)-1]
 
+		case r == '~':
+			tokens = append(tokens, Token{Operator, string(r), i})
+			prevToken = tokens[len(tokens)-1]
+			i++
+
+		case r == '<' || r == '>':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, ErrUnknownSymbol(i)
+			}
+			if len(tokens) > 0 && isOperator(prevToken) {
+				return nil, ErrInvalidRPNSyntax(i)
+			}
+			tokens = append(tokens, Token{Operator, string(runes[i : i+2]), i})
+			prevToken = tokens[len(tokens)-1]
+			i += 2
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '/':
+			if len(tokens) > 0 && isOperator(prevToken) {
+				return nil, ErrInvalidRPNSyntax(i)
+			}
+			tokens = append(tokens, Token{Operator, "//", i})
+			prevToken = tokens[len(tokens)-1]
+			i += 2
+
 		case isSupportedOperator(r):
 			if len(tokens) > 0 && isOperator(prevToken) {
 				return nil, ErrInvalidRPNSyntax(i)
@@ -118,6 +224,34 @@ func Tokenize(input string) ([]Token, error) {
 			prevToken = tokens[len(tokens)-1]
 			i++
 
+		case r == '[' || r == ']':
+			tokType := LeftBracket
+			if r == ']' {
+				tokType = RightBracket
+			}
+			tokens = append(tokens, Token{tokType, string(r), i})
+			prevToken = tokens[len(tokens)-1]
+			i++
+
+		case r == '!':
+			op := "!"
+			if i+1 < len(runes) && runes[i+1] == '!' {
+				op = "!!"
+			}
+			tokens = append(tokens, Token{PostfixOperator, op, i})
+			prevToken = tokens[len(tokens)-1]
+			i += len(op)
+
+		case r == ',':
+			tokens = append(tokens, Token{Comma, string(r), i})
+			prevToken = tokens[len(tokens)-1]
+			i++
+
+		case r == '=':
+			tokens = append(tokens, Token{Assign, string(r), i})
+			prevToken = tokens[len(tokens)-1]
+			i++
+
 		default:
 			return nil, ErrUnknownSymbol(i)
 		}
@@ -135,7 +269,7 @@ func validateExpressionStructure(tokens []Token) error {
 		return ErrInvalidRPNSyntax(0)
 	}
 
-	if tokens[0].Type == Operator && tokens[0].Value != "-" {
+	if tokens[0].Type == Operator && !isPrefixOperator(tokens[0]) {
 		return ErrInvalidRPNSyntax(tokens[0].Pos)
 	}
 
@@ -144,6 +278,10 @@ func validateExpressionStructure(tokens []Token) error {
 	}
 
 	parenCount := 0
+	// calls records for every open parenthesis the function it calls, or ""
+	// for a parenthesis that does not belong to a call, and "[" for every
+	// open bracket.
+	var calls []string
 	for i := 0; i < len(tokens); i++ {
 		token := tokens[i]
 		switch token.Type {
@@ -152,7 +290,7 @@ func validateExpressionStructure(tokens []Token) error {
 				return ErrInvalidRPNSyntax(token.Pos)
 			}
 			next := tokens[i+1]
-			if next.Type != Number && next.Type != Constant && next.Type != LeftBrace && next.Type != Function && (next.Type != Operator || next.Value != "-") {
+			if !startsOperand(next) {
 				return ErrInvalidRPNSyntax(token.Pos)
 			}
 
@@ -161,35 +299,68 @@ func validateExpressionStructure(tokens []Token) error {
 				return ErrInvalidRPNSyntax(token.Pos)
 			}
 
-		case LeftBrace:
+		case LeftBrace, LeftBracket:
 			parenCount++
+			call := ""
+			switch {
+			case token.Type == LeftBracket:
+				call = "["
+			case i > 0 && tokens[i-1].Type == Function:
+				call = tokens[i-1].Value
+			}
+			calls = append(calls, call)
 			if i == len(tokens)-1 {
 				return ErrMismatchedParentheses(token.Pos)
 			}
 			next := tokens[i+1]
-			if next.Type != Number && next.Type != Constant && next.Type != LeftBrace && next.Type != Function && (next.Type != Operator || next.Value != "-") {
+			if !startsOperand(next) {
 				return ErrInvalidRPNSyntax(token.Pos)
 			}
 
-		case RightBrace:
+		case RightBrace, RightBracket:
 			parenCount--
-			if parenCount < 0 {
+			if parenCount < 0 || (calls[len(calls)-1] == "[") != (token.Type == RightBracket) {
 				return ErrMismatchedParentheses(token.Pos)
 			}
+			calls = calls[:len(calls)-1]
 			if i < len(tokens)-1 {
 				next := tokens[i+1]
-				if next.Type != Operator && next.Type != RightBrace {
+				if !endsOperand(next) {
 					return ErrInvalidRPNSyntax(token.Pos)
 				}
 			}
 
-		case Number, Constant:
+		case Comma:
+			if len(calls) == 0 || calls[len(calls)-1] == "" || i == len(tokens)-1 {
+				return ErrInvalidRPNSyntax(token.Pos)
+			}
+			next := tokens[i+1]
+			if !startsOperand(next) {
+				return ErrInvalidRPNSyntax(token.Pos)
+			}
+
+		case PostfixOperator:
+			if i == 0 {
+				return ErrInvalidRPNSyntax(token.Pos)
+			}
+			fallthrough
+
+		case Number, Constant, Variable:
 			if i < len(tokens)-1 {
 				next := tokens[i+1]
-				if next.Type != Operator && next.Type != RightBrace {
+				if !endsOperand(next) {
 					return ErrInvalidRPNSyntax(token.Pos)
 				}
 			}
+
+		case Assign:
+			inEquation := len(calls) > 0 && Equations[calls[len(calls)-1]]
+			if !inEquation && !isAssignmentTarget(tokens[:i]) {
+				return ErrInvalidAssignment(token.Pos)
+			}
+			if i == len(tokens)-1 || !startsOperand(tokens[i+1]) {
+				return ErrInvalidRPNSyntax(token.Pos)
+			}
 		}
 
 		if i == len(tokens)-1 && parenCount > 0 {
@@ -204,9 +375,87 @@ func validateExpressionStructure(tokens []Token) error {
 	return nil
 }
 
+// isAssignmentTarget reports whether target is a variable or the header of a
+// function definition, "name(p1, p2, ...)".
+func isAssignmentTarget(target []Token) bool {
+	if len(target) == 1 {
+		return target[0].Type == Variable
+	}
+	n := len(target)
+	if n < 4 || target[0].Type != Function || target[1].Type != LeftBrace || target[n-1].Type != RightBrace {
+		return false
+	}
+	for i := 2; i < n-1; i++ {
+		want := Variable
+		if i%2 == 1 {
+			want = Comma
+		}
+		if target[i].Type != want {
+			return false
+		}
+	}
+	return n%2 == 0
+}
+
+func startsOperand(t Token) bool {
+	switch t.Type {
+	case Number, Constant, Variable, LeftBrace, LeftBracket, Function:
+		return true
+	case Operator:
+		return isPrefixOperator(t)
+	default:
+		return false
+	}
+}
+
+// endsOperand reports whether t may follow an operand.
+func endsOperand(t Token) bool {
+	switch t.Type {
+	case Operator, RightBrace, RightBracket, Comma, Assign, PostfixOperator:
+		return true
+	}
+	return false
+}
+
+// bindsTighter reports whether the number ending at end is followed by an
+// operator that binds tighter than a leading minus, which then stays an
+// operator: -2^2 is -(2^2) and -5! is -(5!).
+func bindsTighter(runes []rune, end int) bool {
+	switch nextRune(runes, end) {
+	case '^', '!':
+		return true
+	}
+	return false
+}
+
+// scanExponent returns the end of the exponent, such as e-3, of a number
+// whose mantissa ends at i, or i when it has none.
+func scanExponent(runes []rune, i int) int {
+	if i == len(runes) || runes[i] != 'e' && runes[i] != 'E' {
+		return i
+	}
+	j := i + 1
+	if j < len(runes) && (runes[j] == '+' || runes[j] == '-') {
+		j++
+	}
+	if j == len(runes) || !unicode.IsDigit(runes[j]) {
+		return i
+	}
+	for j < len(runes) && unicode.IsDigit(runes[j]) {
+		j++
+	}
+	return j
+}
+
+// isPrefixOperator reports whether t may start an operand: unary minus or
+// bitwise not.
+func isPrefixOperator(t Token) bool {
+	return t.Type == Operator && (t.Value == "-" || t.Value == "~")
+}
+
 func isSupportedOperator(r rune) bool {
 	switch r {
-	case '+', '-', '*', '/', '^':
+	case '+', '-', '*', '/', '^', '%', '&', '|':
 		return true
 	default:
 		return false
@@ -217,5 +466,60 @@ func isOperator(t Token) bool {
 	if t.Type != Operator {
 		return false
 	}
-	return isSupportedOperator(rune(t.Value[0]))
+	_, ok := Operators[t.Value]
+	return ok
+}
+
+// imaginarySuffix reports whether the number ending at i carries the
+// imaginary unit, as in 4i, rather than running into a name.
+func imaginarySuffix(runes []rune, i int) bool {
+	if i >= len(runes) || runes[i] != 'i' {
+		return false
+	}
+	return i+1 == len(runes) || !unicode.IsLetter(runes[i+1]) && !unicode.IsDigit(runes[i+1])
+}
+
+// integerPrefix returns the base selected by a 0x, 0b or 0o prefix at i, or
+// 0 if there is none.
+func integerPrefix(runes []rune, i int) int {
+	if i+1 >= len(runes) || runes[i] != '0' {
+		return 0
+	}
+	switch runes[i+1] {
+	case 'x', 'X':
+		return 16
+	case 'b', 'B':
+		return 2
+	case 'o', 'O':
+		return 8
+	}
+	return 0
+}
+
+// scanInteger returns the end of the prefixed integer literal at i.
+func scanInteger(runes []rune, i int) (int, error) {
+	base := integerPrefix(runes, i)
+	end := i + 2
+	for end < len(runes) && digitValue(runes[end]) < base {
+		end++
+	}
+	if end == i+2 {
+		return 0, ErrInvalidNumber(i)
+	}
+	if end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '.') {
+		return 0, ErrInvalidNumber(end)
+	}
+	return end, nil
+}
+
+func digitValue(r rune) int {
+	switch {
+	case '0' <= r && r <= '9':
+		return int(r - '0')
+	case 'a' <= r && r <= 'f':
+		return int(r-'a') + 10
+	case 'A' <= r && r <= 'F':
+		return int(r-'A') + 10
+	}
+	return 16
 }
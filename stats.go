@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/a1sarpi/gocalc/src/evaluation"
+	"github.com/a1sarpi/gocalc/src/tokenizer"
+)
+
+type jsonStats struct {
+	Count  int     `json:"count"`
+	Sum    float64 `json:"sum"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	Mode   float64 `json:"mode"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Range  float64 `json:"range"`
+	Q1     float64 `json:"q1"`
+	Q3     float64 `json:"q3"`
+	// Var and StdDev are null for a single number.
+	Var     *float64 `json:"var"`
+	StdDev  *float64 `json:"stddev"`
+	PVar    float64  `json:"pvar"`
+	PStdDev float64  `json:"pstddev"`
+}
+
+// processStats reads numbers separated by spaces, commas or newlines from
+// r and prints every statistic of them. As in a batch, text after # on a
+// line is skipped.
+func processStats(r io.Reader) error {
+	xs, err := readNumbers(r)
+	if err != nil {
+		return err
+	}
+	s, err := evaluation.Summarize(xs)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		out := jsonStats{
+			Count: s.Count, Sum: s.Sum, Mean: s.Mean, Median: s.Median, Mode: s.Mode,
+			Min: s.Min, Max: s.Max, Range: s.Range, Q1: s.Q1, Q3: s.Q3,
+			PVar: s.PVar, PStdDev: s.PStdDev,
+		}
+		if s.Count > 1 {
+			out.Var, out.StdDev = &s.Var, &s.StdDev
+		}
+		return json.NewEncoder(os.Stdout).Encode(out)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "count\t%d\n", s.Count)
+	for _, stat := range []struct {
+		name  string
+		value float64
+	}{
+		{"sum", s.Sum},
+		{"mean", s.Mean},
+		{"median", s.Median},
+		{"mode", s.Mode},
+		{"min", s.Min},
+		{"max", s.Max},
+		{"range", s.Range},
+		{"q1", s.Q1},
+		{"q3", s.Q3},
+		{"var", s.Var},
+		{"stddev", s.StdDev},
+		{"pvar", s.PVar},
+		{"pstddev", s.PStdDev},
+	} {
+		value := "undefined"
+		if !math.IsNaN(stat.value) {
+			value = formatResult(stat.value)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", stat.name, value)
+	}
+	return w.Flush()
+}
+
+// readNumbers parses the numbers of r, reporting the line and column of
+// the first that is not one.
+func readNumbers(r io.Reader) ([]float64, error) {
+	var xs []float64
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		for col := 0; col < len(line); {
+			if isSeparator(rune(line[col])) {
+				col++
+				continue
+			}
+			end := col
+			for end < len(line) && !isSeparator(rune(line[end])) {
+				end++
+			}
+			x, err := strconv.ParseFloat(line[col:end], 64)
+			if err != nil || math.IsNaN(x) || math.IsInf(x, 0) {
+				return nil, &lineError{Line: n, Col: col + 1, Err: tokenizer.ErrInvalidNumber(col)}
+			}
+			xs = append(xs, x)
+			col = end
+		}
+	}
+	return xs, scanner.Err()
+}
+
+func isSeparator(r rune) bool {
+	return r == ',' || unicode.IsSpace(r)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/a1sarpi/gocalc/src/table"
+	"github.com/spf13/cobra"
+)
+
+var (
+	tableVars   []string
+	tableFrom   []float64
+	tableTo     []float64
+	tableStep   []float64
+	tableOutput string
+)
+
+var tableCmd = &cobra.Command{
+	Use:   "table EXPRESSION",
+	Short: "Tabulate an expression over ranges of its variables",
+	Long: `Tabulate an expression over ranges of its variables. Each --var is stepped
+from the --from to the --to in the same position by its --step, which is 1
+when no --step is given, and every combination gets a row:
+
+  calc table "x^2 + sin(x)" --var x --from 0 --to 10 --step 0.5
+  calc table "x * y" --var x --from 1 --to 3 --var y --from 1 --to 3
+
+Rows that cannot be evaluated show the error code, such as domain, in
+place of the result. Use -- before an expression that starts with a minus
+sign.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := setFormatter(); err != nil {
+			return err
+		}
+		style, err := table.ParseStyle(tableOutput)
+		if err != nil {
+			return err
+		}
+		if len(tableVars) == 0 {
+			return fmt.Errorf("at least one --var is required")
+		}
+		if len(tableFrom) != len(tableVars) || len(tableTo) != len(tableVars) {
+			return fmt.Errorf("every --var needs a --from and a --to")
+		}
+		steps := tableStep
+		if len(steps) == 0 {
+			for range tableVars {
+				steps = append(steps, 1)
+			}
+		}
+		if len(steps) != len(tableVars) {
+			return fmt.Errorf("give a --step for every --var or for none")
+		}
+
+		ranges := make([]table.Range, len(tableVars))
+		for i, name := range tableVars {
+			ranges[i] = table.Range{Name: name, From: tableFrom[i], To: tableTo[i], Step: steps[i]}
+		}
+		t, err := table.New(args[0], ranges, useRadians)
+		if err != nil {
+			return fmt.Errorf("parse error: %w", err)
+		}
+		return t.Write(cmd.OutOrStdout(), style, formatResult)
+	},
+}
+
+func init() {
+	tableCmd.Flags().StringArrayVar(&tableVars, "var", nil, "Variable to range over, repeatable")
+	tableCmd.Flags().Float64SliceVar(&tableFrom, "from", nil, "First value of each --var")
+	tableCmd.Flags().Float64SliceVar(&tableTo, "to", nil, "Last value of each --var")
+	tableCmd.Flags().Float64SliceVar(&tableStep, "step", nil, "Step of each --var (default 1)")
+	tableCmd.Flags().StringVarP(&tableOutput, "output", "o", "text", "Output format: text, csv or markdown")
+	rootCmd.AddCommand(tableCmd)
+}